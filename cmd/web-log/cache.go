@@ -0,0 +1,40 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"web-log/internal/cache"
+)
+
+func runCache(args []string) {
+	if len(args) == 0 || args[0] != "prune" {
+		fmt.Fprintln(os.Stderr, "Usage: web-log cache prune [--older-than 720h] [--all]")
+		os.Exit(1)
+	}
+
+	fs := flag.NewFlagSet("cache prune", flag.ExitOnError)
+	olderThan := fs.Duration("older-than", 30*24*time.Hour, "Remove responses cached longer ago than this")
+	all := fs.Bool("all", false, "Remove every cached response")
+	if err := fs.Parse(args[1:]); err != nil {
+		os.Exit(1)
+	}
+
+	dir, err := cache.DefaultDir()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	var cutoff time.Time
+	if !*all {
+		cutoff = time.Now().Add(-*olderThan)
+	}
+	removed, freed, err := cache.Open(dir).Prune(cutoff)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	fmt.Printf("Removed %d cached responses (%.1f KB) from %s\n", removed, float64(freed)/1024, dir)
+}
@@ -0,0 +1,313 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+
+	"web-log/internal/config"
+	"web-log/internal/history"
+	"web-log/internal/obsidian"
+	"web-log/internal/rules"
+	"web-log/internal/summary"
+)
+
+// loadConfig reads the config file and points the history sources at the
+// paths it sets.
+func loadConfig(path string) (*config.Config, error) {
+	cfg, err := config.Load(path)
+	if err != nil {
+		return nil, err
+	}
+	for _, name := range cfg.PathNames() {
+		if err := history.SetPath(name, cfg.Paths[name]); err != nil {
+			return nil, fmt.Errorf("%s: paths: %w", cfg.Path, err)
+		}
+	}
+	return cfg, nil
+}
+
+// defaultConfigPath is shown as the --config default; when it cannot be
+// determined no config file is read.
+func defaultConfigPath() string {
+	path, err := config.DefaultPath()
+	if err != nil {
+		return ""
+	}
+	return path
+}
+
+// setFlags returns the names of the flags given on the command line, which
+// win over the environment and the config file.
+func setFlags(fs *flag.FlagSet) map[string]bool {
+	set := map[string]bool{}
+	fs.Visit(func(f *flag.Flag) {
+		set[f.Name] = true
+	})
+	return set
+}
+
+// resolveTimezone picks the --timezone flag, then TZ (which Go already
+// applies to time.Local), then the config file, then the system zone.
+func resolveTimezone(flagValue string, cfg *config.Config) (*time.Location, string, error) {
+	if flagValue != "" {
+		location, err := time.LoadLocation(flagValue)
+		if err != nil {
+			return nil, "", fmt.Errorf("--timezone %q: %w", flagValue, err)
+		}
+		return location, "flag", nil
+	}
+	if _, ok := os.LookupEnv("TZ"); ok {
+		return time.Local, "env TZ", nil
+	}
+	location, err := cfg.Location()
+	if err != nil {
+		return nil, "", err
+	}
+	if location != nil {
+		return location, "config", nil
+	}
+	return time.Local, "default", nil
+}
+
+// zoneName names location the IANA way. time.Local is called "Local", so
+// its name comes from TZ or the /etc/localtime link where possible, and
+// from the zone abbreviation otherwise.
+func zoneName(location *time.Location) string {
+	if location != time.Local {
+		return location.String()
+	}
+	if tz := strings.TrimPrefix(os.Getenv("TZ"), ":"); tz != "" {
+		return tz
+	}
+	if target, err := os.Readlink("/etc/localtime"); err == nil {
+		if _, name, ok := strings.Cut(target, "zoneinfo/"); ok {
+			return name
+		}
+	}
+	zone, _ := time.Now().In(location).Zone()
+	return zone
+}
+
+// providerConfig layers the provider flags over the config file; the
+// environment sits between them (see summary.ResolveProvider).
+func providerConfig(cfg *config.Config, name, model, baseURL string) summary.ProviderConfig {
+	return summary.ProviderConfig{
+		Name:    name,
+		Model:   model,
+		BaseURL: baseURL,
+		Defaults: summary.ProviderDefaults{
+			Name:     cfg.Provider,
+			Models:   cfg.ProviderModels(),
+			BaseURLs: cfg.ProviderBaseURLs(),
+		},
+		Log: func(msg string) {
+			fmt.Fprintln(os.Stderr, msg)
+		},
+	}
+}
+
+// readPromptTemplate returns the template file's text, or "" for the
+// built-in guidance.
+func readPromptTemplate(path string) (string, error) {
+	if path == "" {
+		return "", nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("prompt template: %w", err)
+	}
+	return string(data), nil
+}
+
+func runConfig(args []string) {
+	if len(args) == 0 || args[0] != "show" {
+		fmt.Fprintln(os.Stderr, "Usage: web-log config show [--config FILE]")
+		os.Exit(1)
+	}
+	fs := flag.NewFlagSet("config show", flag.ExitOnError)
+	configPath := fs.String("config", defaultConfigPath(), "Config file")
+	if err := fs.Parse(args[1:]); err != nil {
+		os.Exit(1)
+	}
+	cfg, err := loadConfig(*configPath)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	if err := printConfig(cfg); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+// printConfig writes the effective settings as TOML, each commented with
+// where its value comes from. Flags are per run, so only the environment,
+// the file and the defaults show up here.
+func printConfig(cfg *config.Config) error {
+	status := "not found, using defaults"
+	if cfg.Found {
+		status = "loaded"
+	}
+	fmt.Printf("# config file: %s (%s)\n", cfg.Path, status)
+	fmt.Println("# precedence: flags > environment > config file > defaults")
+	fmt.Println()
+
+	line := func(key, value, origin string) {
+		fmt.Printf("%-40s # %s\n", key+" = "+value, origin)
+	}
+
+	provider, err := summary.ResolveProvider(providerConfig(cfg, "", "", ""))
+	if err != nil {
+		return err
+	}
+	line("provider", quote(provider.Name.Value), provider.Name.Origin)
+	apiKey := "not set"
+	if provider.APIKeySet {
+		apiKey = "set"
+	}
+	fmt.Printf("# API key from %s: %s\n", provider.APIKeyEnv, apiKey)
+
+	sources, sourcesOrigin := "all", "default"
+	if cfg.Sources != "" {
+		sources, sourcesOrigin = cfg.Sources, "config"
+	}
+	line("sources", quote(sources), sourcesOrigin)
+
+	location, tzOrigin, err := resolveTimezone("", cfg)
+	if err != nil {
+		return err
+	}
+	line("timezone", quote(zoneName(location)), tzOrigin)
+
+	template, templateOrigin := "", "default: built-in guidance"
+	if cfg.PromptTemplate != "" {
+		template, templateOrigin = cfg.PromptTemplate, "config"
+	}
+	line("prompt_template", quote(template), templateOrigin)
+
+	rulesOrigin := "default"
+	if cfg.DefaultRules != nil {
+		rulesOrigin = "config"
+	}
+	line("default_rules", strconv.FormatBool(cfg.DefaultRules == nil || *cfg.DefaultRules), rulesOrigin)
+
+	fmt.Println()
+	fmt.Printf("[providers.%s]\n", provider.Name.Value)
+	line("model", quote(provider.Model.Value), provider.Model.Origin)
+	line("base_url", quote(provider.BaseURL.Value), provider.BaseURL.Origin)
+
+	fmt.Println()
+	fmt.Println("[paths]")
+	for _, source := range history.Sources() {
+		path, overridden, err := history.SourcePath(source.Name())
+		if err != nil {
+			fmt.Printf("# %s: %v\n", source.Name(), err)
+			continue
+		}
+		origin := "default"
+		if overridden {
+			origin = "config"
+		}
+		line(source.Name(), quote(path), origin)
+	}
+
+	fmt.Println()
+	fmt.Println("[output]")
+	format, formatOrigin := defaultFormat, "default"
+	if cfg.Output.Format != "" {
+		format, formatOrigin = cfg.Output.Format, "config"
+	}
+	line("format", quote(format), formatOrigin)
+	outputPath, pathOrigin := "", "default: stdout"
+	if cfg.Output.Path != "" {
+		outputPath, pathOrigin = cfg.Output.Path, "config"
+	}
+	line("path", quote(outputPath), pathOrigin)
+	entriesOrigin := "default"
+	if cfg.Output.IncludeEntries != nil {
+		entriesOrigin = "config"
+	}
+	line("include_entries", strconv.FormatBool(cfg.Output.IncludeEntries != nil && *cfg.Output.IncludeEntries), entriesOrigin)
+
+	fmt.Println()
+	fmt.Println("[obsidian]")
+	setting := func(key, value, fallback, fallbackOrigin string) {
+		if value != "" {
+			line(key, quote(value), "config")
+			return
+		}
+		line(key, quote(fallback), fallbackOrigin)
+	}
+	setting("vault", cfg.Obsidian.Vault, "", "default: none")
+	setting("folder", cfg.Obsidian.Folder, "", "default: vault root")
+	setting("note_per", cfg.Obsidian.NotePer, "day", "default")
+	notePer := cfg.Obsidian.NotePer
+	if notePer == "" {
+		notePer = "day"
+	}
+	defaultNote := obsidian.DefaultDayNote
+	if notePer == "period" {
+		defaultNote = obsidian.DefaultPeriodNote
+	}
+	setting("note", cfg.Obsidian.Note, defaultNote, "default")
+
+	fmt.Println()
+	fmt.Println("[logseq]")
+	setting("graph", cfg.Logseq.Graph, "", "default: none")
+
+	fmt.Println()
+	fmt.Println("[redact]")
+	enabledOrigin := "default"
+	if cfg.Redact.Enabled != nil {
+		enabledOrigin = "config"
+	}
+	line("enabled", strconv.FormatBool(cfg.Redact.Enabled == nil || *cfg.Redact.Enabled), enabledOrigin)
+	patterns := make([]string, len(cfg.Redact.Patterns))
+	for i, pattern := range cfg.Redact.Patterns {
+		patterns[i] = quote(pattern)
+	}
+	patternsOrigin := "default"
+	if len(patterns) > 0 {
+		patternsOrigin = "config"
+	}
+	line("patterns", "["+strings.Join(patterns, ", ")+"]", patternsOrigin)
+
+	if len(cfg.Rules) > 0 {
+		var b bytes.Buffer
+		if err := toml.NewEncoder(&b).Encode(struct {
+			Rules []rules.Rule `toml:"rule"`
+		}{cfg.Rules}); err != nil {
+			return err
+		}
+		fmt.Println()
+		fmt.Println("# exclusion rules from the config file, checked before the defaults")
+		fmt.Print(b.String())
+	}
+	return nil
+}
+
+// quote renders s as a TOML basic string.
+func quote(s string) string {
+	var b strings.Builder
+	b.WriteByte('"')
+	for _, r := range s {
+		switch {
+		case r == '"' || r == '\\':
+			b.WriteByte('\\')
+			b.WriteRune(r)
+		case r < 0x20 || r == 0x7f:
+			fmt.Fprintf(&b, `\u%04X`, r)
+		default:
+			b.WriteRune(r)
+		}
+	}
+	b.WriteByte('"')
+	return b.String()
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"web-log/internal/summary"
+)
+
+// printDryRun shows what a summary run would send: the entry counts per day
+// and source, what the filters removed and the token estimate on stderr, and
+// the prompt itself on stdout or in promptOut.
+func printDryRun(result prepared, startDate, endDate string, days int, opts summary.Options, promptOut string) error {
+	entries := result.Entries
+	w := os.Stderr
+	fmt.Fprintf(w, "Period: %s to %s (%d days)\n", startDate, endDate, days)
+
+	fmt.Fprintf(w, "\nEntries: %d\n", len(entries))
+	byDay := map[string]map[string]int{}
+	for _, entry := range entries {
+		day := entry.VisitTime.Format("2006-01-02")
+		if byDay[day] == nil {
+			byDay[day] = map[string]int{}
+		}
+		byDay[day][entry.Source]++
+	}
+	for _, day := range sortedKeys(byDay) {
+		counts := byDay[day]
+		total := 0
+		parts := []string{}
+		for _, source := range sortedKeys(counts) {
+			total += counts[source]
+			parts = append(parts, fmt.Sprintf("%s %d", source, counts[source]))
+		}
+		fmt.Fprintf(w, "  %s  %4d  %s\n", day, total, strings.Join(parts, ", "))
+	}
+
+	removed := 0
+	for _, stat := range result.Filtered {
+		removed += stat.Removed
+	}
+	fmt.Fprintf(w, "\nFiltered out: %d\n", removed)
+	for _, stat := range result.Filtered {
+		fmt.Fprintf(w, "  %-24s %4d\n", stat.Rule, stat.Removed)
+	}
+	fmt.Fprintf(w, "\nRedacted: %s\n", result.Redactions)
+
+	if len(entries) == 0 {
+		fmt.Fprintln(w, "\nNo browsing history found for this period; nothing would be sent.")
+		return nil
+	}
+	prompts := summary.Prompts(entries, startDate, endDate, days, opts)
+	tokens := 0
+	for _, prompt := range prompts {
+		tokens += summary.EstimateTokens(prompt)
+	}
+	if len(prompts) == 1 {
+		fmt.Fprintf(w, "\nEstimated prompt tokens: %d (budget %d), 1 call\n", tokens, opts.MaxPromptTokens)
+	} else {
+		fmt.Fprintf(w, "\nEstimated prompt tokens: %d in %d parts (budget %d each), plus merge calls\n", tokens, len(prompts), opts.MaxPromptTokens)
+	}
+
+	text := prompts[0]
+	if len(prompts) > 1 {
+		var b strings.Builder
+		for i, prompt := range prompts {
+			fmt.Fprintf(&b, "===== Part %d of %d (~%d tokens) =====\n\n%s\n\n", i+1, len(prompts), summary.EstimateTokens(prompt), prompt)
+		}
+		text = b.String()
+	}
+	if promptOut == "" {
+		fmt.Fprintln(w)
+		fmt.Println(text)
+		return nil
+	}
+	if err := os.WriteFile(promptOut, []byte(text), 0o600); err != nil {
+		return err
+	}
+	fmt.Fprintf(w, "Prompt written to %s\n", promptOut)
+	return nil
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
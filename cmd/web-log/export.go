@@ -0,0 +1,184 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"web-log/internal/export"
+	"web-log/internal/history"
+	"web-log/internal/redact"
+	"web-log/internal/summary"
+)
+
+// runExport writes the entries a summary of the period would be built from,
+// merged, filtered and redacted the same way, without calling a model.
+func runExport(args []string) {
+	fs := flag.NewFlagSet("export", flag.ExitOnError)
+	configPath := fs.String("config", defaultConfigPath(), "Config file (see 'web-log config show')")
+	days := fs.Int("days", 0, "Number of days to export (default 7)")
+	from := fs.String("from", "", "Start date (YYYY-MM-DD)")
+	to := fs.String("to", "", "End date (YYYY-MM-DD)")
+	timezone := fs.String("timezone", "", "Timezone for days and times, e.g. Europe/Zurich (default $TZ, the config file or the system's)")
+	format := fs.String("format", "csv", "Export format: "+strings.Join(export.Formats, ", "))
+	outputPath := fs.String("output", "", "Write to this file instead of stdout (required for sqlite); {start} and {end} are replaced with the dates")
+	dedupe := fs.Bool("dedupe", true, "Deduplicate URLs")
+	includeRedirects := fs.Bool("include-redirects", false, "Keep redirect hops, reloads and iframe loads")
+	sourceTimeout := fs.Duration("source-timeout", 60*time.Second, "Give up on a history source after this long (0 = no limit)")
+	verbose := fs.Bool("verbose", false, "Report per-source entry counts and read times on stderr")
+	sourcesFlag := fs.String("sources", "all", "Comma-separated sources to read, \"-name\" to exclude (see 'web-log sources')")
+	redactFlag := fs.Bool("redact", true, "Mask credentials, tokens, emails and phone numbers in URLs and titles")
+	var redactPatterns []string
+	fs.Func("redact-pattern", "Also mask matches of this regular expression (repeatable, added to the config file's patterns)", func(pattern string) error {
+		redactPatterns = append(redactPatterns, pattern)
+		return nil
+	})
+	useArchive := fs.Bool("archive", true, "Sync and read the local archive when it exists (see 'web-log sync')")
+	force := fs.Bool("force", false, "Write a sqlite export into a database that is not an earlier export, replacing its entries table")
+	if err := fs.Parse(args); err != nil {
+		os.Exit(1)
+	}
+
+	cfg, err := loadConfig(*configPath)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	set := setFlags(fs)
+	if !set["sources"] && cfg.Sources != "" {
+		*sourcesFlag = cfg.Sources
+	}
+	if !set["redact"] && cfg.Redact.Enabled != nil {
+		*redactFlag = *cfg.Redact.Enabled
+	}
+	redactPatterns = append(append([]string{}, cfg.Redact.Patterns...), redactPatterns...)
+	if err := checkExportFormat(*format, *outputPath); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	location, _, err := resolveTimezone(*timezone, cfg)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	since, until, startDate, endDate, _, err := summary.DateRange(*days, *from, *to, location)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	sources, err := history.SelectSources(*sourcesFlag)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	entries, err := readEntries(ctx, sources, &since, &until, *useArchive, false, *sourceTimeout, *verbose)
+	if ctx.Err() != nil {
+		os.Exit(130)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	ruleSet, err := cfg.RuleSet(location)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s: %v\n", cfg.Path, err)
+		os.Exit(1)
+	}
+	steps := pipeline{location: location, includeRedirects: *includeRedirects, dedupe: *dedupe, rules: ruleSet}
+	if *redactFlag {
+		steps.redactor, err = redact.New(redactPatterns)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+	}
+	result := steps.prepare(entries)
+	if result.Redactions.Total() > 0 {
+		fmt.Fprintf(os.Stderr, "redacted: %s\n", result.Redactions)
+	}
+
+	path := ""
+	if *outputPath != "" && *outputPath != "-" {
+		path = expandOutputPath(*outputPath, startDate, endDate)
+	}
+	if err := writeExport(result.Entries, *format, path, *force); err != nil {
+		if errors.Is(err, export.ErrNotExport) {
+			err = fmt.Errorf("%s: %w; pass --force to replace its entries table anyway", path, err)
+		}
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	if path != "" {
+		fmt.Fprintf(os.Stderr, "Exported %d entries to %s\n", len(result.Entries), path)
+	}
+}
+
+func checkExportFormat(format, outputPath string) error {
+	for _, f := range export.Formats {
+		if f != format {
+			continue
+		}
+		if format == "sqlite" && (outputPath == "" || outputPath == "-") {
+			return fmt.Errorf("--format sqlite needs --output FILE")
+		}
+		return nil
+	}
+	return fmt.Errorf("unknown format %q (want one of %s)", format, strings.Join(export.Formats, ", "))
+}
+
+// writeExport writes entries in format to path, or to stdout when path is
+// empty. force lets a sqlite export replace the entries table of a database
+// that is not an earlier export.
+func writeExport(entries []history.Entry, format, path string, force bool) error {
+	if path != "" {
+		if dir := filepath.Dir(path); dir != "." {
+			if err := os.MkdirAll(dir, 0o755); err != nil {
+				return err
+			}
+		}
+	}
+	if format == "sqlite" {
+		return export.SQLite(path, entries, force)
+	}
+
+	var out io.Writer = os.Stdout
+	var file *os.File
+	if path != "" {
+		var err error
+		if file, err = os.Create(path); err != nil {
+			return err
+		}
+		defer file.Close()
+		out = file
+	}
+	w := bufio.NewWriter(out)
+	var err error
+	if format == "csv" {
+		err = export.CSV(w, entries)
+	} else {
+		err = export.JSONL(w, entries)
+	}
+	if err != nil {
+		return err
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	if file != nil {
+		return file.Close()
+	}
+	return nil
+}
@@ -1,12 +1,19 @@
 package main
 
 import (
+	"context"
 	"flag"
 	"fmt"
 	"os"
+	"os/signal"
 	"strings"
+	"time"
 
+	"web-log/internal/archive"
+	"web-log/internal/cache"
 	"web-log/internal/history"
+	"web-log/internal/redact"
+	"web-log/internal/report"
 	"web-log/internal/summary"
 )
 
@@ -22,6 +29,20 @@ func main() {
 	switch cmd {
 	case "tags":
 		runTags(os.Args[2:])
+	case "sources":
+		runSources()
+	case "sync":
+		runSync(os.Args[2:])
+	case "export":
+		runExport(os.Args[2:])
+	case "cache":
+		runCache(os.Args[2:])
+	case "usage":
+		runUsage(os.Args[2:])
+	case "rules":
+		runRules(os.Args[2:])
+	case "config":
+		runConfig(os.Args[2:])
 	case "version", "--version", "-v":
 		fmt.Println(version)
 	case "help", "--help", "-h":
@@ -39,52 +60,332 @@ func main() {
 
 func runTags(args []string) {
 	fs := flag.NewFlagSet("tags", flag.ExitOnError)
-	days := fs.Int("days", 7, "Number of days to summarize")
+	configPath := fs.String("config", defaultConfigPath(), "Config file (see 'web-log config show')")
+	days := fs.Int("days", 0, "Number of days to summarize (default 7)")
 	from := fs.String("from", "", "Start date (YYYY-MM-DD)")
 	to := fs.String("to", "", "End date (YYYY-MM-DD)")
+	timezone := fs.String("timezone", "", "Timezone for days and times, e.g. Europe/Zurich (default $TZ, the config file or the system's)")
 	dedupe := fs.Bool("dedupe", true, "Deduplicate URLs")
+	includeRedirects := fs.Bool("include-redirects", false, "Keep redirect hops, reloads and iframe loads")
+	sourceTimeout := fs.Duration("source-timeout", 60*time.Second, "Give up on a history source after this long (0 = no limit)")
+	verbose := fs.Bool("verbose", false, "Report per-source entry counts and read times on stderr")
+	sourcesFlag := fs.String("sources", "all", "Comma-separated sources to read, \"-name\" to exclude (see 'web-log sources')")
+	providerName := fs.String("provider", "", "LLM provider: "+strings.Join(summary.ProviderNames, ", ")+" (default $WEBLOG_PROVIDER, the config file or openrouter)")
+	model := fs.String("model", "", "Model to use, or comma-separated models to fall back through (default from the provider's *_MODELS or *_MODEL variable or the config file)")
+	baseURL := fs.String("base-url", "", "Override the provider's API base URL")
+	maxPromptTokens := fs.Int("max-prompt-tokens", summary.DefaultMaxPromptTokens, "Summarize in parts and merge when the prompt is estimated above this many tokens")
+	promptTemplate := fs.String("prompt-template", "", "File whose text replaces the built-in grouping guidance of the prompt")
+	noCache := fs.Bool("no-cache", false, "Always call the model instead of reusing a cached response")
+	stream := fs.Bool("stream", true, "Show the model's answer on stderr as it arrives (only when stderr is a terminal)")
+	redactFlag := fs.Bool("redact", true, "Mask credentials, tokens, emails and phone numbers in URLs and titles before prompting")
+	var redactPatterns []string
+	fs.Func("redact-pattern", "Also mask matches of this regular expression (repeatable, added to the config file's patterns)", func(pattern string) error {
+		redactPatterns = append(redactPatterns, pattern)
+		return nil
+	})
+	format := fs.String("format", defaultFormat, "Output format: "+strings.Join(formats, ", "))
+	includeEntries := fs.Bool("include-entries", false, "With --format json, include the entries tags reference")
+	outputPath := fs.String("output", "", "Write the summary to this file instead of stdout; {start} and {end} are replaced with the dates")
+	vaultDir := fs.String("vault", "", "With --format obsidian, the vault to write notes into")
+	notePer := fs.String("note-per", "day", "With --format obsidian, write a note per \"day\" or per \"period\"")
+	graphDir := fs.String("graph", "", "With --format logseq, the graph whose journal pages to write")
+	dryRun := fs.Bool("dry-run", false, "Show the prompt and what goes into it without calling the model")
+	promptOut := fs.String("prompt-out", "", "With --dry-run, write the prompt to this file instead of stdout")
+	useArchive := fs.Bool("archive", true, "Sync and read the local archive when it exists (see 'web-log sync')")
 	if err := fs.Parse(args); err != nil {
 		os.Exit(1)
 	}
 
-	since, until, startDate, endDate, actualDays, err := summary.DateRange(*days, *from, *to)
+	cfg, err := loadConfig(*configPath)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	// Flags given on the command line win over the config file
+	set := setFlags(fs)
+	if !set["sources"] && cfg.Sources != "" {
+		*sourcesFlag = cfg.Sources
+	}
+	if !set["prompt-template"] {
+		*promptTemplate = cfg.PromptTemplate
+	}
+	if !set["format"] && cfg.Output.Format != "" {
+		*format = cfg.Output.Format
+	}
+	if !set["output"] {
+		*outputPath = cfg.Output.Path
+	}
+	if !set["include-entries"] && cfg.Output.IncludeEntries != nil {
+		*includeEntries = *cfg.Output.IncludeEntries
+	}
+	if !set["redact"] && cfg.Redact.Enabled != nil {
+		*redactFlag = *cfg.Redact.Enabled
+	}
+	redactPatterns = append(append([]string{}, cfg.Redact.Patterns...), redactPatterns...)
+	if err := checkFormat(*format); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	outOpts := outputOptions{includeEntries: *includeEntries}
+	switch *format {
+	case "obsidian":
+		outOpts.vault, err = obsidianVault(cfg, *vaultDir, *notePer, set)
+	case "logseq":
+		outOpts.graph, err = logseqGraph(cfg, *graphDir, set)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	location, _, err := resolveTimezone(*timezone, cfg)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	template, err := readPromptTemplate(*promptTemplate)
 	if err != nil {
 		fmt.Fprintln(os.Stderr, err)
 		os.Exit(1)
 	}
 
-	entries, errs := history.ReadAllHistory(&since, &until)
-	for _, err := range errs {
+	since, until, startDate, endDate, actualDays, err := summary.DateRange(*days, *from, *to, location)
+	if err != nil {
 		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
 	}
 
-	if *dedupe {
-		entries = history.Deduplicate(entries)
+	sources, err := history.SelectSources(*sourcesFlag)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	entries, err := readEntries(ctx, sources, &since, &until, *useArchive, *dryRun, *sourceTimeout, *verbose)
+	if ctx.Err() != nil {
+		os.Exit(130)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
 	}
 
+	ruleSet, err := cfg.RuleSet(location)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s: %v\n", cfg.Path, err)
+		os.Exit(1)
+	}
+	steps := pipeline{location: location, includeRedirects: *includeRedirects, dedupe: *dedupe, rules: ruleSet}
+	if *redactFlag {
+		steps.redactor, err = redact.New(redactPatterns)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+	}
+	result := steps.prepare(entries)
+	entries = result.Entries
+
+	opts := summary.Options{
+		MaxPromptTokens: *maxPromptTokens,
+		Progress: func(msg string) {
+			fmt.Fprintln(os.Stderr, msg)
+		},
+		PromptTemplate: template,
+	}
+	if *dryRun {
+		if err := printDryRun(result, startDate, endDate, actualDays, opts, *promptOut); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	rep := &report.Report{
+		Summary:    &summary.Summary{StartDate: startDate, EndDate: endDate, Days: actualDays},
+		Timezone:   zoneName(location),
+		Read:       result.Read,
+		Filtered:   result.Filtered,
+		Redactions: result.Redactions,
+		Generated:  time.Now().In(location),
+	}
 	if len(entries) == 0 {
-		fmt.Println("No browsing history found for this period.")
+		fmt.Fprintln(os.Stderr, "No browsing history found for this period.")
+		// Scripts still get a document to read, with no sections; notes
+		// are only written for days with entries
+		if !writesNotes(*format) {
+			if err := writeReport(rep, *format, *outputPath, outOpts); err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				os.Exit(1)
+			}
+		}
 		return
 	}
+	if result.Redactions.Total() > 0 {
+		fmt.Fprintf(os.Stderr, "redacted: %s\n", result.Redactions)
+	}
+
+	provider, err := summary.NewProvider(providerConfig(cfg, *providerName, *model, *baseURL))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	if !*noCache {
+		dir, err := cache.DefaultDir()
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		provider = summary.NewCachedProvider(provider, cache.Open(dir), func(msg string) {
+			fmt.Fprintln(os.Stderr, msg)
+		})
+	}
+
+	// The preview goes to stderr and is cleared before the summary is
+	// printed, so stdout only ever gets the rendered summary
+	var preview *streamPreview
+	if *stream && isTerminal(os.Stderr) {
+		preview = newStreamPreview(os.Stderr)
+		opts.Stream = preview
+	}
+	output, err := summary.TagsSummary(ctx, provider, entries, startDate, endDate, actualDays, opts)
+	if preview != nil {
+		preview.finish()
+	}
+	// Calls made before a failure were billed too
+	recordUsage(output)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	rep.Summary = output
+	rep.Generated = time.Now().In(location)
+	if err := writeReport(rep, *format, *outputPath, outOpts); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+// readEntries reads the period from the archive when there is one and
+// useArchive is set, and from the browsers' databases otherwise. With
+// readOnly the archive is read as it is, without syncing it.
+func readEntries(ctx context.Context, sources []history.HistorySource, since, until *time.Time, useArchive, readOnly bool, timeout time.Duration, verbose bool) ([]history.Entry, error) {
+	archivePath, err := archive.DefaultPath()
+	if err != nil {
+		return nil, err
+	}
+	if useArchive && archive.Exists(archivePath) {
+		if readOnly {
+			return peekArchive(ctx, archivePath, sources, since, until, timeout, verbose)
+		}
+		return readArchive(ctx, archivePath, sources, since, until, timeout, verbose)
+	}
+	return readLive(ctx, sources, since, until, timeout, verbose), nil
+}
+
+// readLive reads the browsers' own databases.
+func readLive(ctx context.Context, sources []history.HistorySource, since, until *time.Time, timeout time.Duration, verbose bool) []history.Entry {
+	results := history.ReadAllHistory(ctx, sources, since, until, timeout)
+	entries, failed := history.MergeResults(results)
+	for _, result := range failed {
+		fmt.Fprintf(os.Stderr, "%s: %v (after %s)\n", result.Source, result.Err, result.Duration.Round(time.Millisecond))
+	}
+	if verbose {
+		for _, result := range results {
+			if result.Err == nil {
+				fmt.Fprintf(os.Stderr, "%s: %d entries in %s\n", result.Source, len(result.Entries), result.Duration.Round(time.Millisecond))
+			}
+		}
+	}
+	return entries
+}
 
-	output, err := summary.TagsSummary(entries, startDate, endDate, actualDays)
+// readArchive brings the archive up to date and reads the period from it, so
+// visits the browsers have already pruned are still included.
+func readArchive(ctx context.Context, path string, sources []history.HistorySource, since, until *time.Time, timeout time.Duration, verbose bool) ([]history.Entry, error) {
+	a, err := archive.Open(path)
 	if err != nil {
+		return nil, err
+	}
+	defer a.Close()
+
+	printSyncResults(a.Sync(ctx, sources, timeout), verbose)
+	names := make([]string, 0, len(sources))
+	for _, source := range sources {
+		names = append(names, source.Name())
+	}
+	return a.Entries(ctx, since, until, names)
+}
+
+// peekArchive reads the period from the archive without writing to it, and
+// adds the visits the browsers have that a sync would archive, so a dry run
+// sees what a real run would.
+func peekArchive(ctx context.Context, path string, sources []history.HistorySource, since, until *time.Time, timeout time.Duration, verbose bool) ([]history.Entry, error) {
+	a, err := archive.OpenReadOnly(path)
+	if err != nil {
+		return nil, err
+	}
+	defer a.Close()
+
+	names := make([]string, 0, len(sources))
+	for _, source := range sources {
+		names = append(names, source.Name())
+	}
+	archived, err := a.Entries(ctx, since, until, names)
+	if err != nil {
+		return nil, err
+	}
+	return archive.Merge(archived, readLive(ctx, sources, since, until, timeout, verbose)), nil
+}
+
+func runSources() {
+	// Paths set in the config file decide where sources are looked for
+	if _, err := loadConfig(defaultConfigPath()); err != nil {
 		fmt.Fprintln(os.Stderr, err)
 		os.Exit(1)
 	}
-	fmt.Println(output)
+	for _, source := range history.Sources() {
+		status := "not found"
+		if source.Available() {
+			status = "available"
+		}
+		fmt.Printf("%-10s %s\n", source.Name(), status)
+	}
 }
 
 func printHelp() {
 	fmt.Println("web-log — browsing history summary")
 	fmt.Println("")
 	fmt.Println("Usage:")
-	fmt.Println("  web-log tags [--days N] [--from YYYY-MM-DD] [--to YYYY-MM-DD]")
+	fmt.Println("  web-log tags [--days N] [--from YYYY-MM-DD] [--to YYYY-MM-DD] [--sources a,b]")
 	fmt.Println("  web-log (same as tags)")
+	fmt.Println("  web-log sources")
+	fmt.Println("  web-log sync [--sources a,b]")
+	fmt.Println("  web-log export [--from YYYY-MM-DD] [--to YYYY-MM-DD] [--format csv|jsonl|sqlite] [--output FILE]")
+	fmt.Println("  web-log cache prune [--older-than 720h] [--all]")
+	fmt.Println("  web-log usage [--month YYYY-MM]")
+	fmt.Println("  web-log rules list | test <url> [--title T] [--time HH:MM]")
+	fmt.Println("  web-log config show")
 	fmt.Println("  web-log version")
 	fmt.Println("")
 	fmt.Println("Examples:")
 	fmt.Println("  web-log")
 	fmt.Println("  web-log tags --days 7")
 	fmt.Println("  web-log tags --from 2026-01-01 --to 2026-01-31")
+	fmt.Println("  web-log tags --sources safari,firefox")
+	fmt.Println("  web-log tags --sources -edge")
+	fmt.Println("  web-log tags --provider ollama --model llama3.1")
+	fmt.Println("  web-log tags --dry-run --prompt-out prompt.md")
+	fmt.Println("  web-log tags --output ~/journal/{end}.md")
+	fmt.Println("  web-log tags --format obsidian --vault ~/Notes")
+	fmt.Println("  web-log tags --format logseq --graph ~/logseq")
+	fmt.Println("  web-log tags --format org --output ~/org/browsing-{end}.org")
+	fmt.Println("  web-log sync")
+	fmt.Println("  web-log export --days 30 --format sqlite --output history.db")
+	fmt.Println("  web-log cache prune --older-than 168h")
+	fmt.Println("  web-log usage --month 2026-10")
+	fmt.Println("  web-log rules test https://mail.google.com/mail/u/0")
 }
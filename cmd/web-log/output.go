@@ -0,0 +1,147 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"web-log/internal/config"
+	"web-log/internal/logseq"
+	"web-log/internal/obsidian"
+	"web-log/internal/report"
+)
+
+const defaultFormat = "markdown"
+
+// formats lists the --format values.
+var formats = []string{"markdown", "json", "html", "org", "obsidian", "logseq"}
+
+// outputOptions are the settings some formats take.
+type outputOptions struct {
+	// includeEntries adds the entries tags reference to JSON output.
+	includeEntries bool
+	// vault is where --format obsidian writes its notes.
+	vault obsidian.Vault
+	// graph is where --format logseq writes its journal pages.
+	graph logseq.Graph
+}
+
+func checkFormat(format string) error {
+	for _, f := range formats {
+		if f == format {
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown format %q (want one of %s)", format, strings.Join(formats, ", "))
+}
+
+// obsidianVault layers the --vault and --note-per flags over the config
+// file's [obsidian] settings.
+func obsidianVault(cfg *config.Config, dir, per string, set map[string]bool) (obsidian.Vault, error) {
+	vault := obsidian.Vault{Dir: dir, Folder: cfg.Obsidian.Folder, Note: cfg.Obsidian.Note}
+	if !set["vault"] {
+		vault.Dir = cfg.Obsidian.Vault
+	}
+	if !set["note-per"] && cfg.Obsidian.NotePer != "" {
+		per = cfg.Obsidian.NotePer
+	}
+	switch per {
+	case "day":
+		vault.PerDay = true
+	case "period":
+	default:
+		return vault, fmt.Errorf("unknown note-per %q (want day or period)", per)
+	}
+	if vault.Dir == "" {
+		return vault, fmt.Errorf("--format obsidian needs a vault: set --vault or vault under [obsidian] in %s", cfg.Path)
+	}
+	return vault, nil
+}
+
+// logseqGraph takes the --graph flag or the config file's [logseq] graph.
+func logseqGraph(cfg *config.Config, dir string, set map[string]bool) (logseq.Graph, error) {
+	if !set["graph"] {
+		dir = cfg.Logseq.Graph
+	}
+	if dir == "" {
+		return logseq.Graph{}, fmt.Errorf("--format logseq needs a graph: set --graph or graph under [logseq] in %s", cfg.Path)
+	}
+	return logseq.Graph{Dir: dir}, nil
+}
+
+// writesNotes reports whether format writes into a notes directory rather
+// than to stdout or --output.
+func writesNotes(format string) bool {
+	return format == "obsidian" || format == "logseq"
+}
+
+// writeReport renders the report and writes it out. Obsidian notes and
+// Logseq journal pages go into their directories; everything else goes
+// where writeOutput puts it.
+func writeReport(r *report.Report, format, path string, opts outputOptions) error {
+	if writesNotes(format) {
+		var paths []string
+		var err error
+		if format == "obsidian" {
+			paths, err = opts.vault.Write(r)
+		} else {
+			paths, err = opts.graph.Write(r)
+		}
+		for _, p := range paths {
+			fmt.Fprintf(os.Stderr, "Note written to %s\n", p)
+		}
+		return err
+	}
+	text, err := render(r, format, opts)
+	if err != nil {
+		return err
+	}
+	return writeOutput(text, path, r.Summary.StartDate, r.Summary.EndDate)
+}
+
+// render turns the report into the requested format.
+func render(r *report.Report, format string, opts outputOptions) (string, error) {
+	switch format {
+	case "markdown":
+		return r.Summary.Markdown(), nil
+	case "json":
+		data, err := r.JSON(opts.includeEntries)
+		return string(data), err
+	case "html":
+		return r.HTML()
+	case "org":
+		return r.Org(), nil
+	}
+	return "", checkFormat(format)
+}
+
+// writeOutput prints text on stdout, or writes it to path with {start} and
+// {end} replaced by the period's dates.
+func writeOutput(text, path, startDate, endDate string) error {
+	if !strings.HasSuffix(text, "\n") {
+		text += "\n"
+	}
+	if path == "" || path == "-" {
+		_, err := os.Stdout.WriteString(text)
+		return err
+	}
+	path = expandOutputPath(path, startDate, endDate)
+	if dir := filepath.Dir(path); dir != "." {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return err
+		}
+	}
+	if err := os.WriteFile(path, []byte(text), 0o644); err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "Summary written to %s\n", path)
+	return nil
+}
+
+// expandOutputPath replaces {start} and {end} in path with the period's
+// dates.
+func expandOutputPath(path, startDate, endDate string) string {
+	path = strings.ReplaceAll(path, "{start}", startDate)
+	return strings.ReplaceAll(path, "{end}", endDate)
+}
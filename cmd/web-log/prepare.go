@@ -0,0 +1,91 @@
+package main
+
+import (
+	"sort"
+	"time"
+
+	"web-log/internal/history"
+	"web-log/internal/redact"
+	"web-log/internal/report"
+	"web-log/internal/rules"
+)
+
+// pipeline configures what happens to entries between reading and
+// prompting.
+type pipeline struct {
+	// location is the timezone visit times are shown in.
+	location         *time.Location
+	includeRedirects bool
+	dedupe           bool
+	rules            *rules.Set
+	// redactor is nil when redaction is turned off.
+	redactor *redact.Redactor
+}
+
+// prepared holds the entries ready for prompting and what each step did.
+type prepared struct {
+	// Read counts the entries read per source.
+	Read       map[string]int
+	Entries    []history.Entry
+	Filtered   []report.Filter
+	Redactions redact.Stats
+}
+
+// prepare moves visit times into the pipeline's timezone, applies the
+// filters in order, reporting what each of them removed, and then redacts
+// what is left.
+func (p pipeline) prepare(entries []history.Entry) prepared {
+	read := map[string]int{}
+	for _, entry := range entries {
+		read[entry.Source]++
+	}
+	stats := []report.Filter{}
+	if p.location != nil {
+		local := make([]history.Entry, len(entries))
+		for i, entry := range entries {
+			entry.VisitTime = entry.VisitTime.In(p.location)
+			local[i] = entry
+		}
+		entries = local
+	}
+	if !p.includeRedirects {
+		removed := map[string]int{}
+		for _, entry := range entries {
+			if !entry.Transition.IsNavigation() {
+				removed[string(entry.Transition)]++
+			}
+		}
+		entries = history.FilterNavigations(entries)
+		stats = append(stats, sortedStats(removed)...)
+	}
+	if p.dedupe {
+		before := len(entries)
+		entries = history.Deduplicate(entries)
+		if removed := before - len(entries); removed > 0 {
+			stats = append(stats, report.Filter{Rule: "duplicate", Removed: removed})
+		}
+	}
+	entries, excluded := p.rules.Filter(entries)
+	stats = append(stats, sortedStats(excluded)...)
+
+	redactions := redact.Stats{}
+	if p.redactor != nil {
+		entries, redactions = p.redactor.Entries(entries)
+	}
+	return prepared{Read: read, Entries: entries, Filtered: stats, Redactions: redactions}
+}
+
+// sortedStats orders per-rule counts by count, then rule.
+func sortedStats(removed map[string]int) []report.Filter {
+	stats := make([]report.Filter, 0, len(removed))
+	for rule, count := range removed {
+		stats = append(stats, report.Filter{Rule: rule, Removed: count})
+	}
+	sort.Slice(stats, func(i, j int) bool {
+		if stats[i].Removed != stats[j].Removed {
+			return stats[i].Removed > stats[j].Removed
+		}
+		return stats[i].Rule < stats[j].Rule
+	})
+	return stats
+}
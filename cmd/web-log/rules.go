@@ -0,0 +1,94 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"web-log/internal/history"
+	"web-log/internal/rules"
+)
+
+func runRules(args []string) {
+	if len(args) == 0 || (args[0] != "list" && args[0] != "test") {
+		fmt.Fprintln(os.Stderr, "Usage: web-log rules list | test <url> [--title T] [--time HH:MM]")
+		os.Exit(1)
+	}
+
+	fs := flag.NewFlagSet("rules "+args[0], flag.ExitOnError)
+	configPath := fs.String("config", defaultConfigPath(), "Config file holding the rules")
+	timezone := fs.String("timezone", "", "Timezone --time and hours rules are read in (default $TZ, the config file or the system's)")
+	title := fs.String("title", "", "Page title to test title rules against")
+	at := fs.String("time", "", "Visit time of day (HH:MM) to test hours rules against (default now)")
+	// Accept the URL before or after the flags
+	rest := args[1:]
+	target := ""
+	if len(rest) > 0 && !strings.HasPrefix(rest[0], "-") {
+		target, rest = rest[0], rest[1:]
+	}
+	if err := fs.Parse(rest); err != nil {
+		os.Exit(1)
+	}
+	if target == "" && fs.NArg() == 1 {
+		target = fs.Arg(0)
+	} else if fs.NArg() > 0 {
+		target = ""
+	}
+	cfg, err := loadConfig(*configPath)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	location, _, err := resolveTimezone(*timezone, cfg)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	set, err := cfg.RuleSet(location)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s: %v\n", cfg.Path, err)
+		os.Exit(1)
+	}
+
+	if args[0] == "list" {
+		fmt.Printf("Rules from %s, checked in order:\n", cfg.Path)
+		for i, rule := range set.Rules() {
+			fmt.Printf("%3d  %-8s %s\n", i+1, rule.Action, describeRule(rule))
+		}
+		return
+	}
+
+	if target == "" {
+		fmt.Fprintln(os.Stderr, "Usage: web-log rules test <url> [--title T] [--time HH:MM]")
+		os.Exit(1)
+	}
+	visit := time.Now().In(location)
+	if *at != "" {
+		t, err := time.Parse("15:04", *at)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "invalid --time %q (want HH:MM)\n", *at)
+			os.Exit(1)
+		}
+		visit = time.Date(visit.Year(), visit.Month(), visit.Day(), t.Hour(), t.Minute(), 0, 0, location)
+	}
+	entry := history.Entry{URL: target, Title: *title, VisitTime: visit}
+	rule, ok := set.Match(entry)
+	switch {
+	case !ok:
+		fmt.Println("included: no rule matches")
+	case rule.Action == rules.Include:
+		fmt.Printf("included by rule %s\n", describeRule(rule))
+	default:
+		fmt.Printf("excluded by rule %s\n", describeRule(rule))
+	}
+}
+
+func describeRule(rule rules.Rule) string {
+	conditions := rule.Conditions()
+	if rule.Name == conditions {
+		return conditions
+	}
+	return rule.Name + " (" + conditions + ")"
+}
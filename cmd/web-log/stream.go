@@ -0,0 +1,83 @@
+package main
+
+import (
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/mattn/go-isatty"
+)
+
+func isTerminal(f *os.File) bool {
+	return isatty.IsTerminal(f.Fd()) || isatty.IsCygwinTerminal(f.Fd())
+}
+
+// streamPreview shows the tail of the model's answer on a single terminal
+// line while it is generated, so there is something to watch during a long
+// call. The line is redrawn in place and cleared again before the rendered
+// summary is printed, and a retry starts it over.
+type streamPreview struct {
+	w     io.Writer
+	width int
+	tail  []rune
+	drawn bool
+}
+
+func newStreamPreview(w io.Writer) *streamPreview {
+	width := 80
+	if columns, err := strconv.Atoi(os.Getenv("COLUMNS")); err == nil && columns > 20 {
+		width = columns
+	}
+	return &streamPreview{w: w, width: width}
+}
+
+const previewPrefix = "answering: "
+
+func (p *streamPreview) Write(b []byte) (int, error) {
+	// Line breaks and control characters (including any escape sequences
+	// the model sends) would break redrawing the line in place
+	text := strings.Map(func(r rune) rune {
+		if r < ' ' || r == 0x7f || r == utf8.RuneError {
+			return ' '
+		}
+		return r
+	}, string(b))
+	p.tail = append(p.tail, []rune(text)...)
+	// Stay one column short of the edge, where terminals wrap, counting
+	// non-ASCII runes as wide since CJK text takes two columns
+	columns := 0
+	for _, r := range p.tail {
+		columns += runeColumns(r)
+	}
+	for keep := p.width - len(previewPrefix) - 1; columns > keep; p.tail = p.tail[1:] {
+		columns -= runeColumns(p.tail[0])
+	}
+	p.drawn = true
+	if _, err := io.WriteString(p.w, "\r\x1b[K"+previewPrefix+string(p.tail)); err != nil {
+		return 0, err
+	}
+	return len(b), nil
+}
+
+func runeColumns(r rune) int {
+	if r < utf8.RuneSelf {
+		return 1
+	}
+	return 2
+}
+
+// Restart drops what a failed attempt streamed; see summary.Restarter.
+func (p *streamPreview) Restart() {
+	p.tail = nil
+	p.finish()
+}
+
+// finish clears the preview line.
+func (p *streamPreview) finish() {
+	if p.drawn {
+		_, _ = io.WriteString(p.w, "\r\x1b[K")
+		p.drawn = false
+	}
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"time"
+
+	"web-log/internal/archive"
+	"web-log/internal/history"
+)
+
+func runSync(args []string) {
+	fs := flag.NewFlagSet("sync", flag.ExitOnError)
+	configPath := fs.String("config", defaultConfigPath(), "Config file (see 'web-log config show')")
+	sourcesFlag := fs.String("sources", "all", "Comma-separated sources to sync, \"-name\" to exclude (see 'web-log sources')")
+	sourceTimeout := fs.Duration("source-timeout", 5*time.Minute, "Give up on a history source after this long (0 = no limit)")
+	if err := fs.Parse(args); err != nil {
+		os.Exit(1)
+	}
+
+	cfg, err := loadConfig(*configPath)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	if !setFlags(fs)["sources"] && cfg.Sources != "" {
+		*sourcesFlag = cfg.Sources
+	}
+	sources, err := history.SelectSources(*sourcesFlag)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	path, err := archive.DefaultPath()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	a, err := archive.Open(path)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	results := a.Sync(ctx, sources, *sourceTimeout)
+	// Closed before exiting, which would skip a deferred Close
+	if err := a.Close(); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	if len(results) == 0 {
+		fmt.Println("No history sources found.")
+		return
+	}
+	failed := printSyncResults(results, true)
+	fmt.Fprintf(os.Stderr, "archive: %s\n", path)
+	if failed > 0 {
+		os.Exit(1)
+	}
+}
+
+// printSyncResults reports failures always and successes when verbose, and
+// returns the number of failed sources.
+func printSyncResults(results []archive.SyncResult, verbose bool) int {
+	failed := 0
+	for _, result := range results {
+		if result.Err != nil {
+			failed++
+			fmt.Fprintf(os.Stderr, "%s: sync failed: %v\n", result.Source, result.Err)
+			continue
+		}
+		if verbose {
+			fmt.Fprintf(os.Stderr, "%s: %d new of %d read in %s, up to %s\n", result.Source, result.Added, result.Read,
+				result.Duration.Round(time.Millisecond), result.HighWater.Local().Format("2006-01-02 15:04"))
+		}
+	}
+	return failed
+}
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"web-log/internal/summary"
+	"web-log/internal/usage"
+)
+
+func runUsage(args []string) {
+	fs := flag.NewFlagSet("usage", flag.ExitOnError)
+	month := fs.String("month", time.Now().Format("2006-01"), "Month to total (YYYY-MM)")
+	if err := fs.Parse(args); err != nil {
+		os.Exit(1)
+	}
+
+	start, err := time.ParseInLocation("2006-01", *month, time.Local)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "invalid --month %q (want YYYY-MM)\n", *month)
+		os.Exit(1)
+	}
+	path, err := usage.DefaultPath()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	records, err := usage.Read(path)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	totals := usage.Totals(usage.Between(records, start, start.AddDate(0, 1, 0)))
+	if len(totals) == 0 {
+		fmt.Printf("No usage recorded for %s.\n", *month)
+		return
+	}
+	fmt.Printf("Usage for %s\n\n", *month)
+	fmt.Printf("%-12s %-40s %5s %6s %12s %12s %10s\n", "PROVIDER", "MODEL", "RUNS", "CALLS", "PROMPT", "COMPLETION", "COST")
+	var sum usage.Total
+	for _, total := range totals {
+		fmt.Printf("%-12s %-40s %5d %6d %12d %12d %10s\n", total.Provider, total.Model, total.Runs, total.Calls,
+			total.PromptTokens, total.CompletionTokens, formatCost(total))
+		sum.Runs += total.Runs
+		sum.Calls += total.Calls
+		sum.PromptTokens += total.PromptTokens
+		sum.CompletionTokens += total.CompletionTokens
+		sum.Cost += total.Cost
+		sum.UnpricedRuns += total.UnpricedRuns
+	}
+	fmt.Printf("%-12s %-40s %5d %6d %12d %12d %10s\n", "total", "", sum.Runs, sum.Calls,
+		sum.PromptTokens, sum.CompletionTokens, formatCost(sum))
+	if sum.UnpricedRuns > 0 {
+		fmt.Printf("\n%d runs had no cost reported by their provider and are not included in COST.\n", sum.UnpricedRuns)
+	}
+}
+
+func formatCost(total usage.Total) string {
+	if total.UnpricedRuns == total.Runs {
+		return "-"
+	}
+	return fmt.Sprintf("$%.4f", total.Cost)
+}
+
+// recordUsage prints a one-line usage report for a summary on stderr and
+// appends what it cost to the usage ledger. Cached answers cost nothing and
+// are not recorded.
+func recordUsage(s *summary.Summary) {
+	if len(s.Calls) == 0 {
+		return
+	}
+	period := s.StartDate + " to " + s.EndDate
+	now := time.Now().UTC()
+
+	cached := 0
+	var total summary.Usage
+	records := []usage.Record{}
+	index := map[string]int{}
+	for _, call := range s.Calls {
+		if call.Usage.Cached {
+			cached++
+			continue
+		}
+		total = total.Add(call.Usage)
+		key := call.Provider + "\x00" + call.Model
+		i, ok := index[key]
+		if !ok {
+			i = len(records)
+			index[key] = i
+			records = append(records, usage.Record{Time: now, Provider: call.Provider, Model: call.Model, Period: period})
+		}
+		record := &records[i]
+		record.Calls++
+		record.PromptTokens += call.Usage.PromptTokens
+		record.CompletionTokens += call.Usage.CompletionTokens
+		if call.Usage.Cost != nil {
+			cost := *call.Usage.Cost
+			if record.Cost != nil {
+				cost += *record.Cost
+			}
+			record.Cost = &cost
+		}
+	}
+
+	line := fmt.Sprintf("usage: %d calls", len(s.Calls))
+	if cached > 0 {
+		line += fmt.Sprintf(" (%d cached)", cached)
+	}
+	line += fmt.Sprintf(", %d prompt + %d completion tokens", total.PromptTokens, total.CompletionTokens)
+	if total.Cost != nil {
+		line += fmt.Sprintf(", $%.4f", *total.Cost)
+	} else if len(records) > 0 {
+		line += ", cost not reported"
+	}
+	fmt.Fprintln(os.Stderr, line)
+
+	path, err := usage.DefaultPath()
+	if err == nil {
+		err = usage.Append(path, records)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "usage ledger: %v\n", err)
+	}
+}
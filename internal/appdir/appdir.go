@@ -0,0 +1,65 @@
+// Package appdir locates web-log's per-user directories.
+package appdir
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+)
+
+// Data returns the directory for files web-log owns, such as the archive:
+// ~/Library/Application Support/web-log on macOS, %LOCALAPPDATA%\web-log on
+// Windows and $XDG_DATA_HOME/web-log (~/.local/share/web-log) elsewhere.
+func Data() (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	switch runtime.GOOS {
+	case "darwin":
+		return filepath.Join(home, "Library", "Application Support", "web-log"), nil
+	case "windows":
+		base := os.Getenv("LOCALAPPDATA")
+		if base == "" {
+			base = filepath.Join(home, "AppData", "Local")
+		}
+		return filepath.Join(base, "web-log"), nil
+	default:
+		base := os.Getenv("XDG_DATA_HOME")
+		if base == "" {
+			base = filepath.Join(home, ".local", "share")
+		}
+		return filepath.Join(base, "web-log"), nil
+	}
+}
+
+// Cache returns the directory for files that can be recreated at a cost:
+// ~/Library/Caches/web-log on macOS, %LOCALAPPDATA%\web-log on Windows and
+// $XDG_CACHE_HOME/web-log (~/.cache/web-log) elsewhere.
+func Cache() (string, error) {
+	base, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(base, "web-log"), nil
+}
+
+// Config returns the directory for user settings: $XDG_CONFIG_HOME/web-log,
+// falling back to ~/.config/web-log, or %APPDATA%\web-log on Windows.
+func Config() (string, error) {
+	if base := os.Getenv("XDG_CONFIG_HOME"); base != "" {
+		return filepath.Join(base, "web-log"), nil
+	}
+	if runtime.GOOS == "windows" {
+		base, err := os.UserConfigDir()
+		if err != nil {
+			return "", err
+		}
+		return filepath.Join(base, "web-log"), nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, ".config", "web-log"), nil
+}
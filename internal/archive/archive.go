@@ -0,0 +1,325 @@
+// Package archive keeps a local copy of browsing history owned by web-log,
+// so periods the browsers have already pruned can still be summarized.
+package archive
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"web-log/internal/appdir"
+	"web-log/internal/history"
+
+	_ "modernc.org/sqlite"
+)
+
+// schema keys visits on the profile directory rather than its display name,
+// which can be renamed and need not be unique.
+const schema = `
+CREATE TABLE IF NOT EXISTS visits (
+	source      TEXT    NOT NULL,
+	profile     TEXT    NOT NULL DEFAULT '',
+	profile_dir TEXT    NOT NULL DEFAULT '',
+	visit_id    INTEGER NOT NULL,
+	url         TEXT    NOT NULL,
+	title       TEXT    NOT NULL DEFAULT '',
+	visit_time  INTEGER NOT NULL,
+	transition  TEXT    NOT NULL DEFAULT '',
+	duration    INTEGER NOT NULL DEFAULT 0,
+	from_visit  INTEGER NOT NULL DEFAULT 0,
+	referrer    TEXT    NOT NULL DEFAULT '',
+	search_term TEXT    NOT NULL DEFAULT '',
+	PRIMARY KEY (source, profile_dir, visit_id)
+);
+CREATE INDEX IF NOT EXISTS visits_visit_time ON visits (visit_time);
+CREATE TABLE IF NOT EXISTS sync_state (
+	source     TEXT    PRIMARY KEY,
+	high_water INTEGER NOT NULL,
+	synced_at  INTEGER NOT NULL
+);
+`
+
+type Archive struct {
+	db *sql.DB
+}
+
+// SyncResult reports what one source contributed to a sync.
+type SyncResult struct {
+	Source    string
+	Read      int
+	Added     int
+	HighWater time.Time
+	Duration  time.Duration
+	Err       error
+}
+
+// DefaultPath returns the archive location in the per-user data directory
+// (see appdir.Data).
+func DefaultPath() (string, error) {
+	dir, err := appdir.Data()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "archive.db"), nil
+}
+
+// Exists reports whether an archive has been created at path.
+func Exists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
+
+// Open opens the archive at path, creating it and its directory if needed.
+func Open(path string) (*Archive, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return nil, err
+	}
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		return nil, err
+	}
+	// A single connection keeps writes serialized without SQLITE_BUSY.
+	db.SetMaxOpenConns(1)
+	if _, err := db.Exec(schema); err != nil {
+		_ = db.Close()
+		return nil, err
+	}
+	return &Archive{db: db}, nil
+}
+
+// OpenReadOnly opens an existing archive without writing to it: nothing is
+// created or synced.
+func OpenReadOnly(path string) (*Archive, error) {
+	uri := &url.URL{Scheme: "file", Path: path, RawQuery: "mode=ro"}
+	db, err := sql.Open("sqlite", uri.String())
+	if err != nil {
+		return nil, err
+	}
+	return &Archive{db: db}, nil
+}
+
+func (a *Archive) Close() error {
+	return a.db.Close()
+}
+
+// HighWater returns the newest visit time archived for source.
+func (a *Archive) HighWater(ctx context.Context, source string) (time.Time, bool, error) {
+	var value int64
+	err := a.db.QueryRowContext(ctx, "SELECT high_water FROM sync_state WHERE source = ?", source).Scan(&value)
+	if err == sql.ErrNoRows {
+		return time.Time{}, false, nil
+	}
+	if err != nil {
+		return time.Time{}, false, err
+	}
+	return time.UnixMicro(value).UTC(), true, nil
+}
+
+// Add stores entries for source, ignoring visits that are already archived,
+// and advances the source's high-water mark. It returns how many visits were
+// new.
+func (a *Archive) Add(ctx context.Context, source string, entries []history.Entry) (int, error) {
+	return a.add(ctx, source, entries, true)
+}
+
+// add stores entries, and advances the high-water mark when advance is set.
+func (a *Archive) add(ctx context.Context, source string, entries []history.Entry, advance bool) (int, error) {
+	tx, err := a.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	stmt, err := tx.PrepareContext(ctx, "INSERT OR IGNORE INTO visits (source, profile, profile_dir, visit_id, url, title, visit_time, transition, duration, from_visit, referrer, search_term) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)")
+	if err != nil {
+		return 0, err
+	}
+	defer stmt.Close()
+
+	added := 0
+	var highWater int64
+	for _, entry := range entries {
+		visitTime := entry.VisitTime.UnixMicro()
+		res, err := stmt.ExecContext(ctx, source, entry.Profile, entry.ProfileDir, entry.VisitID, entry.URL, entry.Title, visitTime,
+			string(entry.Transition), entry.Duration.Microseconds(), entry.FromVisit, entry.Referrer, entry.SearchTerm)
+		if err != nil {
+			return 0, err
+		}
+		n, err := res.RowsAffected()
+		if err != nil {
+			return 0, err
+		}
+		added += int(n)
+		if visitTime > highWater {
+			highWater = visitTime
+		}
+	}
+
+	if advance && highWater > 0 {
+		_, err := tx.ExecContext(ctx, `INSERT INTO sync_state (source, high_water, synced_at) VALUES (?, ?, ?)
+			ON CONFLICT(source) DO UPDATE SET high_water = MAX(high_water, excluded.high_water), synced_at = excluded.synced_at`,
+			source, highWater, time.Now().UnixMicro())
+		if err != nil {
+			return 0, err
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+	return added, nil
+}
+
+// Sync copies new visits from every available source into the archive.
+// Each source is read from its own high-water mark onwards; the boundary
+// visit is read again and skipped by the primary key. Sources are read
+// concurrently and written one at a time. When only some of a source's
+// profiles could be read, what was read is stored but the high-water mark
+// stays put, so the next sync reads the other profiles' visits again.
+func (a *Archive) Sync(ctx context.Context, sources []history.HistorySource, timeout time.Duration) []SyncResult {
+	available := []history.HistorySource{}
+	for _, source := range sources {
+		if source.Available() {
+			available = append(available, source)
+		}
+	}
+
+	results := make([]SyncResult, len(available))
+	reads := make([]history.SourceResult, len(available))
+	var wg sync.WaitGroup
+	for i, source := range available {
+		results[i].Source = source.Name()
+		highWater, ok, err := a.HighWater(ctx, source.Name())
+		if err != nil {
+			results[i].Err = err
+			continue
+		}
+		var since *time.Time
+		if ok {
+			since = &highWater
+		}
+		wg.Add(1)
+		go func(i int, source history.HistorySource) {
+			defer wg.Done()
+			reads[i] = history.ReadSource(ctx, source, since, nil, timeout)
+		}(i, source)
+	}
+	wg.Wait()
+
+	for i := range results {
+		if results[i].Err != nil {
+			continue
+		}
+		read := reads[i]
+		results[i].Duration = read.Duration
+		var partial *history.PartialError
+		if read.Err != nil && !errors.As(read.Err, &partial) {
+			results[i].Err = read.Err
+			continue
+		}
+		results[i].Read = len(read.Entries)
+		added, err := a.add(ctx, read.Source, read.Entries, partial == nil)
+		if err != nil {
+			results[i].Err = err
+			continue
+		}
+		results[i].Added = added
+		results[i].HighWater, _, results[i].Err = a.HighWater(ctx, read.Source)
+		if partial != nil {
+			results[i].Err = partial
+		}
+	}
+	return results
+}
+
+// Entries returns archived visits in [since, until) for the given sources
+// (all sources when empty), newest first.
+func (a *Archive) Entries(ctx context.Context, since *time.Time, until *time.Time, sources []string) ([]history.Entry, error) {
+	query := "SELECT source, profile, profile_dir, visit_id, url, title, visit_time, transition, duration, from_visit, referrer, search_term FROM visits"
+	args := []any{}
+	conditions := []string{}
+	if since != nil {
+		conditions = append(conditions, "visit_time >= ?")
+		args = append(args, since.UnixMicro())
+	}
+	if until != nil {
+		conditions = append(conditions, "visit_time < ?")
+		args = append(args, until.UnixMicro())
+	}
+	if len(sources) > 0 {
+		placeholders := ""
+		for i, source := range sources {
+			if i > 0 {
+				placeholders += ", "
+			}
+			placeholders += "?"
+			args = append(args, source)
+		}
+		conditions = append(conditions, "source IN ("+placeholders+")")
+	}
+	for i, condition := range conditions {
+		if i == 0 {
+			query += " WHERE " + condition
+		} else {
+			query += " AND " + condition
+		}
+	}
+	query += " ORDER BY visit_time DESC"
+
+	rows, err := a.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	entries := []history.Entry{}
+	for rows.Next() {
+		var entry history.Entry
+		var visitTime int64
+		var transition string
+		var duration int64
+		if err := rows.Scan(&entry.Source, &entry.Profile, &entry.ProfileDir, &entry.VisitID, &entry.URL, &entry.Title, &visitTime,
+			&transition, &duration, &entry.FromVisit, &entry.Referrer, &entry.SearchTerm); err != nil {
+			return nil, err
+		}
+		entry.VisitTime = time.UnixMicro(visitTime).UTC()
+		entry.Transition = history.Transition(transition)
+		entry.Duration = time.Duration(duration) * time.Microsecond
+		entries = append(entries, entry)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+// Merge adds to archived entries the visits in live that are not archived
+// yet, matched the way Sync matches them, without writing anything. The
+// result is newest first, like Entries.
+func Merge(archived []history.Entry, live []history.Entry) []history.Entry {
+	type visitKey struct {
+		source, profileDir string
+		visitID            int64
+	}
+	seen := map[visitKey]bool{}
+	merged := append([]history.Entry{}, archived...)
+	for _, entry := range archived {
+		seen[visitKey{entry.Source, entry.ProfileDir, entry.VisitID}] = true
+	}
+	for _, entry := range live {
+		if !seen[visitKey{entry.Source, entry.ProfileDir, entry.VisitID}] {
+			merged = append(merged, entry)
+		}
+	}
+	sort.SliceStable(merged, func(i, j int) bool {
+		return merged[i].VisitTime.After(merged[j].VisitTime)
+	})
+	return merged
+}
@@ -0,0 +1,149 @@
+package archive
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"web-log/internal/history"
+)
+
+type fakeSource struct {
+	entries []history.Entry
+	err     error
+}
+
+func (f *fakeSource) Name() string    { return "fake" }
+func (f *fakeSource) Available() bool { return true }
+func (f *fakeSource) Read(ctx context.Context, since, until *time.Time) ([]history.Entry, error) {
+	return f.entries, f.err
+}
+
+func openTemp(t *testing.T) (*Archive, string) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "archive.db")
+	a, err := Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { a.Close() })
+	return a, path
+}
+
+func visit(dir, name string, id int64, at time.Time) history.Entry {
+	return history.Entry{URL: "https://example.com/", Source: "fake", Profile: name, ProfileDir: dir, VisitID: id, VisitTime: at}
+}
+
+func TestAddKeysOnProfileDir(t *testing.T) {
+	a, _ := openTemp(t)
+	ctx := context.Background()
+	at := time.Date(2026, 10, 1, 9, 0, 0, 0, time.UTC)
+
+	// Two profiles called "Work" with the same visit id are different visits
+	added, err := a.Add(ctx, "fake", []history.Entry{visit("Default", "Work", 1, at), visit("Profile 1", "Work", 1, at)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if added != 2 {
+		t.Errorf("added %d, want 2", added)
+	}
+
+	// Renaming a profile does not archive its visits again
+	added, err = a.Add(ctx, "fake", []history.Entry{visit("Default", "Personal", 1, at)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if added != 0 {
+		t.Errorf("added %d after rename, want 0", added)
+	}
+}
+
+func TestSyncPartialReadKeepsHighWater(t *testing.T) {
+	a, _ := openTemp(t)
+	ctx := context.Background()
+	at := time.Date(2026, 10, 1, 9, 0, 0, 0, time.UTC)
+	source := &fakeSource{
+		entries: []history.Entry{visit("Default", "Personal", 1, at)},
+		err:     &history.PartialError{Err: errors.New("database is locked")},
+	}
+
+	results := a.Sync(ctx, []history.HistorySource{source}, 0)
+	if len(results) != 1 || results[0].Err == nil {
+		t.Fatalf("partial read should be reported, got %+v", results)
+	}
+	if results[0].Added != 1 {
+		t.Errorf("added %d, want the 1 visit that was read", results[0].Added)
+	}
+	if _, ok, err := a.HighWater(ctx, "fake"); err != nil || ok {
+		t.Errorf("high-water mark set after a partial read (ok=%v, err=%v)", ok, err)
+	}
+
+	source.err = nil
+	source.entries = append(source.entries, visit("Profile 1", "Work", 1, at.Add(time.Hour)))
+	results = a.Sync(ctx, []history.HistorySource{source}, 0)
+	if results[0].Err != nil {
+		t.Fatal(results[0].Err)
+	}
+	if results[0].Added != 1 {
+		t.Errorf("added %d, want the other profile's visit", results[0].Added)
+	}
+	highWater, ok, err := a.HighWater(ctx, "fake")
+	if err != nil || !ok || !highWater.Equal(at.Add(time.Hour)) {
+		t.Errorf("high-water mark = %v, %v, %v; want %v", highWater, ok, err, at.Add(time.Hour))
+	}
+}
+
+func TestOpenReadOnlyWritesNothing(t *testing.T) {
+	a, path := openTemp(t)
+	at := time.Date(2026, 10, 1, 9, 0, 0, 0, time.UTC)
+	if _, err := a.Add(context.Background(), "fake", []history.Entry{visit("Default", "Personal", 1, at)}); err != nil {
+		t.Fatal(err)
+	}
+	a.Close()
+	before, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ro, err := OpenReadOnly(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	entries, err := ro.Entries(context.Background(), nil, nil, nil)
+	if err != nil || len(entries) != 1 {
+		t.Fatalf("entries = %v, %v; want the archived visit", entries, err)
+	}
+	if _, err := ro.Add(context.Background(), "fake", []history.Entry{visit("Default", "Personal", 2, at)}); err == nil {
+		t.Error("Add succeeded on a read-only archive")
+	}
+	ro.Close()
+
+	after, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(before, after) {
+		t.Error("archive changed after a read-only open")
+	}
+}
+
+func TestMerge(t *testing.T) {
+	at := time.Date(2026, 10, 1, 9, 0, 0, 0, time.UTC)
+	archived := []history.Entry{visit("Default", "Personal", 1, at), visit("Profile 1", "Work", 1, at)}
+	live := []history.Entry{
+		visit("Default", "Personal", 1, at),
+		visit("Default", "Personal", 2, at.Add(time.Hour)),
+		visit("Profile 2", "Personal", 1, at.Add(-time.Hour)),
+	}
+	merged := Merge(archived, live)
+	if len(merged) != 4 {
+		t.Fatalf("got %d entries, want 4: %+v", len(merged), merged)
+	}
+	if merged[0].VisitID != 2 || merged[3].ProfileDir != "Profile 2" {
+		t.Errorf("merged entries not newest first: %+v", merged)
+	}
+}
@@ -0,0 +1,181 @@
+// Package cache stores model responses on disk, so summarizing the same
+// history with the same prompt and model again needs no API call.
+package cache
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"web-log/internal/appdir"
+)
+
+// Key identifies a response. Responses are only reused for the same
+// provider, model list, prompt and prompt template version.
+type Key struct {
+	Provider        string
+	Model           string
+	Prompt          string
+	TemplateVersion string
+}
+
+// PromptHash is the hex SHA-256 of the prompt.
+func (k Key) PromptHash() string {
+	sum := sha256.Sum256([]byte(k.Prompt))
+	return hex.EncodeToString(sum[:])
+}
+
+func (k Key) id() string {
+	sum := sha256.Sum256([]byte(strings.Join([]string{k.Provider, k.Model, k.TemplateVersion, k.PromptHash()}, "\x00")))
+	return hex.EncodeToString(sum[:])
+}
+
+// Entry is a cached response with what it was cached for.
+type Entry struct {
+	Provider        string    `json:"provider"`
+	Model           string    `json:"model"`
+	PromptHash      string    `json:"prompt_hash"`
+	TemplateVersion string    `json:"template_version"`
+	CreatedAt       time.Time `json:"created_at"`
+	// ResponseModel is the model that actually answered, which differs from
+	// Model when a fallback was used.
+	ResponseModel string `json:"response_model"`
+	FinishReason  string `json:"finish_reason"`
+	Response      string `json:"response"`
+}
+
+type Store struct {
+	dir string
+}
+
+// DefaultDir returns the response cache location in the per-user cache
+// directory (see appdir.Cache).
+func DefaultDir() (string, error) {
+	dir, err := appdir.Cache()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "responses"), nil
+}
+
+// Open returns the store in dir. The directory is created on the first Put.
+func Open(dir string) *Store {
+	return &Store{dir: dir}
+}
+
+func (s *Store) Dir() string {
+	return s.dir
+}
+
+func (s *Store) path(key Key) string {
+	return filepath.Join(s.dir, key.id()+".json")
+}
+
+// Get returns the cached response for key. A missing or unreadable entry is
+// a miss.
+func (s *Store) Get(key Key) (Entry, bool) {
+	data, err := os.ReadFile(s.path(key))
+	if err != nil {
+		return Entry{}, false
+	}
+	var entry Entry
+	if err := json.Unmarshal(data, &entry); err != nil || entry.Response == "" {
+		return Entry{}, false
+	}
+	return entry, true
+}
+
+// Put stores entry under key, filling in the key fields and creation time.
+func (s *Store) Put(key Key, entry Entry) error {
+	entry.Provider = key.Provider
+	entry.Model = key.Model
+	entry.PromptHash = key.PromptHash()
+	entry.TemplateVersion = key.TemplateVersion
+	if entry.CreatedAt.IsZero() {
+		entry.CreatedAt = time.Now().UTC()
+	}
+	data, err := json.MarshalIndent(entry, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(s.dir, 0o700); err != nil {
+		return err
+	}
+
+	// Write to a temporary file first so readers never see half an entry
+	tmp, err := os.CreateTemp(s.dir, ".entry-*")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), s.path(key))
+}
+
+// Delete removes the entry for key, if there is one.
+func (s *Store) Delete(key Key) error {
+	if err := os.Remove(s.path(key)); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+// Prune removes entries created before cutoff and returns how many were
+// removed and the bytes freed. A zero cutoff removes everything.
+func (s *Store) Prune(cutoff time.Time) (int, int64, error) {
+	files, err := os.ReadDir(s.dir)
+	if errors.Is(err, fs.ErrNotExist) {
+		return 0, 0, nil
+	}
+	if err != nil {
+		return 0, 0, err
+	}
+
+	removed := 0
+	var freed int64
+	for _, file := range files {
+		if file.IsDir() {
+			continue
+		}
+		info, err := file.Info()
+		if err != nil {
+			continue
+		}
+		path := filepath.Join(s.dir, file.Name())
+		if !cutoff.IsZero() && !created(path, info).Before(cutoff) {
+			continue
+		}
+		if err := os.Remove(path); err != nil {
+			return removed, freed, err
+		}
+		removed++
+		freed += info.Size()
+	}
+	return removed, freed, nil
+}
+
+// created reads an entry's creation time, falling back to the file's
+// modification time for leftovers that do not parse.
+func created(path string, info fs.FileInfo) time.Time {
+	data, err := os.ReadFile(path)
+	if err == nil {
+		var entry Entry
+		if json.Unmarshal(data, &entry) == nil && !entry.CreatedAt.IsZero() {
+			return entry.CreatedAt
+		}
+	}
+	return info.ModTime()
+}
@@ -0,0 +1,108 @@
+package cache
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestKeyChanges(t *testing.T) {
+	base := Key{Provider: "openai", Model: "gpt-4o-mini", Prompt: "Summarize this", TemplateVersion: "3"}
+	tests := []struct {
+		name string
+		key  Key
+	}{
+		{"provider", Key{Provider: "openrouter", Model: base.Model, Prompt: base.Prompt, TemplateVersion: base.TemplateVersion}},
+		{"model", Key{Provider: base.Provider, Model: "gpt-4o", Prompt: base.Prompt, TemplateVersion: base.TemplateVersion}},
+		{"fallback models", Key{Provider: base.Provider, Model: "gpt-4o-mini,gpt-4o", Prompt: base.Prompt, TemplateVersion: base.TemplateVersion}},
+		{"prompt", Key{Provider: base.Provider, Model: base.Model, Prompt: "Summarize this!", TemplateVersion: base.TemplateVersion}},
+		{"template version", Key{Provider: base.Provider, Model: base.Model, Prompt: base.Prompt, TemplateVersion: "4"}},
+	}
+	store := Open(t.TempDir())
+	if err := store.Put(base, Entry{Response: "cached"}); err != nil {
+		t.Fatal(err)
+	}
+	for _, test := range tests {
+		if test.key.id() == base.id() {
+			t.Errorf("%s: changing it kept the key", test.name)
+		}
+		if _, ok := store.Get(test.key); ok {
+			t.Errorf("%s: changing it still hit the cache", test.name)
+		}
+	}
+	entry, ok := store.Get(base)
+	if !ok || entry.Response != "cached" {
+		t.Fatalf("Get(base) = %+v, %v, want the stored response", entry, ok)
+	}
+	if entry.Provider != base.Provider || entry.Model != base.Model || entry.PromptHash != base.PromptHash() || entry.TemplateVersion != base.TemplateVersion {
+		t.Errorf("entry = %+v, want the key's fields filled in", entry)
+	}
+}
+
+func TestGetMisses(t *testing.T) {
+	store := Open(t.TempDir())
+	key := Key{Provider: "openai", Model: "gpt-4o", Prompt: "p"}
+	if _, ok := store.Get(key); ok {
+		t.Errorf("hit in an empty store")
+	}
+	for _, content := range []string{"{", `{"response": ""}`} {
+		if err := os.WriteFile(store.path(key), []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		if _, ok := store.Get(key); ok {
+			t.Errorf("%q: hit, want a miss", content)
+		}
+	}
+	if err := store.Delete(key); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Delete(key); err != nil {
+		t.Errorf("deleting a missing entry: %v", err)
+	}
+}
+
+func TestPrune(t *testing.T) {
+	store := Open(filepath.Join(t.TempDir(), "responses"))
+	if removed, freed, err := store.Prune(time.Now()); err != nil || removed != 0 || freed != 0 {
+		t.Fatalf("missing directory: got %d, %d, %v, want nothing to do", removed, freed, err)
+	}
+
+	now := time.Date(2026, 10, 17, 12, 0, 0, 0, time.UTC)
+	keys := map[string]Key{}
+	for name, age := range map[string]time.Duration{"old": 40 * 24 * time.Hour, "edge": 30 * 24 * time.Hour, "fresh": time.Hour} {
+		key := Key{Provider: "openai", Model: "gpt-4o", Prompt: name}
+		keys[name] = key
+		if err := store.Put(key, Entry{Response: name, CreatedAt: now.Add(-age)}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// A leftover that does not parse is aged by its modification time.
+	leftover := filepath.Join(store.Dir(), "leftover.json")
+	if err := os.WriteFile(leftover, []byte("{"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chtimes(leftover, now.AddDate(0, 0, -60), now.AddDate(0, 0, -60)); err != nil {
+		t.Fatal(err)
+	}
+
+	removed, freed, err := store.Prune(now.AddDate(0, 0, -30))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if removed != 2 || freed <= 0 {
+		t.Errorf("removed %d entries and %d bytes, want the old entry and the leftover", removed, freed)
+	}
+	for name, want := range map[string]bool{"old": false, "edge": true, "fresh": true} {
+		if _, ok := store.Get(keys[name]); ok != want {
+			t.Errorf("%s: cached = %v after pruning, want %v", name, ok, want)
+		}
+	}
+	if _, err := os.Stat(leftover); err == nil {
+		t.Errorf("the leftover survived pruning")
+	}
+
+	if removed, _, err := store.Prune(time.Time{}); err != nil || removed != 2 {
+		t.Errorf("zero cutoff: removed %d, %v, want the 2 remaining entries", removed, err)
+	}
+}
@@ -0,0 +1,205 @@
+// Package config reads web-log's settings file.
+//
+// Settings are resolved in order of precedence: command-line flags, then
+// environment variables, then the config file, then built-in defaults. This
+// package only reads the file; callers layer flags and the environment on
+// top.
+package config
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+
+	"web-log/internal/appdir"
+	"web-log/internal/rules"
+)
+
+// Config is the layout of config.toml. Zero values mean "not set".
+type Config struct {
+	Provider string `toml:"provider"`
+	// Providers holds per-provider settings keyed by provider name.
+	Providers map[string]Provider `toml:"providers"`
+	// Sources is a --sources value such as "all,-edge".
+	Sources string `toml:"sources"`
+	// Paths overrides where a source's data lives, keyed by source name.
+	Paths map[string]string `toml:"paths"`
+	// Timezone is an IANA name such as "Europe/Zurich"; days, times in the
+	// prompt and rule hours are read in it.
+	Timezone string `toml:"timezone"`
+	// PromptTemplate is a file whose text replaces the built-in grouping
+	// guidance of the prompt.
+	PromptTemplate string   `toml:"prompt_template"`
+	Output         Output   `toml:"output"`
+	Obsidian       Obsidian `toml:"obsidian"`
+	Logseq         Logseq   `toml:"logseq"`
+	Redact         Redact   `toml:"redact"`
+	// DefaultRules keeps the built-in exclusion rules after the user's; on
+	// unless set to false.
+	DefaultRules *bool        `toml:"default_rules"`
+	Rules        []rules.Rule `toml:"rule"`
+
+	// Path is where the config was read from, and Found whether it existed.
+	Path  string `toml:"-"`
+	Found bool   `toml:"-"`
+}
+
+type Provider struct {
+	// Model may list several comma-separated models to fall back through.
+	Model   string `toml:"model"`
+	BaseURL string `toml:"base_url"`
+}
+
+type Output struct {
+	Format string `toml:"format"`
+	// Path is a file to write the summary to instead of stdout; {start} and
+	// {end} are replaced with the period's dates.
+	Path string `toml:"path"`
+	// IncludeEntries adds the entries tags reference to JSON output.
+	IncludeEntries *bool `toml:"include_entries"`
+}
+
+// Obsidian configures --format obsidian, which writes notes into a vault.
+type Obsidian struct {
+	Vault string `toml:"vault"`
+	// Folder is where notes go inside the vault, such as "Daily".
+	Folder string `toml:"folder"`
+	// Note names the note file without ".md"; {date}, {start} and {end} are
+	// replaced with dates.
+	Note string `toml:"note"`
+	// NotePer is "day" for a note per day or "period" for one note per run.
+	NotePer string `toml:"note_per"`
+}
+
+// Logseq configures --format logseq, which writes journal pages into a
+// graph.
+type Logseq struct {
+	Graph string `toml:"graph"`
+}
+
+type Redact struct {
+	Enabled *bool `toml:"enabled"`
+	// Patterns are regular expressions masked on top of the built-in rules.
+	Patterns []string `toml:"patterns"`
+}
+
+// DefaultPath returns $WEBLOG_CONFIG, or config.toml in the config
+// directory (see appdir.Config).
+func DefaultPath() (string, error) {
+	if path := os.Getenv("WEBLOG_CONFIG"); path != "" {
+		return path, nil
+	}
+	dir, err := appdir.Config()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "config.toml"), nil
+}
+
+// Load reads the config at path. A missing file gives an empty Config, so
+// every setting falls through to its default; unknown keys are an error, so
+// typos do not go unnoticed.
+func Load(path string) (*Config, error) {
+	cfg := &Config{Path: path}
+	if path == "" {
+		return cfg, nil
+	}
+	meta, err := toml.DecodeFile(path, cfg)
+	if errors.Is(err, fs.ErrNotExist) {
+		return cfg, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	cfg.Found = true
+	if undecoded := meta.Undecoded(); len(undecoded) > 0 {
+		keys := make([]string, len(undecoded))
+		for i, key := range undecoded {
+			keys[i] = key.String()
+		}
+		return nil, fmt.Errorf("%s: unknown settings: %s", path, strings.Join(keys, ", "))
+	}
+
+	cfg.PromptTemplate = expandHome(cfg.PromptTemplate)
+	cfg.Output.Path = expandHome(cfg.Output.Path)
+	cfg.Obsidian.Vault = expandHome(cfg.Obsidian.Vault)
+	cfg.Logseq.Graph = expandHome(cfg.Logseq.Graph)
+	for name, p := range cfg.Paths {
+		cfg.Paths[name] = expandHome(p)
+	}
+	if _, err := cfg.Location(); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return cfg, nil
+}
+
+// Location returns the configured timezone, or nil when none is set.
+func (c *Config) Location() (*time.Location, error) {
+	if c.Timezone == "" {
+		return nil, nil
+	}
+	location, err := time.LoadLocation(c.Timezone)
+	if err != nil {
+		return nil, fmt.Errorf("timezone %q: %w", c.Timezone, err)
+	}
+	return location, nil
+}
+
+// RuleSet compiles the user's rules followed, unless turned off, by the
+// built-in ones.
+func (c *Config) RuleSet(location *time.Location) (*rules.Set, error) {
+	list := append([]rules.Rule{}, c.Rules...)
+	if c.DefaultRules == nil || *c.DefaultRules {
+		list = append(list, rules.Defaults...)
+	}
+	return rules.New(list, location)
+}
+
+// ProviderModels returns the configured models keyed by lowercase provider
+// name.
+func (c *Config) ProviderModels() map[string]string {
+	models := map[string]string{}
+	for name, p := range c.Providers {
+		models[strings.ToLower(name)] = p.Model
+	}
+	return models
+}
+
+// ProviderBaseURLs returns the configured base URLs keyed by lowercase
+// provider name.
+func (c *Config) ProviderBaseURLs() map[string]string {
+	urls := map[string]string{}
+	for name, p := range c.Providers {
+		urls[strings.ToLower(name)] = p.BaseURL
+	}
+	return urls
+}
+
+// PathNames returns the sources with a configured path, sorted.
+func (c *Config) PathNames() []string {
+	names := make([]string, 0, len(c.Paths))
+	for name := range c.Paths {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// expandHome replaces a leading "~/" with the home directory.
+func expandHome(path string) string {
+	if !strings.HasPrefix(path, "~/") {
+		return path
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return path
+	}
+	return filepath.Join(home, path[2:])
+}
@@ -0,0 +1,125 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"web-log/internal/history"
+	"web-log/internal/rules"
+)
+
+func writeConfig(t *testing.T, text string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "config.toml")
+	if err := os.WriteFile(path, []byte(text), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoad(t *testing.T) {
+	t.Setenv("HOME", "/home/test")
+	path := writeConfig(t, `
+provider = "anthropic"
+timezone = "Europe/Zurich"
+prompt_template = "~/prompts/tags.md"
+
+[providers.OpenRouter]
+model = "a/b,c/d"
+
+[output]
+format = "json"
+path = "~/summaries/{start}.json"
+
+[[rule]]
+name = "work"
+domains = ["*.corp.example"]
+`)
+	cfg, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !cfg.Found || cfg.Provider != "anthropic" || cfg.Output.Format != "json" {
+		t.Errorf("cfg = %+v", cfg)
+	}
+	if cfg.PromptTemplate != "/home/test/prompts/tags.md" || cfg.Output.Path != "/home/test/summaries/{start}.json" {
+		t.Errorf("paths not expanded: %q, %q", cfg.PromptTemplate, cfg.Output.Path)
+	}
+	if got := cfg.ProviderModels()["openrouter"]; got != "a/b,c/d" {
+		t.Errorf("openrouter model = %q, want it keyed by lowercase name", got)
+	}
+	if location, err := cfg.Location(); err != nil || location.String() != "Europe/Zurich" {
+		t.Errorf("location = %v, %v", location, err)
+	}
+}
+
+func TestLoadMissingFile(t *testing.T) {
+	cfg, err := Load(filepath.Join(t.TempDir(), "config.toml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Found || cfg.Provider != "" {
+		t.Errorf("cfg = %+v, want an empty config", cfg)
+	}
+	if location, err := cfg.Location(); location != nil || err != nil {
+		t.Errorf("location = %v, %v; want none set", location, err)
+	}
+}
+
+func TestLoadErrors(t *testing.T) {
+	tests := []struct {
+		text string
+		want string
+	}{
+		{`provdier = "openai"`, "unknown settings: provdier"},
+		{"[output]\nformt = \"json\"", "unknown settings: output.formt"},
+		{`timezone = "Mars/Olympus"`, `timezone "Mars/Olympus"`},
+		{`provider = `, "config.toml"},
+	}
+	for _, tt := range tests {
+		_, err := Load(writeConfig(t, tt.text))
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("Load(%q) = %v, want %q", tt.text, err, tt.want)
+		}
+	}
+}
+
+func TestDefaultPath(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", "/xdg")
+	t.Setenv("WEBLOG_CONFIG", "")
+	if path, err := DefaultPath(); err != nil || path != filepath.Join("/xdg", "web-log", "config.toml") {
+		t.Errorf("DefaultPath() = %q, %v", path, err)
+	}
+	t.Setenv("WEBLOG_CONFIG", "/etc/web-log.toml")
+	if path, err := DefaultPath(); err != nil || path != "/etc/web-log.toml" {
+		t.Errorf("DefaultPath() with WEBLOG_CONFIG = %q, %v", path, err)
+	}
+}
+
+func TestRuleSetOrder(t *testing.T) {
+	mail := history.Entry{URL: "https://mail.google.com/mail/u/0/", VisitTime: time.Date(2026, 10, 17, 9, 0, 0, 0, time.UTC)}
+	off := false
+	tests := []struct {
+		name    string
+		cfg     Config
+		rule    string
+		matched bool
+	}{
+		{"defaults", Config{}, "mail", true},
+		{"user rules first", Config{Rules: []rules.Rule{{Name: "keep-mail", Action: rules.Include, Domains: []string{"mail.google.com"}}}}, "keep-mail", true},
+		{"defaults off", Config{DefaultRules: &off}, "", false},
+	}
+	for _, tt := range tests {
+		set, err := tt.cfg.RuleSet(time.UTC)
+		if err != nil {
+			t.Fatal(err)
+		}
+		rule, ok := set.Match(mail)
+		if ok != tt.matched || rule.Name != tt.rule {
+			t.Errorf("%s: matched %q, %v; want %q", tt.name, rule.Name, ok, tt.rule)
+		}
+	}
+}
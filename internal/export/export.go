@@ -0,0 +1,208 @@
+// Package export writes history entries for use in other tools: CSV,
+// JSON Lines, or a SQLite database. Every format has the same fields, and
+// rows come in the same order, oldest first.
+package export
+
+import (
+	"database/sql"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"sort"
+	"strconv"
+	"time"
+
+	"web-log/internal/history"
+
+	_ "modernc.org/sqlite"
+)
+
+// Formats lists the export formats.
+var Formats = []string{"csv", "jsonl", "sqlite"}
+
+// columns are the exported fields, in order. Visit times are RFC 3339 in
+// the entries' own timezone.
+var columns = []string{"url", "title", "visit_time", "source", "profile", "transition", "duration_seconds", "referrer", "search_term"}
+
+type record struct {
+	URL             string `json:"url"`
+	Title           string `json:"title"`
+	VisitTime       string `json:"visit_time"`
+	Source          string `json:"source"`
+	Profile         string `json:"profile,omitempty"`
+	Transition      string `json:"transition,omitempty"`
+	DurationSeconds int    `json:"duration_seconds,omitempty"`
+	Referrer        string `json:"referrer,omitempty"`
+	SearchTerm      string `json:"search_term,omitempty"`
+}
+
+func newRecord(entry history.Entry) record {
+	searchTerm := ""
+	if search, ok := history.ExtractSearch(entry); ok {
+		searchTerm = search.Query
+	}
+	return record{
+		URL:             entry.URL,
+		Title:           entry.Title,
+		VisitTime:       entry.VisitTime.Format(time.RFC3339),
+		Source:          entry.Source,
+		Profile:         entry.Profile,
+		Transition:      string(entry.Transition),
+		DurationSeconds: int(entry.Duration.Seconds()),
+		Referrer:        entry.Referrer,
+		SearchTerm:      searchTerm,
+	}
+}
+
+// sorted returns the entries oldest first, then by source, profile and
+// URL, so exporting the same period twice gives the same rows in the same
+// order. Deduplication leaves them in no particular order.
+func sorted(entries []history.Entry) []history.Entry {
+	result := append([]history.Entry{}, entries...)
+	sort.SliceStable(result, func(i, j int) bool {
+		a, b := result[i], result[j]
+		if !a.VisitTime.Equal(b.VisitTime) {
+			return a.VisitTime.Before(b.VisitTime)
+		}
+		if a.Source != b.Source {
+			return a.Source < b.Source
+		}
+		if a.Profile != b.Profile {
+			return a.Profile < b.Profile
+		}
+		return a.URL < b.URL
+	})
+	return result
+}
+
+// CSV writes a header row and one row per entry. Unknown values are empty,
+// except duration_seconds, which is 0.
+func CSV(w io.Writer, entries []history.Entry) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write(columns); err != nil {
+		return err
+	}
+	for _, entry := range sorted(entries) {
+		r := newRecord(entry)
+		if err := cw.Write([]string{
+			r.URL, r.Title, r.VisitTime, r.Source, r.Profile, r.Transition,
+			strconv.Itoa(r.DurationSeconds), r.Referrer, r.SearchTerm,
+		}); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// JSONL writes one JSON object per line. Fields that are unknown for an
+// entry are left out.
+func JSONL(w io.Writer, entries []history.Entry) error {
+	enc := json.NewEncoder(w)
+	enc.SetEscapeHTML(false)
+	for _, entry := range sorted(entries) {
+		if err := enc.Encode(newRecord(entry)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+const sqliteSchema = `
+CREATE TABLE entries (
+	url              TEXT    NOT NULL,
+	title            TEXT    NOT NULL DEFAULT '',
+	visit_time       TEXT    NOT NULL,
+	source           TEXT    NOT NULL,
+	profile          TEXT    NOT NULL DEFAULT '',
+	transition       TEXT    NOT NULL DEFAULT '',
+	duration_seconds INTEGER NOT NULL DEFAULT 0,
+	referrer         TEXT    NOT NULL DEFAULT '',
+	search_term      TEXT    NOT NULL DEFAULT ''
+);
+CREATE INDEX entries_visit_time ON entries (visit_time);
+`
+
+// ErrNotExport is returned by SQLite for a database that holds data of its
+// own rather than an earlier export.
+var ErrNotExport = errors.New("the database is not a web-log export")
+
+// SQLite writes the entries into the entries table of the database at path,
+// creating the file if needed. An entries table from an earlier export is
+// replaced; anything else in the database is left alone. A database with
+// tables but no earlier export is refused with ErrNotExport unless force is
+// set, so a wrong --output never drops someone's own entries table.
+func SQLite(path string, entries []history.Entry, force bool) error {
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	if !force {
+		if err := checkExportDB(tx); err != nil {
+			return err
+		}
+	}
+	if _, err := tx.Exec("DROP TABLE IF EXISTS entries"); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(sqliteSchema); err != nil {
+		return fmt.Errorf("creating entries table: %w", err)
+	}
+	stmt, err := tx.Prepare(`INSERT INTO entries
+		(url, title, visit_time, source, profile, transition, duration_seconds, referrer, search_term)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+	for _, entry := range sorted(entries) {
+		r := newRecord(entry)
+		if _, err := stmt.Exec(r.URL, r.Title, r.VisitTime, r.Source, r.Profile, r.Transition,
+			r.DurationSeconds, r.Referrer, r.SearchTerm); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// checkExportDB accepts an empty database, or one whose entries table has
+// exactly the exported columns, as only an earlier export leaves it that way.
+func checkExportDB(tx *sql.Tx) error {
+	var tables int
+	if err := tx.QueryRow("SELECT count(*) FROM sqlite_master WHERE type = 'table' AND name NOT LIKE 'sqlite_%'").Scan(&tables); err != nil {
+		return err
+	}
+	if tables == 0 {
+		return nil
+	}
+	rows, err := tx.Query("SELECT name FROM pragma_table_info('entries') ORDER BY cid")
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	existing := []string{}
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return err
+		}
+		existing = append(existing, name)
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	if !slices.Equal(existing, columns) {
+		return ErrNotExport
+	}
+	return nil
+}
@@ -0,0 +1,168 @@
+package export
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/csv"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"web-log/internal/history"
+)
+
+func unordered() []history.Entry {
+	at := time.Date(2026, 10, 12, 9, 0, 0, 0, time.UTC)
+	return []history.Entry{
+		{URL: "https://b.example/", Source: "firefox", VisitTime: at},
+		{URL: "https://late.example/", Source: "chrome", VisitTime: at.Add(time.Hour)},
+		{URL: "https://b.example/", Source: "chrome", Profile: "Work", VisitTime: at},
+		{URL: "https://a.example/", Source: "chrome", Profile: "Work", VisitTime: at},
+		{URL: "https://c.example/", Source: "chrome", Profile: "Personal", VisitTime: at},
+		{URL: "https://early.example/", Source: "safari", VisitTime: at.Add(-time.Hour)},
+	}
+}
+
+var wantOrder = []string{
+	"https://early.example/",
+	"https://c.example/",
+	"https://a.example/",
+	"https://b.example/",
+	"https://b.example/",
+	"https://late.example/",
+}
+
+func TestCSVOrder(t *testing.T) {
+	entries := unordered()
+	reversed := make([]history.Entry, len(entries))
+	for i, entry := range entries {
+		reversed[len(entries)-1-i] = entry
+	}
+
+	outputs := []string{}
+	for _, input := range [][]history.Entry{entries, reversed} {
+		var buf bytes.Buffer
+		if err := CSV(&buf, input); err != nil {
+			t.Fatal(err)
+		}
+		outputs = append(outputs, buf.String())
+	}
+	if outputs[0] != outputs[1] {
+		t.Errorf("output depends on input order:\n%s\n%s", outputs[0], outputs[1])
+	}
+
+	rows, err := csv.NewReader(bytes.NewBufferString(outputs[0])).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != len(wantOrder)+1 {
+		t.Fatalf("got %d rows, want a header and %d entries", len(rows), len(wantOrder))
+	}
+	for i, want := range wantOrder {
+		if rows[i+1][0] != want {
+			t.Errorf("row %d = %s, want %s", i+1, rows[i+1][0], want)
+		}
+	}
+	if rows[5][3] != "firefox" {
+		t.Errorf("row 5 source = %s, want firefox after chrome", rows[5][3])
+	}
+}
+
+func TestSQLiteOrder(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "export.db")
+	if err := SQLite(path, unordered(), false); err != nil {
+		t.Fatal(err)
+	}
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	rows, err := db.Query("SELECT url FROM entries ORDER BY rowid")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+	i := 0
+	for ; rows.Next(); i++ {
+		var url string
+		if err := rows.Scan(&url); err != nil {
+			t.Fatal(err)
+		}
+		if i < len(wantOrder) && url != wantOrder[i] {
+			t.Errorf("row %d = %s, want %s", i, url, wantOrder[i])
+		}
+	}
+	if i != len(wantOrder) {
+		t.Errorf("got %d rows, want %d", i, len(wantOrder))
+	}
+}
+
+// TestSQLiteRefusesOtherDatabases keeps a database's own tables unless
+// forced, and replaces an earlier export while leaving tables added next to
+// it alone.
+func TestSQLiteRefusesOtherDatabases(t *testing.T) {
+	const ownEntries = "CREATE TABLE entries (id INTEGER PRIMARY KEY, note TEXT); INSERT INTO entries (note) VALUES ('mine');"
+	const notes = "CREATE TABLE notes (note TEXT); INSERT INTO notes VALUES ('mine');"
+	tests := []struct {
+		name    string
+		earlier bool
+		setup   string
+		force   bool
+		wantErr bool
+		// kept reads back the 'mine' row the setup wrote
+		kept string
+	}{
+		{name: "new file"},
+		{name: "own entries table", setup: ownEntries, wantErr: true, kept: "SELECT note FROM entries"},
+		{name: "other tables", setup: notes, wantErr: true, kept: "SELECT note FROM notes"},
+		{name: "own entries table, forced", setup: ownEntries, force: true},
+		{name: "earlier export", earlier: true},
+		{name: "earlier export and other tables", earlier: true, setup: notes, kept: "SELECT note FROM notes"},
+	}
+	for _, test := range tests {
+		path := filepath.Join(t.TempDir(), "export.db")
+		if test.earlier {
+			if err := SQLite(path, unordered()[:1], false); err != nil {
+				t.Fatal(err)
+			}
+		}
+		db, err := sql.Open("sqlite", path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer db.Close()
+		if _, err := db.Exec(test.setup); err != nil {
+			t.Fatal(err)
+		}
+
+		err = SQLite(path, unordered(), test.force)
+		if test.wantErr {
+			if !errors.Is(err, ErrNotExport) {
+				t.Errorf("%s: err = %v, want ErrNotExport", test.name, err)
+			}
+		} else if err != nil {
+			t.Errorf("%s: %v", test.name, err)
+		} else {
+			var rows int
+			if err := db.QueryRow("SELECT count(*) FROM entries").Scan(&rows); err != nil || rows != len(wantOrder) {
+				t.Errorf("%s: exported %d rows (%v), want %d", test.name, rows, err, len(wantOrder))
+			}
+		}
+		if test.kept != "" {
+			var note string
+			if err := db.QueryRow(test.kept).Scan(&note); err != nil || note != "mine" {
+				t.Errorf("%s: %s gave %q, %v, want the database's own row", test.name, test.kept, note, err)
+			}
+		}
+	}
+}
+
+func TestSortedLeavesInputAlone(t *testing.T) {
+	entries := unordered()
+	sorted(entries)
+	if entries[0].URL != "https://b.example/" || entries[0].Source != "firefox" {
+		t.Errorf("sorted changed its input: %+v", entries[0])
+	}
+}
@@ -0,0 +1,273 @@
+package history
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"time"
+
+	_ "modernc.org/sqlite"
+)
+
+// chromeEpochOffset is the number of microseconds between 1601-01-01 (the
+// Chrome/WebKit epoch) and the Unix epoch. Going through Unix time avoids
+// time.Duration, which overflows after ~292 years.
+const chromeEpochOffset = 11644473600 * 1000000
+
+func toChromeTime(t time.Time) int64 {
+	return t.UnixMicro() + chromeEpochOffset
+}
+
+func fromChromeTime(v int64) time.Time {
+	return time.UnixMicro(v - chromeEpochOffset).UTC()
+}
+
+// ChromiumBrowser describes a browser built on Chromium. They all share the
+// urls/visits History schema and only differ in where the data lives.
+type ChromiumBrowser struct {
+	Name    string
+	DataDir string
+}
+
+type chromiumBrowserInfo struct {
+	name    string
+	darwin  []string
+	linux   []string
+	windows []string
+	// roaming is set for browsers that keep their data under %APPDATA%
+	// rather than %LOCALAPPDATA% on Windows.
+	roaming bool
+}
+
+// chromiumBrowsers maps browser names to their data directory relative to
+// appDataDir on each platform. A nil path means the browser has no build
+// for that platform.
+var chromiumBrowsers = []chromiumBrowserInfo{
+	{"chrome", []string{"Google", "Chrome"}, []string{"google-chrome"}, []string{"Google", "Chrome", "User Data"}, false},
+	{"chromium", []string{"Chromium"}, []string{"chromium"}, []string{"Chromium", "User Data"}, false},
+	{"edge", []string{"Microsoft Edge"}, []string{"microsoft-edge"}, []string{"Microsoft", "Edge", "User Data"}, false},
+	{"brave", []string{"BraveSoftware", "Brave-Browser"}, []string{"BraveSoftware", "Brave-Browser"}, []string{"BraveSoftware", "Brave-Browser", "User Data"}, false},
+	{"arc", []string{"Arc", "User Data"}, nil, nil, false},
+	{"vivaldi", []string{"Vivaldi"}, []string{"vivaldi"}, []string{"Vivaldi", "User Data"}, false},
+	{"opera", []string{"com.operasoftware.Opera"}, []string{"opera"}, []string{"Opera Software", "Opera Stable"}, true},
+}
+
+func (b chromiumBrowserInfo) platformDir() []string {
+	switch runtime.GOOS {
+	case "darwin":
+		return b.darwin
+	case "windows":
+		return b.windows
+	default:
+		return b.linux
+	}
+}
+
+// KnownChromiumBrowsers returns every Chromium browser supported on this
+// platform, installed or not, with data directories set by SetPath applied.
+func KnownChromiumBrowsers() ([]ChromiumBrowser, error) {
+	browsers := make([]ChromiumBrowser, 0, len(chromiumBrowsers))
+	for _, b := range chromiumBrowsers {
+		dir := b.platformDir()
+		if dir == nil {
+			continue
+		}
+		base, err := appDataDir(b.roaming)
+		if err != nil {
+			return nil, err
+		}
+		dataDir := filepath.Join(append([]string{base}, dir...)...)
+		if path, ok := pathOverrides[b.name]; ok {
+			dataDir = path
+		}
+		browsers = append(browsers, ChromiumBrowser{Name: b.name, DataDir: dataDir})
+	}
+	return browsers, nil
+}
+
+// Installed reports whether the browser's data directory exists.
+func (b ChromiumBrowser) Installed() bool {
+	info, err := os.Stat(b.DataDir)
+	return err == nil && info.IsDir()
+}
+
+// ChromiumProfile is one browser profile with its own History database.
+type ChromiumProfile struct {
+	Dir         string
+	Name        string
+	HistoryPath string
+}
+
+type chromiumLocalState struct {
+	Profile struct {
+		InfoCache map[string]struct {
+			Name string `json:"name"`
+		} `json:"info_cache"`
+	} `json:"profile"`
+}
+
+// Profiles lists the browser's profiles from the "Local State" file, using
+// the display name the user gave each profile. Browsers without a usable
+// Local State fall back to Default/History, or History directly in the data
+// directory as Opera keeps it.
+func (b ChromiumBrowser) Profiles() ([]ChromiumProfile, error) {
+	profiles := []ChromiumProfile{}
+	if data, err := os.ReadFile(filepath.Join(b.DataDir, "Local State")); err == nil {
+		var state chromiumLocalState
+		if err := json.Unmarshal(data, &state); err != nil {
+			return nil, fmt.Errorf("parse Local State: %w", err)
+		}
+		dirs := make([]string, 0, len(state.Profile.InfoCache))
+		for dir := range state.Profile.InfoCache {
+			dirs = append(dirs, dir)
+		}
+		sort.Strings(dirs)
+		for _, dir := range dirs {
+			path := filepath.Join(b.DataDir, dir, "History")
+			if _, err := os.Stat(path); err != nil {
+				continue
+			}
+			name := state.Profile.InfoCache[dir].Name
+			if name == "" {
+				name = dir
+			}
+			profiles = append(profiles, ChromiumProfile{Dir: dir, Name: name, HistoryPath: path})
+		}
+	}
+	if len(profiles) > 0 {
+		return profiles, nil
+	}
+
+	candidates := []ChromiumProfile{
+		{Dir: "Default", Name: "Default", HistoryPath: filepath.Join(b.DataDir, "Default", "History")},
+		{Dir: "", Name: "", HistoryPath: filepath.Join(b.DataDir, "History")},
+	}
+	for _, profile := range candidates {
+		if _, err := os.Stat(profile.HistoryPath); err == nil {
+			return []ChromiumProfile{profile}, nil
+		}
+	}
+	_, err := os.Stat(candidates[0].HistoryPath)
+	return nil, err
+}
+
+// ReadChromiumHistory reads the History database of every profile of the
+// browser. It fails when no profile could be read at all, and returns what
+// it read with a *PartialError when only some could.
+func ReadChromiumHistory(ctx context.Context, browser ChromiumBrowser, since *time.Time, until *time.Time) ([]Entry, error) {
+	profiles, err := browser.Profiles()
+	if err != nil {
+		return nil, err
+	}
+
+	entries := []Entry{}
+	var firstErr error
+	read := 0
+	for _, profile := range profiles {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		profileEntries, err := readChromiumProfile(ctx, browser, profile, since, until)
+		if err != nil {
+			if firstErr == nil {
+				firstErr = fmt.Errorf("profile %q: %w", profile.Name, err)
+			}
+			continue
+		}
+		read++
+		entries = append(entries, profileEntries...)
+	}
+	if read == 0 && firstErr != nil {
+		return nil, firstErr
+	}
+	if firstErr != nil {
+		return entries, &PartialError{Err: firstErr}
+	}
+	return entries, nil
+}
+
+func readChromiumProfile(ctx context.Context, browser ChromiumBrowser, profile ChromiumProfile, since *time.Time, until *time.Time) ([]Entry, error) {
+	path := profile.HistoryPath
+	db, cleanup, err := openSnapshot(ctx, path, "weblog-"+browser.Name+"-*.db")
+	if err != nil {
+		return nil, err
+	}
+	defer cleanup()
+
+	// Terms typed into the omnibox for a search engine, when the fork keeps them
+	searchTermExpr := "NULL"
+	hasSearchTerms, err := columnExists(ctx, db, "keyword_search_terms", "term")
+	if err != nil {
+		return nil, err
+	}
+	if hasSearchTerms {
+		searchTermExpr = "(SELECT term FROM keyword_search_terms WHERE keyword_search_terms.url_id = urls.id LIMIT 1)"
+	}
+
+	query := "SELECT visits.id, urls.url, urls.title, visits.visit_time, visits.transition, visits.visit_duration, visits.from_visit, from_urls.url, " + searchTermExpr +
+		" FROM visits JOIN urls ON visits.url = urls.id" +
+		" LEFT JOIN visits from_visits ON from_visits.id = visits.from_visit" +
+		" LEFT JOIN urls from_urls ON from_urls.id = from_visits.url"
+	args := []any{}
+	conditions := []string{}
+	if since != nil {
+		sinceVal := toChromeTime(*since)
+		conditions = append(conditions, "visits.visit_time >= ?")
+		args = append(args, sinceVal)
+	}
+	if until != nil {
+		untilVal := toChromeTime(*until)
+		conditions = append(conditions, "visits.visit_time < ?")
+		args = append(args, untilVal)
+	}
+	if len(conditions) > 0 {
+		query += " WHERE " + joinConditions(conditions)
+	}
+	query += " ORDER BY visits.visit_time DESC"
+
+	rows, err := db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	entries := []Entry{}
+	for rows.Next() {
+		var visitID int64
+		var url string
+		var title sql.NullString
+		var visitRaw int64
+		var transition int64
+		var duration int64
+		var fromVisit sql.NullInt64
+		var referrer sql.NullString
+		var searchTerm sql.NullString
+		if err := rows.Scan(&visitID, &url, &title, &visitRaw, &transition, &duration, &fromVisit, &referrer, &searchTerm); err != nil {
+			return nil, err
+		}
+		visitTime := fromChromeTime(visitRaw)
+		entries = append(entries, Entry{
+			URL:        url,
+			Title:      title.String,
+			VisitTime:  visitTime,
+			Source:     browser.Name,
+			Profile:    profile.Name,
+			ProfileDir: profile.Dir,
+			VisitID:    visitID,
+			Transition: chromeTransition(transition),
+			Duration:   time.Duration(duration) * time.Microsecond,
+			FromVisit:  fromVisit.Int64,
+			Referrer:   referrer.String,
+			SearchTerm: searchTerm.String,
+		})
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
@@ -0,0 +1,233 @@
+package history
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// chromiumSchema is the part of a Chromium History database the reader
+// uses. keyword_search_terms is left to each test since forks may lack it.
+const chromiumSchema = `
+CREATE TABLE urls (id INTEGER PRIMARY KEY, url LONGVARCHAR, title LONGVARCHAR);
+CREATE TABLE visits (
+	id INTEGER PRIMARY KEY,
+	url INTEGER NOT NULL,
+	visit_time INTEGER NOT NULL,
+	from_visit INTEGER,
+	transition INTEGER DEFAULT 0 NOT NULL,
+	visit_duration INTEGER DEFAULT 0 NOT NULL
+);
+`
+
+func chromeTime(t time.Time) string {
+	return fmt.Sprint(toChromeTime(t))
+}
+
+// TestReadChromiumHistory reads a profile where an omnibox search (core type
+// 5) led to a link, with the search term Chrome recorded, plus a visit
+// outside the requested period.
+func TestReadChromiumHistory(t *testing.T) {
+	dataDir := t.TempDir()
+	base := time.Date(2026, 3, 5, 9, 0, 0, 0, time.UTC)
+	createDB(t, filepath.Join(dataDir, "Default", "History"), chromiumSchema+`
+CREATE TABLE keyword_search_terms (keyword_id INTEGER NOT NULL, url_id INTEGER NOT NULL, term LONGVARCHAR NOT NULL, normalized_term LONGVARCHAR NOT NULL);
+INSERT INTO urls VALUES
+	(1, 'https://www.google.com/search?q=go+sqlite', 'go sqlite - Google Search'),
+	(2, 'https://pkg.go.dev/modernc.org/sqlite', 'sqlite package'),
+	(3, 'https://example.com/old', 'Old');
+INSERT INTO keyword_search_terms VALUES (2, 1, 'Go sqlite', 'go sqlite');
+INSERT INTO visits VALUES
+	(10, 1, `+chromeTime(base)+`, 0, 805306373, 4000000),
+	(11, 2, `+chromeTime(base.Add(time.Minute))+`, 10, 805306368, 90000000),
+	(12, 3, `+chromeTime(base.Add(-48*time.Hour))+`, 0, 805306368, 0);
+`)
+
+	since := base.Add(-time.Hour)
+	until := base.Add(time.Hour)
+	browser := ChromiumBrowser{Name: "brave", DataDir: dataDir}
+	entries, err := ReadChromiumHistory(context.Background(), browser, &since, &until)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Entry{
+		{
+			URL: "https://pkg.go.dev/modernc.org/sqlite", Title: "sqlite package", VisitTime: base.Add(time.Minute),
+			VisitID: 11, Transition: TransitionLink, Duration: 90 * time.Second,
+			FromVisit: 10, Referrer: "https://www.google.com/search?q=go+sqlite",
+		},
+		{
+			URL: "https://www.google.com/search?q=go+sqlite", Title: "go sqlite - Google Search", VisitTime: base,
+			VisitID: 10, Transition: TransitionTyped, Duration: 4 * time.Second, SearchTerm: "Go sqlite",
+		},
+	}
+	for i := range want {
+		want[i].Source = "brave"
+		want[i].Profile = "Default"
+		want[i].ProfileDir = "Default"
+	}
+	if !reflect.DeepEqual(entries, want) {
+		t.Errorf("got %+v\nwant %+v", entries, want)
+	}
+}
+
+// TestReadChromiumHistoryDataDir reads History straight from the data
+// directory, as Opera keeps it, from a fork without keyword_search_terms.
+func TestReadChromiumHistoryDataDir(t *testing.T) {
+	dataDir := t.TempDir()
+	createDB(t, filepath.Join(dataDir, "History"), chromiumSchema+`
+INSERT INTO urls VALUES (1, 'https://example.com/', 'Example');
+INSERT INTO visits VALUES (1, 1, 13416000000000000, 0, 805306369, 0);
+`)
+	entries, err := ReadChromiumHistory(context.Background(), ChromiumBrowser{Name: "opera", DataDir: dataDir}, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 {
+		t.Fatalf("got %d entries, want 1", len(entries))
+	}
+	entry := entries[0]
+	if entry.Source != "opera" || entry.Profile != "" || entry.ProfileDir != "" || entry.SearchTerm != "" {
+		t.Errorf("entry = %+v, want an opera visit without profile or search term", entry)
+	}
+	if entry.Transition != TransitionTyped {
+		t.Errorf("transition = %q, want %q", entry.Transition, TransitionTyped)
+	}
+}
+
+func TestReadChromiumHistoryMissing(t *testing.T) {
+	entries, err := ReadChromiumHistory(context.Background(), ChromiumBrowser{Name: "edge", DataDir: t.TempDir()}, nil, nil)
+	if err == nil {
+		t.Errorf("got %d entries, want an error for a data directory without History", len(entries))
+	}
+}
+
+func TestChromeTimeRoundTrip(t *testing.T) {
+	times := []time.Time{
+		time.Date(1601, 1, 1, 0, 0, 0, 0, time.UTC),
+		time.Date(1970, 1, 1, 0, 0, 0, 0, time.UTC),
+		time.Date(2026, 3, 5, 9, 30, 15, 123456000, time.UTC),
+	}
+	for _, want := range times {
+		if got := fromChromeTime(toChromeTime(want)); !got.Equal(want) {
+			t.Errorf("%v: round trip gave %v", want, got)
+		}
+	}
+	if got := toChromeTime(time.Date(1601, 1, 1, 0, 0, 0, 0, time.UTC)); got != 0 {
+		t.Errorf("Chrome epoch = %d, want 0", got)
+	}
+}
+
+func TestChromiumProfiles(t *testing.T) {
+	tests := []struct {
+		name       string
+		localState string
+		histories  []string
+		want       []ChromiumProfile
+		wantErr    bool
+	}{
+		{
+			name:       "profiles from Local State",
+			localState: `{"profile": {"info_cache": {"Profile 1": {"name": "Work"}, "Default": {"name": "Personal"}, "Profile 2": {"name": ""}}}}`,
+			histories:  []string{"Default", "Profile 1", "Profile 2"},
+			want: []ChromiumProfile{
+				{Dir: "Default", Name: "Personal"},
+				{Dir: "Profile 1", Name: "Work"},
+				{Dir: "Profile 2", Name: "Profile 2"},
+			},
+		},
+		{
+			name:       "profiles without History are skipped",
+			localState: `{"profile": {"info_cache": {"Default": {"name": "Personal"}, "Profile 3": {"name": "Guest"}}}}`,
+			histories:  []string{"Default"},
+			want:       []ChromiumProfile{{Dir: "Default", Name: "Personal"}},
+		},
+		{
+			name:      "no Local State",
+			histories: []string{"Default"},
+			want:      []ChromiumProfile{{Dir: "Default", Name: "Default"}},
+		},
+		{
+			name:       "no usable profile in Local State",
+			localState: `{"profile": {"info_cache": {}}}`,
+			histories:  []string{"Default"},
+			want:       []ChromiumProfile{{Dir: "Default", Name: "Default"}},
+		},
+		{
+			name:      "History in the data directory",
+			histories: []string{""},
+			want:      []ChromiumProfile{{Dir: "", Name: ""}},
+		},
+		{
+			name:       "broken Local State",
+			localState: `{"profile": `,
+			histories:  []string{"Default"},
+			wantErr:    true,
+		},
+		{
+			name:    "nothing",
+			wantErr: true,
+		},
+	}
+	for _, test := range tests {
+		dataDir := t.TempDir()
+		if test.localState != "" {
+			writeFile(t, filepath.Join(dataDir, "Local State"), test.localState)
+		}
+		for _, dir := range test.histories {
+			writeFile(t, filepath.Join(dataDir, dir, "History"), "")
+		}
+		got, err := ChromiumBrowser{Name: "chrome", DataDir: dataDir}.Profiles()
+		if test.wantErr {
+			if err == nil {
+				t.Errorf("%s: got %+v, want an error", test.name, got)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: %v", test.name, err)
+			continue
+		}
+		for i := range test.want {
+			test.want[i].HistoryPath = filepath.Join(dataDir, test.want[i].Dir, "History")
+		}
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%s: got %+v, want %+v", test.name, got, test.want)
+		}
+	}
+}
+
+// TestReadChromiumHistoryProfiles reads two profiles into entries carrying
+// their display names, and keeps the readable one when the other's History
+// is not a database.
+func TestReadChromiumHistoryProfiles(t *testing.T) {
+	dataDir := t.TempDir()
+	writeFile(t, filepath.Join(dataDir, "Local State"),
+		`{"profile": {"info_cache": {"Default": {"name": "Personal"}, "Profile 1": {"name": "Work"}, "Profile 2": {"name": "Broken"}}}}`)
+	for _, dir := range []string{"Default", "Profile 1"} {
+		createDB(t, filepath.Join(dataDir, dir, "History"), chromiumSchema+`
+INSERT INTO urls VALUES (1, 'https://example.com/', 'Example');
+INSERT INTO visits VALUES (1, 1, 13416000000000000, 0, 805306368, 0);
+`)
+	}
+	writeFile(t, filepath.Join(dataDir, "Profile 2", "History"), "not a database")
+
+	entries, err := ReadChromiumHistory(context.Background(), ChromiumBrowser{Name: "chrome", DataDir: dataDir}, nil, nil)
+	var partial *PartialError
+	if !errors.As(err, &partial) || !strings.Contains(err.Error(), `"Broken"`) {
+		t.Errorf("err = %v, want a *PartialError naming the Broken profile", err)
+	}
+	got := map[string]string{}
+	for _, entry := range entries {
+		got[entry.ProfileDir] = entry.Profile
+	}
+	want := map[string]string{"Default": "Personal", "Profile 1": "Work"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("profiles read = %v, want %v", got, want)
+	}
+}
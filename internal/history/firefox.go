@@ -0,0 +1,234 @@
+package history
+
+import (
+	"bufio"
+	"context"
+	"database/sql"
+	"errors"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+)
+
+type firefoxProfile struct {
+	Name string
+	Path string
+}
+
+// FirefoxDataPath returns the directory holding profiles.ini. On Linux the
+// Snap and Flatpak locations are tried after ~/.mozilla/firefox; a path set
+// with SetPath wins over all of them.
+func FirefoxDataPath() (string, error) {
+	if path, ok := pathOverrides["firefox"]; ok {
+		return path, nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	switch runtime.GOOS {
+	case "darwin":
+		return filepath.Join(home, "Library", "Application Support", "Firefox"), nil
+	case "windows":
+		base, err := appDataDir(true)
+		if err != nil {
+			return "", err
+		}
+		return filepath.Join(base, "Mozilla", "Firefox"), nil
+	default:
+		return firstExisting([]string{
+			filepath.Join(home, ".mozilla", "firefox"),
+			filepath.Join(home, "snap", "firefox", "common", ".mozilla", "firefox"),
+			filepath.Join(home, ".var", "app", "org.mozilla.firefox", ".mozilla", "firefox"),
+		}), nil
+	}
+}
+
+// FirefoxInstalled reports whether a Firefox data directory exists.
+func FirefoxInstalled() bool {
+	path, err := FirefoxDataPath()
+	if err != nil {
+		return false
+	}
+	info, err := os.Stat(path)
+	return err == nil && info.IsDir()
+}
+
+// ReadFirefoxHistory reads places.sqlite of every profile. It fails when no
+// profile could be read at all, and returns what it read with a
+// *PartialError when only some could.
+func ReadFirefoxHistory(ctx context.Context, since *time.Time, until *time.Time) ([]Entry, error) {
+	dataPath, err := FirefoxDataPath()
+	if err != nil {
+		return nil, err
+	}
+	profiles, err := firefoxProfiles(dataPath)
+	if err != nil {
+		return nil, err
+	}
+
+	entries := []Entry{}
+	var firstErr error
+	read := 0
+	for _, profile := range profiles {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		profileEntries, err := readFirefoxProfile(ctx, profile, since, until)
+		if err != nil {
+			if firstErr == nil {
+				firstErr = err
+			}
+			continue
+		}
+		read++
+		entries = append(entries, profileEntries...)
+	}
+	if read == 0 && firstErr != nil {
+		return nil, firstErr
+	}
+	if firstErr != nil {
+		return entries, &PartialError{Err: firstErr}
+	}
+	return entries, nil
+}
+
+// firefoxProfiles lists the profiles declared in profiles.ini. Relative
+// paths are resolved against the Firefox data directory.
+func firefoxProfiles(dataPath string) ([]firefoxProfile, error) {
+	file, err := os.Open(filepath.Join(dataPath, "profiles.ini"))
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	profiles := []firefoxProfile{}
+	seen := map[string]bool{}
+	var current *firefoxProfile
+	relative := true
+	flush := func() {
+		if current == nil || current.Path == "" {
+			return
+		}
+		path := filepath.FromSlash(current.Path)
+		if relative {
+			path = filepath.Join(dataPath, path)
+		}
+		if !seen[path] {
+			seen[path] = true
+			profiles = append(profiles, firefoxProfile{Name: current.Name, Path: path})
+		}
+	}
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, ";") || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]") {
+			flush()
+			current = nil
+			relative = true
+			if strings.HasPrefix(line, "[Profile") {
+				current = &firefoxProfile{}
+			}
+			continue
+		}
+		if current == nil {
+			continue
+		}
+		key, value, ok := strings.Cut(line, "=")
+		if !ok {
+			continue
+		}
+		switch strings.TrimSpace(key) {
+		case "Name":
+			current.Name = strings.TrimSpace(value)
+		case "Path":
+			current.Path = strings.TrimSpace(value)
+		case "IsRelative":
+			relative = strings.TrimSpace(value) != "0"
+		}
+	}
+	flush()
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if len(profiles) == 0 {
+		return nil, errors.New("no Firefox profiles found in profiles.ini")
+	}
+	return profiles, nil
+}
+
+func readFirefoxProfile(ctx context.Context, profile firefoxProfile, since *time.Time, until *time.Time) ([]Entry, error) {
+	path := filepath.Join(profile.Path, "places.sqlite")
+	if _, err := os.Stat(path); err != nil {
+		return nil, err
+	}
+
+	db, cleanup, err := openSnapshot(ctx, path, "weblog-firefox-*.db")
+	if err != nil {
+		return nil, err
+	}
+	defer cleanup()
+
+	query := "SELECT v.id, p.url, p.title, v.visit_date, v.visit_type, v.from_visit, from_places.url," +
+		" EXISTS (SELECT 1 FROM moz_historyvisits r WHERE r.from_visit = v.id AND r.visit_type IN (5, 6))" +
+		" FROM moz_historyvisits v JOIN moz_places p ON v.place_id = p.id" +
+		" LEFT JOIN moz_historyvisits from_visits ON from_visits.id = v.from_visit" +
+		" LEFT JOIN moz_places from_places ON from_places.id = from_visits.place_id"
+	args := []any{}
+	conditions := []string{}
+	if since != nil {
+		conditions = append(conditions, "v.visit_date >= ?")
+		args = append(args, since.UnixMicro())
+	}
+	if until != nil {
+		conditions = append(conditions, "v.visit_date < ?")
+		args = append(args, until.UnixMicro())
+	}
+	if len(conditions) > 0 {
+		query += " WHERE " + joinConditions(conditions)
+	}
+	query += " ORDER BY v.visit_date DESC"
+
+	rows, err := db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	entries := []Entry{}
+	for rows.Next() {
+		var visitID int64
+		var url string
+		var title sql.NullString
+		var visitRaw int64
+		var visitType int64
+		var fromVisit sql.NullInt64
+		var referrer sql.NullString
+		var redirectSource bool
+		if err := rows.Scan(&visitID, &url, &title, &visitRaw, &visitType, &fromVisit, &referrer, &redirectSource); err != nil {
+			return nil, err
+		}
+		entries = append(entries, Entry{
+			URL:        url,
+			Title:      title.String,
+			VisitTime:  time.UnixMicro(visitRaw).UTC(),
+			Source:     "firefox",
+			Profile:    profile.Name,
+			ProfileDir: filepath.Base(profile.Path),
+			VisitID:    visitID,
+			Transition: firefoxTransition(visitType, redirectSource),
+			FromVisit:  fromVisit.Int64,
+			Referrer:   referrer.String,
+		})
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
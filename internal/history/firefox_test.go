@@ -0,0 +1,248 @@
+package history
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// createDB writes a fixture database at path from the given SQL.
+func createDB(t *testing.T, path string, script string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	if _, err := db.Exec(script); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// overridePath points the named source at path for the rest of the test.
+func overridePath(t *testing.T, name string, path string) {
+	t.Helper()
+	old, hadOld := pathOverrides[name]
+	pathOverrides[name] = path
+	t.Cleanup(func() {
+		if hadOld {
+			pathOverrides[name] = old
+		} else {
+			delete(pathOverrides, name)
+		}
+	})
+}
+
+func writeFile(t *testing.T, path string, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestFirefoxProfiles(t *testing.T) {
+	dataPath := t.TempDir()
+	tests := []struct {
+		name    string
+		ini     string
+		want    []firefoxProfile
+		wantErr bool
+	}{
+		{
+			name: "relative and absolute",
+			ini: `[General]
+StartWithLastProfile=1
+
+[Profile0]
+Name=default-release
+IsRelative=1
+Path=Profiles/abcd1234.default-release
+
+[Profile1]
+Name=Work
+IsRelative=0
+Path=/srv/firefox/work
+`,
+			want: []firefoxProfile{
+				{Name: "default-release", Path: filepath.Join(dataPath, "Profiles", "abcd1234.default-release")},
+				{Name: "Work", Path: filepath.FromSlash("/srv/firefox/work")},
+			},
+		},
+		{
+			name: "install sections and comments are skipped",
+			ini: `; written by Firefox
+[Install4F96D1932A9F858E]
+Default=Profiles/abcd1234.default-release
+Locked=1
+
+# the only profile
+[Profile0]
+Name = default
+Path = Profiles/abcd1234.default-release
+`,
+			want: []firefoxProfile{
+				{Name: "default", Path: filepath.Join(dataPath, "Profiles", "abcd1234.default-release")},
+			},
+		},
+		{
+			name: "duplicate paths are listed once",
+			ini: `[Profile0]
+Name=first
+Path=Profiles/same
+
+[Profile1]
+Name=second
+Path=Profiles/same
+
+[Profile2]
+Name=no path
+`,
+			want: []firefoxProfile{
+				{Name: "first", Path: filepath.Join(dataPath, "Profiles", "same")},
+			},
+		},
+		{
+			name:    "no profiles",
+			ini:     "[General]\nStartWithLastProfile=1\n",
+			wantErr: true,
+		},
+	}
+	for _, test := range tests {
+		writeFile(t, filepath.Join(dataPath, "profiles.ini"), test.ini)
+		got, err := firefoxProfiles(dataPath)
+		if test.wantErr {
+			if err == nil {
+				t.Errorf("%s: got %+v, want an error", test.name, got)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: %v", test.name, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%s: got %+v, want %+v", test.name, got, test.want)
+		}
+	}
+}
+
+func TestFirefoxProfilesMissingIni(t *testing.T) {
+	if _, err := firefoxProfiles(t.TempDir()); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("err = %v, want a not-exist error", err)
+	}
+}
+
+// placesSchema is the part of Firefox's places.sqlite the reader uses.
+const placesSchema = `
+CREATE TABLE moz_places (id INTEGER PRIMARY KEY, url LONGVARCHAR, title LONGVARCHAR);
+CREATE TABLE moz_historyvisits (
+	id INTEGER PRIMARY KEY,
+	from_visit INTEGER,
+	place_id INTEGER,
+	visit_date INTEGER,
+	visit_type INTEGER
+);
+`
+
+// TestReadFirefoxHistory reads a profile where a search led to a t.co link
+// that redirected (visit type 5) to an article, plus a visit outside the
+// requested period.
+func TestReadFirefoxHistory(t *testing.T) {
+	dataPath := t.TempDir()
+	writeFile(t, filepath.Join(dataPath, "profiles.ini"), `[Profile0]
+Name=default-release
+IsRelative=1
+Path=Profiles/abcd1234.default-release
+`)
+	base := time.Date(2026, 3, 5, 9, 0, 0, 0, time.UTC)
+	createDB(t, filepath.Join(dataPath, "Profiles", "abcd1234.default-release", "places.sqlite"), placesSchema+`
+INSERT INTO moz_places VALUES
+	(1, 'https://duckduckgo.com/?q=sqlite', 'sqlite at DuckDuckGo'),
+	(2, 'https://t.co/abc', NULL),
+	(3, 'https://example.com/article', 'Article'),
+	(4, 'https://example.com/old', 'Old');
+INSERT INTO moz_historyvisits VALUES
+	(1, 0, 1, `+micros(base)+`, 2),
+	(2, 1, 2, `+micros(base.Add(time.Minute))+`, 1),
+	(3, 2, 3, `+micros(base.Add(time.Minute+time.Second))+`, 5),
+	(4, 0, 4, `+micros(base.Add(-48*time.Hour))+`, 1);
+`)
+	overridePath(t, "firefox", dataPath)
+
+	since := base.Add(-time.Hour)
+	entries, err := ReadFirefoxHistory(context.Background(), &since, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Entry{
+		{
+			URL: "https://example.com/article", Title: "Article", VisitTime: base.Add(time.Minute + time.Second),
+			VisitID: 3, Transition: TransitionLink, FromVisit: 2, Referrer: "https://t.co/abc",
+		},
+		{
+			URL: "https://t.co/abc", VisitTime: base.Add(time.Minute),
+			VisitID: 2, Transition: TransitionRedirect, FromVisit: 1, Referrer: "https://duckduckgo.com/?q=sqlite",
+		},
+		{
+			URL: "https://duckduckgo.com/?q=sqlite", Title: "sqlite at DuckDuckGo", VisitTime: base,
+			VisitID: 1, Transition: TransitionTyped,
+		},
+	}
+	for i := range want {
+		want[i].Source = "firefox"
+		want[i].Profile = "default-release"
+		want[i].ProfileDir = "abcd1234.default-release"
+	}
+	if !reflect.DeepEqual(entries, want) {
+		t.Errorf("got %+v\nwant %+v", entries, want)
+	}
+}
+
+// TestReadFirefoxHistoryPartial keeps the readable profile when another
+// one's places.sqlite is missing.
+func TestReadFirefoxHistoryPartial(t *testing.T) {
+	dataPath := t.TempDir()
+	writeFile(t, filepath.Join(dataPath, "profiles.ini"), `[Profile0]
+Name=gone
+Path=Profiles/gone
+
+[Profile1]
+Name=default
+Path=Profiles/default
+`)
+	createDB(t, filepath.Join(dataPath, "Profiles", "default", "places.sqlite"), placesSchema+`
+INSERT INTO moz_places VALUES (1, 'https://example.com/', 'Example');
+INSERT INTO moz_historyvisits VALUES (1, 0, 1, 1772701200000000, 1);
+`)
+	overridePath(t, "firefox", dataPath)
+
+	entries, err := ReadFirefoxHistory(context.Background(), nil, nil)
+	var partial *PartialError
+	if !errors.As(err, &partial) {
+		t.Fatalf("err = %v, want a *PartialError", err)
+	}
+	if len(entries) != 1 || entries[0].Profile != "default" {
+		t.Errorf("entries = %+v, want the default profile's visit", entries)
+	}
+
+	writeFile(t, filepath.Join(dataPath, "profiles.ini"), "[Profile0]\nName=gone\nPath=Profiles/gone\n")
+	if entries, err := ReadFirefoxHistory(context.Background(), nil, nil); err == nil || errors.As(err, &partial) {
+		t.Errorf("only a missing profile: got %d entries and err %v, want a plain error", len(entries), err)
+	}
+}
+
+func micros(t time.Time) string {
+	return fmt.Sprint(t.UnixMicro())
+}
@@ -1,24 +1,101 @@
 package history
 
-import "time"
+import (
+	"context"
+	"sync"
+	"time"
+)
 
-func ReadAllHistory(since *time.Time, until *time.Time) ([]Entry, []error) {
-	entries := []Entry{}
-	errors := []error{}
+// SourceResult is the outcome of reading one source.
+type SourceResult struct {
+	Source   string
+	Entries  []Entry
+	Duration time.Duration
+	Err      error
+}
+
+// ReadAllHistory reads every available source concurrently, each under its
+// own timeout (none when timeout is 0). Sources that are not installed are
+// skipped. Results come back in the order of sources, so output stays stable
+// no matter which database finishes first.
+func ReadAllHistory(ctx context.Context, sources []HistorySource, since *time.Time, until *time.Time, timeout time.Duration) []SourceResult {
+	available := []HistorySource{}
+	for _, source := range sources {
+		if source.Available() {
+			available = append(available, source)
+		}
+	}
+
+	results := make([]SourceResult, len(available))
+	var wg sync.WaitGroup
+	for i, source := range available {
+		wg.Add(1)
+		go func(i int, source HistorySource) {
+			defer wg.Done()
+			results[i] = ReadSource(ctx, source, since, until, timeout)
+		}(i, source)
+	}
+	wg.Wait()
+	return results
+}
+
+// ReadSource runs one source and stops waiting for it when its deadline
+// passes, even if the reader is stuck somewhere that ignores ctx.
+func ReadSource(ctx context.Context, source HistorySource, since *time.Time, until *time.Time, timeout time.Duration) SourceResult {
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
 
-	safariEntries, err := ReadSafariHistory(since, until)
-	if err != nil {
-		errors = append(errors, err)
-	} else {
-		entries = append(entries, safariEntries...)
+	type readResult struct {
+		entries []Entry
+		err     error
 	}
+	start := time.Now()
+	ch := make(chan readResult, 1)
+	go func() {
+		entries, err := source.Read(ctx, since, until)
+		ch <- readResult{entries, err}
+	}()
 
-	chromeEntries, err := ReadChromeHistory(since, until)
-	if err != nil {
-		errors = append(errors, err)
-	} else {
-		entries = append(entries, chromeEntries...)
+	result := SourceResult{Source: source.Name()}
+	select {
+	case r := <-ch:
+		result.Entries, result.Err = r.entries, r.err
+	case <-ctx.Done():
+		result.Err = ctx.Err()
 	}
+	result.Duration = time.Since(start)
+	return result
+}
+
+// PartialError is returned together with the entries that were read when
+// some of a source's profiles could not be read.
+type PartialError struct {
+	// Err is the first profile's failure.
+	Err error
+}
+
+func (e *PartialError) Error() string {
+	return "some profiles were not read: " + e.Err.Error()
+}
 
-	return entries, errors
+func (e *PartialError) Unwrap() error {
+	return e.Err
+}
+
+// MergeResults flattens the entries of all results into one list and
+// collects the failures. A partly read source contributes its entries and
+// is listed as failed too.
+func MergeResults(results []SourceResult) ([]Entry, []SourceResult) {
+	entries := []Entry{}
+	failed := []SourceResult{}
+	for _, result := range results {
+		if result.Err != nil {
+			failed = append(failed, result)
+		}
+		entries = append(entries, result.Entries...)
+	}
+	return entries, failed
 }
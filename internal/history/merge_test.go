@@ -0,0 +1,91 @@
+package history
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestMergeResultsKeepsPartialEntries(t *testing.T) {
+	results := []SourceResult{
+		{Source: "chrome", Entries: []Entry{{URL: "a"}, {URL: "b"}}, Err: &PartialError{Err: errors.New("locked")}},
+		{Source: "firefox", Err: errors.New("not found")},
+		{Source: "safari", Entries: []Entry{{URL: "c"}}},
+	}
+	entries, failed := MergeResults(results)
+	if len(entries) != 3 {
+		t.Errorf("got %d entries, want 3", len(entries))
+	}
+	if len(failed) != 2 || failed[0].Source != "chrome" || failed[1].Source != "firefox" {
+		t.Errorf("failed = %+v, want chrome and firefox", failed)
+	}
+}
+
+func TestReadSourceTimeout(t *testing.T) {
+	block := make(chan struct{})
+	defer close(block)
+	stuck := fakeSource{name: "stuck", available: true, entries: []Entry{{URL: "late"}}, block: block}
+
+	start := time.Now()
+	result := ReadSource(context.Background(), stuck, nil, nil, 20*time.Millisecond)
+	if !errors.Is(result.Err, context.DeadlineExceeded) {
+		t.Errorf("err = %v, want the deadline to pass", result.Err)
+	}
+	if result.Source != "stuck" || len(result.Entries) != 0 {
+		t.Errorf("result = %+v, want no entries from the stuck source", result)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("ReadSource waited %v for a stuck source", elapsed)
+	}
+}
+
+func TestReadSourceCanceled(t *testing.T) {
+	block := make(chan struct{})
+	defer close(block)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	result := ReadSource(ctx, fakeSource{name: "stuck", block: block}, nil, nil, 0)
+	if !errors.Is(result.Err, context.Canceled) {
+		t.Errorf("err = %v, want the cancellation", result.Err)
+	}
+}
+
+// TestReadAllHistory reads sources concurrently: the slow source finishes
+// last but its result stays first, the stuck one times out without holding
+// up the others, and the unavailable one is skipped.
+func TestReadAllHistory(t *testing.T) {
+	slow := make(chan struct{})
+	block := make(chan struct{})
+	defer close(block)
+	sources := []HistorySource{
+		fakeSource{name: "slow", available: true, entries: []Entry{{URL: "a"}}, block: slow},
+		fakeSource{name: "missing", entries: []Entry{{URL: "b"}}},
+		fakeSource{name: "stuck", available: true, block: block},
+		fakeSource{name: "fast", available: true, entries: []Entry{{URL: "c"}}},
+		fakeSource{name: "broken", available: true, err: errors.New("locked")},
+	}
+	time.AfterFunc(10*time.Millisecond, func() { close(slow) })
+
+	results := ReadAllHistory(context.Background(), sources, nil, nil, 200*time.Millisecond)
+	names := []string{}
+	for _, result := range results {
+		names = append(names, result.Source)
+	}
+	if want := []string{"slow", "stuck", "fast", "broken"}; !reflect.DeepEqual(names, want) {
+		t.Fatalf("results for %v, want %v", names, want)
+	}
+	if len(results[0].Entries) != 1 || results[0].Err != nil {
+		t.Errorf("slow: %+v, want its entry", results[0])
+	}
+	if !errors.Is(results[1].Err, context.DeadlineExceeded) {
+		t.Errorf("stuck: err = %v, want the deadline to pass", results[1].Err)
+	}
+	if len(results[2].Entries) != 1 || results[2].Err != nil {
+		t.Errorf("fast: %+v, want its entry", results[2])
+	}
+	if results[3].Err == nil {
+		t.Errorf("broken: want its error")
+	}
+}
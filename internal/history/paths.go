@@ -0,0 +1,97 @@
+package history
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+)
+
+// ErrUnavailable marks a history source that cannot exist on this system,
+// as opposed to one that failed to read.
+var ErrUnavailable = errors.New("not available on this system")
+
+var ErrSafariUnavailable = fmt.Errorf("Safari history is only available on macOS: %w", ErrUnavailable)
+
+// pathOverrides replaces a source's default data location, keyed by source
+// name (see SetPath).
+var pathOverrides = map[string]string{}
+
+// SetPath makes the named source read from path instead of its default
+// location: Safari's History.db, the Firefox directory holding
+// profiles.ini, or a Chromium browser's user data directory.
+func SetPath(name string, path string) error {
+	if _, ok := LookupSource(name); !ok {
+		return fmt.Errorf("unknown source %q (see 'web-log sources')", name)
+	}
+	pathOverrides[name] = path
+	return nil
+}
+
+// SourcePath returns where the named source reads from, and whether that is
+// an override set with SetPath.
+func SourcePath(name string) (string, bool, error) {
+	if path, ok := pathOverrides[name]; ok {
+		return path, true, nil
+	}
+	switch name {
+	case "safari":
+		path, err := SafariHistoryPath()
+		return path, false, err
+	case "firefox":
+		path, err := FirefoxDataPath()
+		return path, false, err
+	}
+	browsers, err := KnownChromiumBrowsers()
+	if err != nil {
+		return "", false, err
+	}
+	for _, browser := range browsers {
+		if browser.Name == name {
+			return browser.DataDir, false, nil
+		}
+	}
+	return "", false, fmt.Errorf("unknown source %q (see 'web-log sources')", name)
+}
+
+// appDataDir returns the per-user directory browsers keep their profiles in:
+// ~/Library/Application Support on macOS, %LOCALAPPDATA% (or %APPDATA% when
+// roaming) on Windows and $XDG_CONFIG_HOME elsewhere.
+func appDataDir(roaming bool) (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	switch runtime.GOOS {
+	case "darwin":
+		return filepath.Join(home, "Library", "Application Support"), nil
+	case "windows":
+		if roaming {
+			if dir := os.Getenv("APPDATA"); dir != "" {
+				return dir, nil
+			}
+			return filepath.Join(home, "AppData", "Roaming"), nil
+		}
+		if dir := os.Getenv("LOCALAPPDATA"); dir != "" {
+			return dir, nil
+		}
+		return filepath.Join(home, "AppData", "Local"), nil
+	default:
+		if dir := os.Getenv("XDG_CONFIG_HOME"); dir != "" {
+			return dir, nil
+		}
+		return filepath.Join(home, ".config"), nil
+	}
+}
+
+// firstExisting returns the first path that exists, or the first candidate
+// when none do so callers still get a meaningful path in errors.
+func firstExisting(candidates []string) string {
+	for _, path := range candidates {
+		if _, err := os.Stat(path); err == nil {
+			return path
+		}
+	}
+	return candidates[0]
+}
@@ -0,0 +1,109 @@
+package history
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+func skipUnlessXDG(t *testing.T) {
+	t.Helper()
+	if runtime.GOOS == "darwin" || runtime.GOOS == "windows" {
+		t.Skip("XDG layout is only used on Linux and the BSDs")
+	}
+}
+
+func TestAppDataDir(t *testing.T) {
+	skipUnlessXDG(t)
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+
+	t.Setenv("XDG_CONFIG_HOME", "")
+	if got, err := appDataDir(false); err != nil || got != filepath.Join(home, ".config") {
+		t.Errorf("without XDG_CONFIG_HOME: got %q, %v, want ~/.config", got, err)
+	}
+	config := filepath.Join(home, "xdg")
+	t.Setenv("XDG_CONFIG_HOME", config)
+	if got, err := appDataDir(true); err != nil || got != config {
+		t.Errorf("with XDG_CONFIG_HOME: got %q, %v, want %q", got, err, config)
+	}
+}
+
+func TestKnownChromiumBrowsersLinux(t *testing.T) {
+	skipUnlessXDG(t)
+	config := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", config)
+	overridePath(t, "vivaldi", "/opt/vivaldi-data")
+
+	browsers, err := KnownChromiumBrowsers()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{
+		"chrome":   filepath.Join(config, "google-chrome"),
+		"chromium": filepath.Join(config, "chromium"),
+		"edge":     filepath.Join(config, "microsoft-edge"),
+		"brave":    filepath.Join(config, "BraveSoftware", "Brave-Browser"),
+		"vivaldi":  "/opt/vivaldi-data",
+		"opera":    filepath.Join(config, "opera"),
+	}
+	got := map[string]string{}
+	for _, browser := range browsers {
+		got[browser.Name] = browser.DataDir
+	}
+	for name, dir := range want {
+		if got[name] != dir {
+			t.Errorf("%s: data dir %q, want %q", name, got[name], dir)
+		}
+	}
+	if _, ok := got["arc"]; ok {
+		t.Errorf("arc is listed, but has no Linux build")
+	}
+}
+
+func TestFirefoxDataPathLinux(t *testing.T) {
+	skipUnlessXDG(t)
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	native := filepath.Join(home, ".mozilla", "firefox")
+	snap := filepath.Join(home, "snap", "firefox", "common", ".mozilla", "firefox")
+
+	if got, _ := FirefoxDataPath(); got != native {
+		t.Errorf("nothing installed: got %q, want %q", got, native)
+	}
+	if err := os.MkdirAll(snap, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := FirefoxDataPath(); got != snap {
+		t.Errorf("Snap only: got %q, want %q", got, snap)
+	}
+	if err := os.MkdirAll(native, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := FirefoxDataPath(); got != native {
+		t.Errorf("both: got %q, want %q", got, native)
+	}
+}
+
+func TestSourcePath(t *testing.T) {
+	overridePath(t, "firefox", "/srv/firefox")
+	if path, overridden, err := SourcePath("firefox"); err != nil || !overridden || path != "/srv/firefox" {
+		t.Errorf("firefox: got %q, %v, %v, want the override", path, overridden, err)
+	}
+	browsers, err := KnownChromiumBrowsers()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, browser := range browsers {
+		if path, overridden, err := SourcePath(browser.Name); err != nil || overridden || path != browser.DataDir {
+			t.Errorf("%s: got %q, %v, %v, want its default %q", browser.Name, path, overridden, err, browser.DataDir)
+		}
+	}
+	if _, _, err := SourcePath("netscape"); err == nil {
+		t.Errorf("unknown source: want an error")
+	}
+	if err := SetPath("netscape", "/tmp"); err == nil {
+		t.Errorf("SetPath on an unknown source: want an error")
+	}
+}
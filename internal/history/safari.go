@@ -1,10 +1,14 @@
 package history
 
 import (
+	"context"
 	"database/sql"
 	"errors"
+	"io/fs"
 	"os"
 	"path/filepath"
+	"runtime"
+	"strings"
 	"time"
 
 	_ "modernc.org/sqlite"
@@ -13,6 +17,12 @@ import (
 var safariEpoch = time.Date(2001, 1, 1, 0, 0, 0, 0, time.UTC)
 
 func SafariHistoryPath() (string, error) {
+	if path, ok := pathOverrides["safari"]; ok {
+		return path, nil
+	}
+	if runtime.GOOS != "darwin" {
+		return "", ErrSafariUnavailable
+	}
 	home, err := os.UserHomeDir()
 	if err != nil {
 		return "", err
@@ -20,40 +30,28 @@ func SafariHistoryPath() (string, error) {
 	return filepath.Join(home, "Library", "Safari", "History.db"), nil
 }
 
-func ReadSafariHistory(since *time.Time, until *time.Time) ([]Entry, error) {
+func ReadSafariHistory(ctx context.Context, since *time.Time, until *time.Time) ([]Entry, error) {
 	path, err := SafariHistoryPath()
 	if err != nil {
 		return nil, err
 	}
 	if _, err := os.Stat(path); err != nil {
+		if errors.Is(err, fs.ErrPermission) {
+			return nil, ErrSafariPermission
+		}
 		return nil, err
 	}
 
-	tmpFile, err := os.CreateTemp("", "weblog-safari-*.db")
-	if err != nil {
-		return nil, err
-	}
-	tmpPath := tmpFile.Name()
-	_ = tmpFile.Close()
-	defer func() {
-		_ = os.Remove(tmpPath)
-		_ = os.Remove(tmpPath + "-wal")
-		_ = os.Remove(tmpPath + "-shm")
-	}()
-
-	if err := copyFile(path, tmpPath); err != nil {
-		return nil, err
-	}
-	_ = copyFile(path+"-wal", tmpPath+"-wal")
-	_ = copyFile(path+"-shm", tmpPath+"-shm")
-
-	db, err := sql.Open("sqlite", tmpPath)
+	db, cleanup, err := openSnapshot(ctx, path, "weblog-safari-*.db")
 	if err != nil {
+		if errors.Is(err, fs.ErrPermission) {
+			return nil, ErrSafariPermission
+		}
 		return nil, err
 	}
-	defer db.Close()
+	defer cleanup()
 
-	hasItemTitle, hasVisitTitle, err := safariTitleColumns(db)
+	hasItemTitle, hasVisitTitle, err := safariTitleColumns(ctx, db)
 	if err != nil {
 		return nil, err
 	}
@@ -68,7 +66,21 @@ func ReadSafariHistory(since *time.Time, until *time.Time) ([]Entry, error) {
 		titleExpr = "hi.title"
 	}
 
-	query := "SELECT hi.url, " + titleExpr + " as title, hv.visit_time FROM history_visits hv JOIN history_items hi ON hv.history_item = hi.id"
+	// Redirect bookkeeping columns only exist on newer Safari versions
+	redirectExprs := []string{"NULL", "NULL", "0", "NULL"}
+	redirectJoin := ""
+	hasRedirects, err := columnExists(ctx, db, "history_visits", "redirect_source")
+	if err != nil {
+		return nil, err
+	}
+	if hasRedirects {
+		redirectExprs = []string{"hv.redirect_source", "hv.redirect_destination", "COALESCE(hv.http_non_get, 0)", "source_items.url"}
+		redirectJoin = " LEFT JOIN history_visits source_visits ON source_visits.id = hv.redirect_source" +
+			" LEFT JOIN history_items source_items ON source_items.id = source_visits.history_item"
+	}
+
+	query := "SELECT hv.id, hi.url, " + titleExpr + " as title, hv.visit_time, " + strings.Join(redirectExprs, ", ") +
+		" FROM history_visits hv JOIN history_items hi ON hv.history_item = hi.id" + redirectJoin
 	args := []any{}
 	conditions := []string{}
 	if since != nil {
@@ -86,7 +98,7 @@ func ReadSafariHistory(since *time.Time, until *time.Time) ([]Entry, error) {
 	}
 	query += " ORDER BY hv.visit_time DESC"
 
-	rows, err := db.Query(query, args...)
+	rows, err := db.QueryContext(ctx, query, args...)
 	if err != nil {
 		return nil, err
 	}
@@ -94,18 +106,27 @@ func ReadSafariHistory(since *time.Time, until *time.Time) ([]Entry, error) {
 
 	entries := []Entry{}
 	for rows.Next() {
+		var visitID int64
 		var url string
 		var title sql.NullString
 		var visitRaw float64
-		if err := rows.Scan(&url, &title, &visitRaw); err != nil {
+		var redirectSource sql.NullInt64
+		var redirectDestination sql.NullInt64
+		var httpNonGet int64
+		var referrer sql.NullString
+		if err := rows.Scan(&visitID, &url, &title, &visitRaw, &redirectSource, &redirectDestination, &httpNonGet, &referrer); err != nil {
 			return nil, err
 		}
 		visitTime := safariEpoch.Add(time.Duration(visitRaw * float64(time.Second)))
 		entry := Entry{
-			URL:       url,
-			Title:     title.String,
-			VisitTime: visitTime,
-			Source:    "safari",
+			URL:        url,
+			Title:      title.String,
+			VisitTime:  visitTime,
+			Source:     "safari",
+			VisitID:    visitID,
+			Transition: safariTransition(redirectSource.Int64 != 0, redirectDestination.Int64 != 0, httpNonGet != 0),
+			FromVisit:  redirectSource.Int64,
+			Referrer:   referrer.String,
 		}
 		entries = append(entries, entry)
 	}
@@ -115,20 +136,38 @@ func ReadSafariHistory(since *time.Time, until *time.Time) ([]Entry, error) {
 	return entries, nil
 }
 
-func safariTitleColumns(db *sql.DB) (bool, bool, error) {
-	hasItemTitle, err := columnExists(db, "history_items", "title")
+// safariTransition derives what it can from Safari's redirect columns; Safari
+// does not record whether a page was typed or followed from a link. A visit
+// with redirect_destination set is a hop that redirected on, like a Chromium
+// visit in a redirect chain that is not its end; one with only
+// redirect_source set is the page the redirect landed on.
+func safariTransition(redirectTarget bool, redirectHop bool, nonGet bool) Transition {
+	switch {
+	case redirectHop:
+		return TransitionRedirect
+	case nonGet:
+		return TransitionFormSubmit
+	case redirectTarget:
+		return TransitionLink
+	default:
+		return TransitionUnknown
+	}
+}
+
+func safariTitleColumns(ctx context.Context, db *sql.DB) (bool, bool, error) {
+	hasItemTitle, err := columnExists(ctx, db, "history_items", "title")
 	if err != nil {
 		return false, false, err
 	}
-	hasVisitTitle, err := columnExists(db, "history_visits", "title")
+	hasVisitTitle, err := columnExists(ctx, db, "history_visits", "title")
 	if err != nil {
 		return false, false, err
 	}
 	return hasItemTitle, hasVisitTitle, nil
 }
 
-func columnExists(db *sql.DB, table string, column string) (bool, error) {
-	rows, err := db.Query("PRAGMA table_info(" + table + ")")
+func columnExists(ctx context.Context, db *sql.DB, table string, column string) (bool, error) {
+	rows, err := db.QueryContext(ctx, "PRAGMA table_info("+table+")")
 	if err != nil {
 		return false, err
 	}
@@ -150,28 +189,6 @@ func columnExists(db *sql.DB, table string, column string) (bool, error) {
 	return false, rows.Err()
 }
 
-func copyFile(src, dst string) error {
-	input, err := os.Open(src)
-	if err != nil {
-		return err
-	}
-	defer input.Close()
-
-	output, err := os.Create(dst)
-	if err != nil {
-		return err
-	}
-	defer func() {
-		_ = output.Close()
-	}()
-
-	_, err = output.ReadFrom(input)
-	if err != nil {
-		return err
-	}
-	return nil
-}
-
 func joinConditions(conditions []string) string {
 	if len(conditions) == 0 {
 		return ""
@@ -0,0 +1,65 @@
+package history
+
+import (
+	"context"
+	"database/sql"
+	"path/filepath"
+	"testing"
+)
+
+// TestReadSafariRedirects reads a History.db with Safari's redirect columns,
+// where a t.co hop (visit 1) redirected on to the article (visit 2).
+func TestReadSafariRedirects(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "History.db")
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.Exec(`
+CREATE TABLE history_items (id INTEGER PRIMARY KEY, url TEXT NOT NULL UNIQUE);
+CREATE TABLE history_visits (
+	id INTEGER PRIMARY KEY,
+	history_item INTEGER NOT NULL,
+	visit_time REAL NOT NULL,
+	title TEXT NULL,
+	redirect_source INTEGER NULL UNIQUE,
+	redirect_destination INTEGER NULL UNIQUE,
+	http_non_get BOOLEAN DEFAULT 0
+);
+INSERT INTO history_items VALUES (1, 'https://t.co/abc'), (2, 'https://example.com/article'), (3, 'https://example.com/');
+INSERT INTO history_visits VALUES
+	(1, 1, 800000000, NULL, NULL, 2, 0),
+	(2, 2, 800000001, 'Article', 1, NULL, 0),
+	(3, 3, 800000002, 'Example', NULL, NULL, 0);
+`); err != nil {
+		t.Fatal(err)
+	}
+	db.Close()
+
+	old, hadOld := pathOverrides["safari"]
+	pathOverrides["safari"] = path
+	t.Cleanup(func() {
+		if hadOld {
+			pathOverrides["safari"] = old
+		} else {
+			delete(pathOverrides, "safari")
+		}
+	})
+
+	entries, err := ReadSafariHistory(context.Background(), nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[int64]Transition{1: TransitionRedirect, 2: TransitionLink, 3: TransitionUnknown}
+	if len(entries) != len(want) {
+		t.Fatalf("got %d entries, want %d", len(entries), len(want))
+	}
+	for _, entry := range entries {
+		if entry.Transition != want[entry.VisitID] {
+			t.Errorf("visit %d (%s): transition %q, want %q", entry.VisitID, entry.URL, entry.Transition, want[entry.VisitID])
+		}
+		if entry.VisitID == 2 && entry.Referrer != "https://t.co/abc" {
+			t.Errorf("redirect target referrer = %q, want the hop's URL", entry.Referrer)
+		}
+	}
+}
@@ -0,0 +1,136 @@
+package history
+
+import (
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Search is a query typed into a search engine or a site's search box.
+type Search struct {
+	Engine    string
+	Query     string
+	VisitTime time.Time
+	URL       string
+	Source    string
+	Profile   string
+}
+
+// searchEngines maps host matchers to the path and query parameter the
+// engine puts the search terms in.
+var searchEngines = []struct {
+	name   string
+	match  func(host string) bool
+	path   string
+	params []string
+}{
+	{"google", func(h string) bool { return hostHasLabel(h, "google") }, "/search", []string{"q"}},
+	{"duckduckgo", func(h string) bool { return hostIs(h, "duckduckgo.com") }, "", []string{"q"}},
+	{"bing", func(h string) bool { return hostIs(h, "bing.com") }, "/search", []string{"q"}},
+	{"kagi", func(h string) bool { return hostIs(h, "kagi.com") }, "/search", []string{"q"}},
+	{"youtube", func(h string) bool { return hostIs(h, "youtube.com") }, "/results", []string{"search_query"}},
+	{"github", func(h string) bool { return h == "github.com" }, "/search", []string{"q"}},
+	{"amazon", func(h string) bool { return hostHasLabel(h, "amazon") }, "/s", []string{"k", "field-keywords"}},
+}
+
+// siteSearchParams are the parameters sites commonly use for their own search
+// box; they are only trusted on paths that mention "search".
+var siteSearchParams = []string{"q", "query", "search", "s", "k", "keyword", "keywords", "term"}
+
+// ExtractSearch returns the search behind an entry, if any. Terms Chrome
+// recorded in keyword_search_terms win over parsing the URL.
+func ExtractSearch(entry Entry) (Search, bool) {
+	parsed, err := url.Parse(entry.URL)
+	if err != nil || parsed.Host == "" {
+		return Search{}, false
+	}
+	host := strings.TrimPrefix(strings.ToLower(parsed.Hostname()), "www.")
+	search := Search{
+		VisitTime: entry.VisitTime,
+		URL:       entry.URL,
+		Source:    entry.Source,
+		Profile:   entry.Profile,
+	}
+
+	engine, query := "", ""
+	values := parsed.Query()
+	for _, e := range searchEngines {
+		if !e.match(host) {
+			continue
+		}
+		if e.path != "" && parsed.Path != e.path {
+			break
+		}
+		engine = e.name
+		query = firstParam(values, e.params)
+		break
+	}
+	if engine == "" && strings.Contains(strings.ToLower(parsed.Path), "search") {
+		if query = firstParam(values, siteSearchParams); query != "" {
+			engine = host
+		}
+	}
+	if entry.SearchTerm != "" {
+		query = entry.SearchTerm
+		if engine == "" {
+			engine = host
+		}
+	}
+
+	search.Engine = engine
+	search.Query = strings.Join(strings.Fields(query), " ")
+	if search.Engine == "" || search.Query == "" {
+		return Search{}, false
+	}
+	return search, true
+}
+
+// ExtractSearches returns the distinct searches in entries, oldest first.
+// Repeating a query on the same engine on the same day counts once.
+func ExtractSearches(entries []Entry) []Search {
+	seen := map[string]bool{}
+	searches := []Search{}
+	for _, entry := range entries {
+		search, ok := ExtractSearch(entry)
+		if !ok {
+			continue
+		}
+		key := search.VisitTime.Format("2006-01-02") + "\x00" + search.Engine + "\x00" + strings.ToLower(search.Query)
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		searches = append(searches, search)
+	}
+	sort.Slice(searches, func(i, j int) bool {
+		return searches[i].VisitTime.Before(searches[j].VisitTime)
+	})
+	return searches
+}
+
+func firstParam(values url.Values, params []string) string {
+	for _, param := range params {
+		if value := strings.TrimSpace(values.Get(param)); value != "" {
+			return value
+		}
+	}
+	return ""
+}
+
+// hostIs matches domain and its subdomains, but not other domains that
+// merely end in the same letters (notbing.com).
+func hostIs(host string, domain string) bool {
+	return host == domain || strings.HasSuffix(host, "."+domain)
+}
+
+// hostHasLabel matches engines with many country domains, e.g. google.com,
+// google.co.uk and google.ch for "google".
+func hostHasLabel(host string, label string) bool {
+	for _, part := range strings.Split(host, ".") {
+		if part == label {
+			return true
+		}
+	}
+	return false
+}
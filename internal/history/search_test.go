@@ -0,0 +1,60 @@
+package history
+
+import (
+	"testing"
+	"time"
+)
+
+func TestExtractSearch(t *testing.T) {
+	tests := []struct {
+		url        string
+		searchTerm string
+		engine     string
+		query      string
+	}{
+		{"https://www.google.com/search?q=garmin+venu", "", "google", "garmin venu"},
+		{"https://www.google.co.uk/search?q=tea", "", "google", "tea"},
+		{"https://www.google.com/maps?q=zurich", "", "", ""},
+		{"https://duckduckgo.com/?q=go+generics", "", "duckduckgo", "go generics"},
+		{"https://html.duckduckgo.com/html/?q=sqlite", "", "duckduckgo", "sqlite"},
+		{"https://www.bing.com/search?q=weather", "", "bing", "weather"},
+		{"https://notbing.com/search?q=weather", "", "notbing.com", "weather"},
+		{"https://evilkagi.com/?q=x", "", "", ""},
+		{"https://m.youtube.com/results?search_query=lofi", "", "youtube", "lofi"},
+		{"https://github.com/search?q=ralphy&type=code", "", "github", "ralphy"},
+		{"https://www.amazon.de/s?k=usb-c+cable", "", "amazon", "usb-c cable"},
+		{"https://docs.python.org/3/search.html?q=asyncio", "", "docs.python.org", "asyncio"},
+		{"https://example.com/about?q=not-a-search", "", "", ""},
+		{"https://www.google.com/search?q=", "", "", ""},
+		{"https://www.google.com/search?q=%20%20spaced%20%20out%20", "", "google", "spaced out"},
+		{"https://www.google.com/search?q=typo", "recorded term", "google", "recorded term"},
+		{"https://intranet.example/find", "quarterly report", "intranet.example", "quarterly report"},
+		{"not a url", "", "", ""},
+	}
+	for _, tt := range tests {
+		search, ok := ExtractSearch(Entry{URL: tt.url, SearchTerm: tt.searchTerm})
+		if ok != (tt.engine != "") || search.Engine != tt.engine || search.Query != tt.query {
+			t.Errorf("ExtractSearch(%q) = %q, %q, %v; want %q, %q", tt.url, search.Engine, search.Query, ok, tt.engine, tt.query)
+		}
+	}
+}
+
+func TestExtractSearchesDedupesPerDay(t *testing.T) {
+	day := time.Date(2026, 10, 12, 9, 0, 0, 0, time.UTC)
+	entries := []Entry{
+		{URL: "https://www.google.com/search?q=Clawdbot", VisitTime: day.Add(2 * time.Hour)},
+		{URL: "https://www.google.com/search?q=clawdbot", VisitTime: day},
+		{URL: "https://duckduckgo.com/?q=clawdbot", VisitTime: day.Add(time.Hour)},
+		{URL: "https://www.google.com/search?q=clawdbot", VisitTime: day.Add(24 * time.Hour)},
+		{URL: "https://example.com/", VisitTime: day},
+	}
+	searches := ExtractSearches(entries)
+	if len(searches) != 3 {
+		t.Fatalf("got %d searches, want 3: %+v", len(searches), searches)
+	}
+	for i := 1; i < len(searches); i++ {
+		if searches[i].VisitTime.Before(searches[i-1].VisitTime) {
+			t.Errorf("searches not oldest first: %+v", searches)
+		}
+	}
+}
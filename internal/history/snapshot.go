@@ -0,0 +1,81 @@
+package history
+
+import (
+	"context"
+	"database/sql"
+	"io"
+	"os"
+
+	_ "modernc.org/sqlite"
+)
+
+// openSnapshot copies a browser database (and its WAL/SHM side files) to a
+// temp location and opens the copy, so the browser's lock on the live file
+// never gets in the way. The returned cleanup closes the db and removes the
+// copies.
+func openSnapshot(ctx context.Context, path string, pattern string) (*sql.DB, func(), error) {
+	tmpFile, err := os.CreateTemp("", pattern)
+	if err != nil {
+		return nil, nil, err
+	}
+	tmpPath := tmpFile.Name()
+	_ = tmpFile.Close()
+	remove := func() {
+		_ = os.Remove(tmpPath)
+		_ = os.Remove(tmpPath + "-wal")
+		_ = os.Remove(tmpPath + "-shm")
+	}
+
+	if err := copyFile(ctx, path, tmpPath); err != nil {
+		remove()
+		return nil, nil, err
+	}
+	_ = copyFile(ctx, path+"-wal", tmpPath+"-wal")
+	_ = copyFile(ctx, path+"-shm", tmpPath+"-shm")
+
+	db, err := sql.Open("sqlite", tmpPath)
+	if err != nil {
+		remove()
+		return nil, nil, err
+	}
+	return db, func() {
+		_ = db.Close()
+		remove()
+	}, nil
+}
+
+// copyFile copies src to dst, giving up between chunks once ctx is done so a
+// multi-hundred-MB History file doesn't outlive a timeout.
+func copyFile(ctx context.Context, src, dst string) error {
+	input, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer input.Close()
+
+	output, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = output.Close()
+	}()
+
+	_, err = io.Copy(output, contextReader{ctx: ctx, r: input})
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+type contextReader struct {
+	ctx context.Context
+	r   io.Reader
+}
+
+func (c contextReader) Read(p []byte) (int, error) {
+	if err := c.ctx.Err(); err != nil {
+		return 0, err
+	}
+	return c.r.Read(p)
+}
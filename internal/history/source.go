@@ -0,0 +1,173 @@
+package history
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"strings"
+	"time"
+)
+
+// HistorySource is anything that can produce history entries: a browser
+// database, an importer, or a fake in tests.
+type HistorySource interface {
+	// Name is the short identifier used in --sources and Entry.Source.
+	Name() string
+	// Available reports whether the source exists on this machine.
+	Available() bool
+	Read(ctx context.Context, since *time.Time, until *time.Time) ([]Entry, error)
+}
+
+var registry []HistorySource
+
+// Register adds a source to the registry. It panics if a source with the
+// same name is already registered.
+func Register(source HistorySource) {
+	if _, ok := LookupSource(source.Name()); ok {
+		panic("history: source registered twice: " + source.Name())
+	}
+	registry = append(registry, source)
+}
+
+// Sources returns all registered sources in registration order.
+func Sources() []HistorySource {
+	return append([]HistorySource(nil), registry...)
+}
+
+func LookupSource(name string) (HistorySource, bool) {
+	for _, source := range registry {
+		if source.Name() == name {
+			return source, true
+		}
+	}
+	return nil, false
+}
+
+// SelectSources resolves a comma-separated --sources value. An empty value
+// or "all" selects every registered source; names prefixed with "-" are
+// removed from the selection, e.g. "all,-edge" or just "-edge".
+func SelectSources(spec string) ([]HistorySource, error) {
+	spec = strings.TrimSpace(spec)
+	if spec == "" || spec == "all" {
+		return Sources(), nil
+	}
+
+	include := []string{}
+	exclude := map[string]bool{}
+	for _, name := range strings.Split(spec, ",") {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" {
+			continue
+		}
+		excluded := strings.HasPrefix(name, "-")
+		name = strings.TrimPrefix(name, "-")
+		if name != "all" {
+			if _, ok := LookupSource(name); !ok {
+				return nil, fmt.Errorf("unknown source %q (see 'web-log sources')", name)
+			}
+		}
+		if excluded {
+			exclude[name] = true
+		} else {
+			include = append(include, name)
+		}
+	}
+	if len(include) == 0 {
+		include = append(include, "all")
+	}
+
+	selected := []HistorySource{}
+	added := map[string]bool{}
+	for _, name := range include {
+		candidates := []HistorySource{}
+		if name == "all" {
+			candidates = Sources()
+		} else {
+			source, _ := LookupSource(name)
+			candidates = append(candidates, source)
+		}
+		for _, source := range candidates {
+			if exclude[source.Name()] || added[source.Name()] {
+				continue
+			}
+			added[source.Name()] = true
+			selected = append(selected, source)
+		}
+	}
+	return selected, nil
+}
+
+type safariSource struct{}
+
+func (safariSource) Name() string { return "safari" }
+
+func (safariSource) Available() bool {
+	path, err := SafariHistoryPath()
+	if err != nil {
+		return false
+	}
+	// A permission error means Safari is there but Full Disk Access is
+	// missing; let Read report that instead of hiding the source.
+	_, err = os.Stat(path)
+	return err == nil || errors.Is(err, fs.ErrPermission)
+}
+
+func (safariSource) Read(ctx context.Context, since *time.Time, until *time.Time) ([]Entry, error) {
+	return ReadSafariHistory(ctx, since, until)
+}
+
+type firefoxSource struct{}
+
+func (firefoxSource) Name() string { return "firefox" }
+
+func (firefoxSource) Available() bool { return FirefoxInstalled() }
+
+func (firefoxSource) Read(ctx context.Context, since *time.Time, until *time.Time) ([]Entry, error) {
+	return ReadFirefoxHistory(ctx, since, until)
+}
+
+// chromiumSource resolves its browser lazily so registration never depends
+// on the home directory being available.
+type chromiumSource struct {
+	name string
+}
+
+func (s chromiumSource) Name() string { return s.name }
+
+func (s chromiumSource) browser() (ChromiumBrowser, error) {
+	browsers, err := KnownChromiumBrowsers()
+	if err != nil {
+		return ChromiumBrowser{}, err
+	}
+	for _, browser := range browsers {
+		if browser.Name == s.name {
+			return browser, nil
+		}
+	}
+	return ChromiumBrowser{}, fmt.Errorf("%s: %w", s.name, ErrUnavailable)
+}
+
+func (s chromiumSource) Available() bool {
+	browser, err := s.browser()
+	return err == nil && browser.Installed()
+}
+
+func (s chromiumSource) Read(ctx context.Context, since *time.Time, until *time.Time) ([]Entry, error) {
+	browser, err := s.browser()
+	if err != nil {
+		return nil, err
+	}
+	return ReadChromiumHistory(ctx, browser, since, until)
+}
+
+func init() {
+	Register(safariSource{})
+	for _, b := range chromiumBrowsers {
+		if b.platformDir() != nil {
+			Register(chromiumSource{name: b.name})
+		}
+	}
+	Register(firefoxSource{})
+}
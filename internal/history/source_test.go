@@ -0,0 +1,113 @@
+package history
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// fakeSource returns fixed entries, or blocks until released when block is
+// set, ignoring ctx like a reader stuck in a file copy would.
+type fakeSource struct {
+	name      string
+	available bool
+	entries   []Entry
+	err       error
+	block     chan struct{}
+}
+
+func (s fakeSource) Name() string { return s.name }
+
+func (s fakeSource) Available() bool { return s.available }
+
+func (s fakeSource) Read(ctx context.Context, since *time.Time, until *time.Time) ([]Entry, error) {
+	if s.block != nil {
+		<-s.block
+	}
+	return s.entries, s.err
+}
+
+func sourceNames(sources []HistorySource) []string {
+	names := []string{}
+	for _, source := range sources {
+		names = append(names, source.Name())
+	}
+	return names
+}
+
+func TestSelectSources(t *testing.T) {
+	all := sourceNames(Sources())
+	without := func(excluded ...string) []string {
+		skip := map[string]bool{}
+		for _, name := range excluded {
+			skip[name] = true
+		}
+		names := []string{}
+		for _, name := range all {
+			if !skip[name] {
+				names = append(names, name)
+			}
+		}
+		return names
+	}
+	tests := []struct {
+		spec    string
+		want    []string
+		wantErr bool
+	}{
+		{spec: "", want: all},
+		{spec: "all", want: all},
+		{spec: " firefox ", want: []string{"firefox"}},
+		{spec: "firefox,Chrome", want: []string{"firefox", "chrome"}},
+		{spec: "chrome,chrome", want: []string{"chrome"}},
+		{spec: "-edge", want: without("edge")},
+		{spec: "all,-edge,-firefox", want: without("edge", "firefox")},
+		{spec: "chrome,firefox,-firefox", want: []string{"chrome"}},
+		{spec: "firefox,all", want: append([]string{"firefox"}, without("firefox")...)},
+		{spec: "chrome,,", want: []string{"chrome"}},
+		{spec: "netscape", wantErr: true},
+		{spec: "all,-netscape", wantErr: true},
+	}
+	for _, test := range tests {
+		got, err := SelectSources(test.spec)
+		if test.wantErr {
+			if err == nil {
+				t.Errorf("%q: got %v, want an error", test.spec, sourceNames(got))
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%q: %v", test.spec, err)
+			continue
+		}
+		if names := sourceNames(got); !reflect.DeepEqual(names, test.want) {
+			t.Errorf("%q: got %v, want %v", test.spec, names, test.want)
+		}
+	}
+}
+
+func TestRegister(t *testing.T) {
+	saved := registry
+	t.Cleanup(func() { registry = saved })
+	registry = append([]HistorySource(nil), saved...)
+
+	Register(fakeSource{name: "fake"})
+	if source, ok := LookupSource("fake"); !ok || source.Name() != "fake" {
+		t.Fatalf("LookupSource(fake) = %v, %v after Register", source, ok)
+	}
+	if names := sourceNames(Sources()); names[len(names)-1] != "fake" {
+		t.Errorf("Sources() = %v, want fake last", names)
+	}
+	selected, err := SelectSources("fake")
+	if err != nil || len(selected) != 1 {
+		t.Errorf("SelectSources(fake) = %v, %v", sourceNames(selected), err)
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Errorf("registering a name twice did not panic")
+		}
+	}()
+	Register(fakeSource{name: "fake"})
+}
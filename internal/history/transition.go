@@ -0,0 +1,92 @@
+package history
+
+// Transition says how the browser arrived at a page.
+type Transition string
+
+const (
+	TransitionUnknown    Transition = ""
+	TransitionLink       Transition = "link"
+	TransitionTyped      Transition = "typed"
+	TransitionBookmark   Transition = "bookmark"
+	TransitionFormSubmit Transition = "form_submit"
+	TransitionReload     Transition = "reload"
+	// TransitionRedirect marks an intermediate hop that redirected on to
+	// another page (t.co links, login bounces), not the page that was read.
+	TransitionRedirect Transition = "redirect"
+	// TransitionSubframe is a navigation inside an iframe.
+	TransitionSubframe Transition = "subframe"
+	TransitionOther    Transition = "other"
+)
+
+// Chrome packs a core type into the low byte of visits.transition and
+// qualifier bits into the high ones (ui::PageTransition).
+const (
+	chromeCoreMask   = 0xFF
+	chromeChainStart = 0x10000000
+	chromeChainEnd   = 0x20000000
+	chromeRedirects  = 0x40000000 | 0x80000000
+)
+
+func chromeTransition(raw int64) Transition {
+	// Every visit in a redirect chain but the last lacks CHAIN_END.
+	if raw&(chromeChainStart|chromeRedirects) != 0 && raw&chromeChainEnd == 0 {
+		return TransitionRedirect
+	}
+	switch raw & chromeCoreMask {
+	case 0:
+		return TransitionLink
+	case 1, 5, 9, 10:
+		// Typed, omnibox suggestion, keyword search and its generated visit.
+		return TransitionTyped
+	case 2:
+		return TransitionBookmark
+	case 3, 4:
+		return TransitionSubframe
+	case 7:
+		return TransitionFormSubmit
+	case 8:
+		return TransitionReload
+	default:
+		return TransitionOther
+	}
+}
+
+// firefoxTransition maps moz_historyvisits.visit_type. Firefox stores the
+// redirect on the destination visit (types 5 and 6), so the query marks the
+// source visit of such a redirect separately.
+func firefoxTransition(visitType int64, redirectSource bool) Transition {
+	if redirectSource {
+		return TransitionRedirect
+	}
+	switch visitType {
+	case 1, 5, 6:
+		return TransitionLink
+	case 2:
+		return TransitionTyped
+	case 3:
+		return TransitionBookmark
+	case 4, 8:
+		return TransitionSubframe
+	case 9:
+		return TransitionReload
+	default:
+		return TransitionOther
+	}
+}
+
+// IsNavigation reports whether the visit is a page the user actually landed
+// on, as opposed to a redirect hop, reload or iframe load.
+func (t Transition) IsNavigation() bool {
+	return t != TransitionRedirect && t != TransitionReload && t != TransitionSubframe
+}
+
+// FilterNavigations drops redirects, reloads and iframe loads.
+func FilterNavigations(entries []Entry) []Entry {
+	result := make([]Entry, 0, len(entries))
+	for _, entry := range entries {
+		if entry.Transition.IsNavigation() {
+			result = append(result, entry)
+		}
+	}
+	return result
+}
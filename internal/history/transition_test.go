@@ -0,0 +1,74 @@
+package history
+
+import "testing"
+
+func TestChromeTransition(t *testing.T) {
+	tests := []struct {
+		name string
+		raw  int64
+		want Transition
+	}{
+		{"link", 0 | chromeChainStart | chromeChainEnd, TransitionLink},
+		{"typed", 1 | chromeChainStart | chromeChainEnd, TransitionTyped},
+		{"keyword", 10, TransitionTyped},
+		{"bookmark", 2, TransitionBookmark},
+		{"subframe", 3, TransitionSubframe},
+		{"form", 7 | chromeChainStart | chromeChainEnd, TransitionFormSubmit},
+		{"reload", 8, TransitionReload},
+		{"chain start", 0 | chromeChainStart, TransitionRedirect},
+		{"server redirect mid-chain", 0 | 0x80000000, TransitionRedirect},
+		{"redirect chain end", 0 | 0x80000000 | chromeChainEnd, TransitionLink},
+		{"generated", 6, TransitionOther},
+	}
+	for _, tt := range tests {
+		if got := chromeTransition(tt.raw); got != tt.want {
+			t.Errorf("%s: chromeTransition(%#x) = %q, want %q", tt.name, tt.raw, got, tt.want)
+		}
+	}
+}
+
+func TestFirefoxTransition(t *testing.T) {
+	tests := []struct {
+		visitType      int64
+		redirectSource bool
+		want           Transition
+	}{
+		{1, false, TransitionLink},
+		{2, false, TransitionTyped},
+		{3, false, TransitionBookmark},
+		{5, false, TransitionLink},
+		{8, false, TransitionSubframe},
+		{9, false, TransitionReload},
+		{1, true, TransitionRedirect},
+		{7, false, TransitionOther},
+	}
+	for _, tt := range tests {
+		if got := firefoxTransition(tt.visitType, tt.redirectSource); got != tt.want {
+			t.Errorf("firefoxTransition(%d, %v) = %q, want %q", tt.visitType, tt.redirectSource, got, tt.want)
+		}
+	}
+}
+
+func TestSafariTransition(t *testing.T) {
+	tests := []struct {
+		name                        string
+		redirectTarget, redirectHop bool
+		nonGet                      bool
+		want                        Transition
+	}{
+		{"plain visit", false, false, false, TransitionUnknown},
+		{"redirect source hop", false, true, false, TransitionRedirect},
+		{"redirect target", true, false, false, TransitionLink},
+		{"middle of a chain", true, true, false, TransitionRedirect},
+		{"post that redirected", false, true, true, TransitionRedirect},
+		{"form submit", false, false, true, TransitionFormSubmit},
+	}
+	for _, tt := range tests {
+		if got := safariTransition(tt.redirectTarget, tt.redirectHop, tt.nonGet); got != tt.want {
+			t.Errorf("%s: got %q, want %q", tt.name, got, tt.want)
+		}
+		if got := safariTransition(tt.redirectTarget, tt.redirectHop, tt.nonGet).IsNavigation(); got == (tt.want == TransitionRedirect) {
+			t.Errorf("%s: IsNavigation = %v", tt.name, got)
+		}
+	}
+}
@@ -1,31 +1,80 @@
 package history
 
-import "time"
+import (
+	"sort"
+	"time"
+)
 
 type Entry struct {
 	URL       string
 	Title     string
 	VisitTime time.Time
 	Source    string
+	// Profile is the browser profile's display name (e.g. "Work"), empty
+	// for browsers without profiles.
+	Profile string
+	// ProfileDir is the profile's directory name (e.g. "Profile 1"), which
+	// unlike the display name is unique and survives renames.
+	ProfileDir string
+	// VisitID is the visit's row id in the source database, unique per
+	// source and profile directory.
+	VisitID    int64
+	Transition Transition
+	// Duration is the time spent on the page, when the browser records it.
+	Duration time.Duration
+	// FromVisit is the VisitID of the referring visit and Referrer its URL.
+	FromVisit int64
+	Referrer  string
+	// SearchTerm is the query the browser itself recorded for a search
+	// results page (Chrome's keyword_search_terms).
+	SearchTerm string
 }
 
+// Deduplicate collapses repeated visits to a URL within one browser profile
+// into its most recent visit, adding up the time spent on the page. The
+// result is ordered by visit time, newest first.
 func Deduplicate(entries []Entry) []Entry {
 	if len(entries) == 0 {
 		return entries
 	}
-	seen := make(map[string]Entry, len(entries))
+	// Profiles are kept apart by browser and directory, so Work and
+	// Personal visits to the same URL both survive even when two browsers
+	// name a profile alike or a profile is renamed.
+	type key struct{ source, profileDir, url string }
+	seen := make(map[key]Entry, len(entries))
 	for _, entry := range entries {
 		if entry.URL == "" {
 			continue
 		}
-		existing, ok := seen[entry.URL]
+		k := key{entry.Source, entry.ProfileDir, entry.URL}
+		existing, ok := seen[k]
 		if !ok || entry.VisitTime.After(existing.VisitTime) {
-			seen[entry.URL] = entry
+			// Keep the most recent visit but count time from all of them
+			if ok {
+				entry.Duration += existing.Duration
+			}
+			seen[k] = entry
+		} else {
+			existing.Duration += entry.Duration
+			seen[k] = existing
 		}
 	}
 	result := make([]Entry, 0, len(seen))
 	for _, entry := range seen {
 		result = append(result, entry)
 	}
+	sort.Slice(result, func(i, j int) bool {
+		a, b := result[i], result[j]
+		if !a.VisitTime.Equal(b.VisitTime) {
+			return a.VisitTime.After(b.VisitTime)
+		}
+		if a.Source != b.Source {
+			return a.Source < b.Source
+		}
+		if a.ProfileDir != b.ProfileDir {
+			return a.ProfileDir < b.ProfileDir
+		}
+		return a.URL < b.URL
+	})
 	return result
 }
@@ -0,0 +1,31 @@
+package history
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestDeduplicate(t *testing.T) {
+	base := time.Date(2026, 3, 5, 9, 0, 0, 0, time.UTC)
+	entries := []Entry{
+		{URL: "https://example.com/", Source: "chrome", ProfileDir: "Default", Profile: "Person 1", VisitTime: base, Duration: time.Minute},
+		{URL: "https://example.com/", Source: "chrome", ProfileDir: "Default", Profile: "Person 1", VisitTime: base.Add(2 * time.Hour), Duration: 2 * time.Minute, Title: "latest"},
+		{URL: "https://example.com/", Source: "chrome", ProfileDir: "Default", Profile: "Person 1", VisitTime: base.Add(time.Hour), Duration: 3 * time.Minute},
+		// Same display name in another browser
+		{URL: "https://example.com/", Source: "brave", ProfileDir: "Default", Profile: "Person 1", VisitTime: base},
+		// Same display name in another profile of the same browser
+		{URL: "https://example.com/", Source: "chrome", ProfileDir: "Profile 1", Profile: "Person 1", VisitTime: base},
+		// Renamed profile: same directory, new display name
+		{URL: "https://example.com/", Source: "chrome", ProfileDir: "Default", Profile: "Personal", VisitTime: base.Add(30 * time.Minute)},
+		{URL: "", Source: "chrome", ProfileDir: "Default", VisitTime: base},
+	}
+	want := []Entry{
+		{URL: "https://example.com/", Source: "chrome", ProfileDir: "Default", Profile: "Person 1", VisitTime: base.Add(2 * time.Hour), Duration: 6 * time.Minute, Title: "latest"},
+		{URL: "https://example.com/", Source: "brave", ProfileDir: "Default", Profile: "Person 1", VisitTime: base},
+		{URL: "https://example.com/", Source: "chrome", ProfileDir: "Profile 1", Profile: "Person 1", VisitTime: base},
+	}
+	if got := Deduplicate(entries); !reflect.DeepEqual(got, want) {
+		t.Errorf("got %+v\nwant %+v", got, want)
+	}
+}
@@ -0,0 +1,166 @@
+// Package logseq writes summaries into a Logseq graph as outline blocks on
+// the journal page of each day. The summary is a single top-level block
+// marked with a web-log property; re-running replaces that block and leaves
+// the rest of the page alone.
+package logseq
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"unicode"
+
+	"web-log/internal/report"
+	"web-log/internal/summary"
+)
+
+// marker is the property that identifies web-log's block on a page.
+const marker = "web-log:: summary"
+
+// Graph is a Logseq graph directory, the one holding journals/ and pages/.
+type Graph struct {
+	Dir string
+}
+
+// Write puts the summary of each day with entries on that day's journal
+// page, journals/YYYY_MM_DD.md, and returns the pages written.
+func (g Graph) Write(r *report.Report) ([]string, error) {
+	if g.Dir == "" {
+		return nil, errors.New("no graph directory set")
+	}
+	if info, err := os.Stat(g.Dir); err != nil {
+		return nil, fmt.Errorf("graph: %w", err)
+	} else if !info.IsDir() {
+		return nil, fmt.Errorf("graph %s is not a directory", g.Dir)
+	}
+
+	s := r.Summary
+	paths := []string{}
+	for _, date := range s.Dates() {
+		sources := map[string]int{}
+		entries := 0
+		for _, entry := range s.Entries {
+			if entry.VisitTime.Format("2006-01-02") == date {
+				entries++
+				sources[entry.Source]++
+			}
+		}
+		block := outline(s.ForDay(date), entries, sources, r.Generated.Format("2006-01-02 15:04"))
+		path := filepath.Join(g.Dir, "journals", strings.ReplaceAll(date, "-", "_")+".md")
+		if err := writePage(path, block); err != nil {
+			return paths, fmt.Errorf("%s: %w", path, err)
+		}
+		paths = append(paths, path)
+	}
+	return paths, nil
+}
+
+// outline renders a day's summary as a Logseq block, "Browsing summary",
+// whose properties hold the marker, the entry count and the entries per
+// source. Sections are its child blocks, tags are blocks below them with
+// their sites as a block property, and subtags are nested under their tag
+// as namespaced tags such as #ai-agents/clawdbot.
+func outline(s *summary.Summary, entries int, sources map[string]int, generated string) string {
+	names := make([]string, 0, len(sources))
+	for name := range sources {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	counts := make([]string, len(names))
+	for i, name := range names {
+		counts[i] = fmt.Sprintf("%s %d", name, sources[name])
+	}
+
+	var b strings.Builder
+	b.WriteString("- Browsing summary\n")
+	b.WriteString("  " + marker + "\n")
+	fmt.Fprintf(&b, "  entries:: %d\n", entries)
+	if len(counts) > 0 {
+		fmt.Fprintf(&b, "  sources:: %s\n", strings.Join(counts, ", "))
+	}
+	fmt.Fprintf(&b, "  generated:: %s\n", generated)
+	for _, section := range s.Sections {
+		fmt.Fprintf(&b, "\t- **%s**\n", section.Name)
+		for _, tag := range section.Tags {
+			block(&b, "\t\t", tagRef(tag.Name), tag)
+			for _, sub := range tag.SubTags {
+				block(&b, "\t\t\t", tagRef(tag.Name+"/"+sub.Name), sub)
+			}
+		}
+	}
+	return strings.TrimRight(b.String(), "\n")
+}
+
+func block(b *strings.Builder, indent, ref string, tag summary.Tag) {
+	line := fmt.Sprintf("%s (%d)", ref, tag.Count)
+	if tag.Description != "" {
+		line += " " + tag.Description
+	}
+	b.WriteString(indent + "- " + line + "\n")
+	if len(tag.Sites) > 0 {
+		b.WriteString(indent + "  sites:: " + strings.Join(tag.Sites, ", ") + "\n")
+	}
+}
+
+// tagRef writes name as a tag, bracketed when it has characters that would
+// end a plain #tag.
+func tagRef(name string) string {
+	for _, r := range name {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) && !strings.ContainsRune("-_/", r) {
+			return "#[[" + name + "]]"
+		}
+	}
+	return "#" + name
+}
+
+// writePage replaces web-log's block on the page, or appends the block when
+// the page has none yet.
+func writePage(path, block string) error {
+	existing, err := os.ReadFile(path)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(path, []byte(replaceBlock(string(existing), block)), 0o644)
+}
+
+// replaceBlock swaps the top-level block carrying the marker property, with
+// everything nested below it, for block.
+func replaceBlock(page, block string) string {
+	lines := strings.Split(strings.TrimRight(page, "\n"), "\n")
+	// Logseq starts a new page with a single empty block
+	if strings.TrimSpace(page) == "" || strings.TrimSpace(page) == "-" {
+		lines = nil
+	}
+	start, end := -1, len(lines)
+	for i, line := range lines {
+		if !strings.HasPrefix(line, "-") {
+			continue
+		}
+		if start >= 0 {
+			end = i
+			break
+		}
+		// Block properties follow the block's first line
+		for _, prop := range lines[i+1:] {
+			if !strings.HasPrefix(prop, "  ") || !strings.Contains(prop, ":: ") {
+				break
+			}
+			if strings.TrimSpace(prop) == marker {
+				start = i
+				break
+			}
+		}
+	}
+	if start < 0 {
+		return strings.Join(append(lines, block), "\n") + "\n"
+	}
+	result := append(append(append([]string{}, lines[:start]...), block), lines[end:]...)
+	return strings.Join(result, "\n") + "\n"
+}
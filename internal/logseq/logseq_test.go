@@ -0,0 +1,96 @@
+package logseq
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"web-log/internal/history"
+	"web-log/internal/report"
+	"web-log/internal/summary"
+)
+
+func TestReplaceBlock(t *testing.T) {
+	block := "- Browsing summary\n  " + marker + "\n\t- new"
+	tests := []struct {
+		name string
+		page string
+		want string
+	}{
+		{"new page", "", block + "\n"},
+		{"empty first block", "-\n", block + "\n"},
+		{"appended", "- standup\n\t- notes\n", "- standup\n\t- notes\n" + block + "\n"},
+		{
+			"replaced in place",
+			"- morning\n- Browsing summary\n  " + marker + "\n  entries:: 3\n\t- old\n\t\t- older\n- evening\n",
+			"- morning\n" + block + "\n- evening\n",
+		},
+		{"replaced at the end", "- morning\n- Browsing summary\n  " + marker + "\n\t- old\n", "- morning\n" + block + "\n"},
+		{"marker in a child block is not ours", "- morning\n\t- quoted\n\t  " + marker + "\n", "- morning\n\t- quoted\n\t  " + marker + "\n" + block + "\n"},
+	}
+	for _, tt := range tests {
+		if got := replaceBlock(tt.page, block); got != tt.want {
+			t.Errorf("%s: got %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestTagRef(t *testing.T) {
+	tests := map[string]string{
+		"ai-agents":          "#ai-agents",
+		"ai-agents/clawdbot": "#ai-agents/clawdbot",
+		"node.js":            "#[[node.js]]",
+		"machine learning":   "#[[machine learning]]",
+	}
+	for name, want := range tests {
+		if got := tagRef(name); got != want {
+			t.Errorf("tagRef(%q) = %q, want %q", name, got, want)
+		}
+	}
+}
+
+func TestWriteDropsSubtagsUnderSmallTags(t *testing.T) {
+	entries := []history.Entry{}
+	ids := []int{}
+	for i := 0; i < 14; i++ {
+		day := 12
+		if i >= 11 {
+			day = 13
+		}
+		entries = append(entries, history.Entry{URL: "https://example.com/", Source: "chrome", VisitTime: time.Date(2026, 10, day, 9, i, 0, 0, time.UTC)})
+		ids = append(ids, i+1)
+	}
+	s := &summary.Summary{
+		StartDate: "2026-10-12", EndDate: "2026-10-13", Days: 2, Entries: entries,
+		Sections: []summary.Section{{Name: "Development", Tags: []summary.Tag{
+			{Name: "ai-agents", Count: 14, Entries: ids, SubTags: []summary.Tag{
+				{Name: "clawdbot", Count: 6, Entries: []int{1, 2, 3, 12, 13, 14}},
+			}},
+		}}},
+	}
+	dir := t.TempDir()
+	if _, err := (Graph{Dir: dir}).Write(&report.Report{Summary: s, Generated: time.Date(2026, 10, 17, 9, 0, 0, 0, time.UTC)}); err != nil {
+		t.Fatal(err)
+	}
+
+	first, err := os.ReadFile(filepath.Join(dir, "journals", "2026_10_12.md"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(first), "\t\t\t- #ai-agents/clawdbot (3)") {
+		t.Errorf("first day page lacks the subtag:\n%s", first)
+	}
+	// ai-agents has 3 entries on the second day, too few for subtags
+	second, err := os.ReadFile(filepath.Join(dir, "journals", "2026_10_13.md"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(second), "\t\t- #ai-agents (3)") {
+		t.Errorf("second day page lacks the tag:\n%s", second)
+	}
+	if strings.Contains(string(second), "#ai-agents/") {
+		t.Errorf("second day page shows a subtag under a small tag:\n%s", second)
+	}
+}
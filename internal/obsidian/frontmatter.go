@@ -0,0 +1,159 @@
+package obsidian
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ownKey holds web-log's properties. Its tags list the tags web-log added to
+// the note, so a re-run can swap them without touching the user's own.
+const ownKey = "web-log"
+
+// properties are what web-log writes into a note's frontmatter.
+type properties struct {
+	date    string
+	tags    []string
+	entries int
+	sources map[string]int
+	// start and end are the period the summary covers.
+	start, end string
+	generated  string
+}
+
+type ownProperties struct {
+	Entries   int            `yaml:"entries"`
+	Sources   map[string]int `yaml:"sources"`
+	Tags      []string       `yaml:"tags"`
+	Start     string         `yaml:"start"`
+	End       string         `yaml:"end"`
+	Generated string         `yaml:"generated"`
+}
+
+// mergeFrontmatter writes p into the YAML front. Other keys keep their order,
+// values and comments; date is only set when the note has none.
+func mergeFrontmatter(front string, p properties) (string, error) {
+	var doc yaml.Node
+	if strings.TrimSpace(front) != "" {
+		if err := yaml.Unmarshal([]byte(front), &doc); err != nil {
+			return "", err
+		}
+	}
+	if doc.Kind == 0 {
+		doc = yaml.Node{Kind: yaml.DocumentNode, Content: []*yaml.Node{{Kind: yaml.MappingNode}}}
+	}
+	root := doc.Content[0]
+	if root.Kind != yaml.MappingNode {
+		return "", errors.New("not a set of properties")
+	}
+
+	var previous ownProperties
+	if own := value(root, ownKey); own != nil {
+		// If the property was edited into something else, it is simply replaced
+		_ = own.Decode(&previous)
+	}
+
+	if value(root, "date") == nil {
+		set(root, "date", &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!timestamp", Value: p.date})
+	}
+
+	drop := map[string]bool{}
+	for _, tag := range previous.Tags {
+		drop[tag] = true
+	}
+	tags := []string{}
+	seen := map[string]bool{}
+	for _, tag := range existingTags(value(root, "tags")) {
+		if !drop[tag] && !seen[tag] {
+			seen[tag] = true
+			tags = append(tags, tag)
+		}
+	}
+	added := []string{}
+	for _, tag := range p.tags {
+		if !seen[tag] {
+			seen[tag] = true
+			tags = append(tags, tag)
+			added = append(added, tag)
+		}
+	}
+	tagsNode := &yaml.Node{Kind: yaml.SequenceNode, Tag: "!!seq"}
+	for _, tag := range tags {
+		tagsNode.Content = append(tagsNode.Content, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: tag})
+	}
+	set(root, "tags", tagsNode)
+
+	own := &yaml.Node{}
+	if err := own.Encode(ownProperties{
+		Entries:   p.entries,
+		Sources:   p.sources,
+		Tags:      added,
+		Start:     p.start,
+		End:       p.end,
+		Generated: p.generated,
+	}); err != nil {
+		return "", err
+	}
+	set(root, ownKey, own)
+
+	var b bytes.Buffer
+	enc := yaml.NewEncoder(&b)
+	enc.SetIndent(2)
+	if err := enc.Encode(&doc); err != nil {
+		return "", err
+	}
+	if err := enc.Close(); err != nil {
+		return "", err
+	}
+	return b.String(), nil
+}
+
+// existingTags reads a tags property, which Obsidian also accepts as a
+// single string of comma- or space-separated tags.
+func existingTags(node *yaml.Node) []string {
+	if node == nil {
+		return nil
+	}
+	var values []string
+	switch node.Kind {
+	case yaml.SequenceNode:
+		for _, item := range node.Content {
+			values = append(values, item.Value)
+		}
+	case yaml.ScalarNode:
+		values = strings.FieldsFunc(node.Value, func(r rune) bool {
+			return r == ',' || r == ' '
+		})
+	}
+	tags := []string{}
+	for _, value := range values {
+		if tag := strings.TrimPrefix(strings.TrimSpace(value), "#"); tag != "" {
+			tags = append(tags, tag)
+		}
+	}
+	return tags
+}
+
+// value returns the value of key in a mapping node, or nil.
+func value(mapping *yaml.Node, key string) *yaml.Node {
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		if mapping.Content[i].Value == key {
+			return mapping.Content[i+1]
+		}
+	}
+	return nil
+}
+
+// set replaces the value of key in a mapping node, adding the key at the end
+// if it is missing.
+func set(mapping *yaml.Node, key string, v *yaml.Node) {
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		if mapping.Content[i].Value == key {
+			mapping.Content[i+1] = v
+			return
+		}
+	}
+	mapping.Content = append(mapping.Content, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: key}, v)
+}
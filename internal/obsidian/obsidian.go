@@ -0,0 +1,267 @@
+// Package obsidian writes summaries into an Obsidian vault, one note per day
+// or per period. Notes get YAML frontmatter with the date, tags and counts,
+// and the summary goes in a delimited block; re-running replaces the block
+// and web-log's own properties and leaves everything else in the note alone.
+package obsidian
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+	"unicode"
+
+	"web-log/internal/report"
+	"web-log/internal/summary"
+)
+
+// The summary block is wrapped in Obsidian comments, which are hidden in
+// reading view.
+const (
+	blockStart = "%% web-log:start %%"
+	blockEnd   = "%% web-log:end %%"
+)
+
+const (
+	// DefaultDayNote matches the file names of Obsidian's daily notes.
+	DefaultDayNote = "{date}"
+	// DefaultPeriodNote names notes that cover a whole period.
+	DefaultPeriodNote = "Browsing {start} to {end}"
+)
+
+// Vault says where and how notes are written.
+type Vault struct {
+	Dir string
+	// Folder is a directory inside the vault, or "" for its root.
+	Folder string
+	// Note names the note without ".md"; {date}, {start} and {end} are
+	// replaced with dates. Empty picks DefaultDayNote or DefaultPeriodNote.
+	Note string
+	// PerDay writes a note for each day with entries instead of one note
+	// for the period.
+	PerDay bool
+}
+
+// note is one note's worth of the report.
+type note struct {
+	summary *summary.Summary
+	// date is the day the note is filed under; the period's end date for
+	// period notes.
+	date string
+	// entries counts the entries visited in the note's days, tagged or not.
+	entries int
+	sources map[string]int
+}
+
+// Write writes the report's notes and returns their paths.
+func (v Vault) Write(r *report.Report) ([]string, error) {
+	if v.Dir == "" {
+		return nil, errors.New("no vault directory set")
+	}
+	if info, err := os.Stat(v.Dir); err != nil {
+		return nil, fmt.Errorf("vault: %w", err)
+	} else if !info.IsDir() {
+		return nil, fmt.Errorf("vault %s is not a directory", v.Dir)
+	}
+
+	s := r.Summary
+	notes := []note{}
+	if v.PerDay {
+		for _, date := range s.Dates() {
+			n := note{summary: s.ForDay(date), date: date, sources: map[string]int{}}
+			for _, entry := range s.Entries {
+				if entry.VisitTime.Format("2006-01-02") == date {
+					n.entries++
+					n.sources[entry.Source]++
+				}
+			}
+			notes = append(notes, n)
+		}
+	} else {
+		n := note{summary: s, date: s.EndDate, entries: len(s.Entries), sources: map[string]int{}}
+		for _, source := range r.Sources() {
+			if source.Included > 0 {
+				n.sources[source.Name] = source.Included
+			}
+		}
+		notes = append(notes, n)
+	}
+
+	paths := []string{}
+	for _, n := range notes {
+		path := v.path(n)
+		if err := writeNote(path, n, r.Generated.Format(time.RFC3339)); err != nil {
+			return paths, fmt.Errorf("%s: %w", path, err)
+		}
+		paths = append(paths, path)
+	}
+	return paths, nil
+}
+
+func (v Vault) path(n note) string {
+	name := v.Note
+	if name == "" {
+		name = DefaultPeriodNote
+		if v.PerDay {
+			name = DefaultDayNote
+		}
+	}
+	name = strings.NewReplacer(
+		"{date}", n.date,
+		"{start}", n.summary.StartDate,
+		"{end}", n.summary.EndDate,
+	).Replace(name)
+	return filepath.Join(v.Dir, v.Folder, name+".md")
+}
+
+// writeNote creates the note, or updates the frontmatter and summary block
+// of an existing one.
+func writeNote(path string, n note, generated string) error {
+	existing, err := os.ReadFile(path)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	front, body := splitFrontmatter(string(existing))
+
+	tags := noteTags(n.summary)
+	props := properties{
+		date:      n.date,
+		tags:      tags,
+		entries:   n.entries,
+		sources:   n.sources,
+		start:     n.summary.StartDate,
+		end:       n.summary.EndDate,
+		generated: generated,
+	}
+	front, err = mergeFrontmatter(front, props)
+	if err != nil {
+		return fmt.Errorf("frontmatter: %w", err)
+	}
+	body = replaceBlock(body, blockStart+"\n"+renderBlock(n.summary)+"\n"+blockEnd)
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(path, []byte("---\n"+front+"---\n"+body), 0o644)
+}
+
+// splitFrontmatter separates the YAML between the leading "---" lines from
+// the rest of the note. A note without frontmatter has an empty front.
+func splitFrontmatter(text string) (front, body string) {
+	text = strings.TrimPrefix(text, "\ufeff")
+	if !strings.HasPrefix(text, "---\n") && !strings.HasPrefix(text, "---\r\n") {
+		return "", text
+	}
+	rest := text[strings.Index(text, "\n")+1:]
+	for offset := 0; offset < len(rest); {
+		end := strings.IndexByte(rest[offset:], '\n')
+		line := rest[offset:]
+		if end >= 0 {
+			line = rest[offset : offset+end+1]
+		}
+		if strings.TrimRight(line, "\r\n") == "---" {
+			return rest[:offset], rest[offset+len(line):]
+		}
+		offset += len(line)
+	}
+	// An unterminated block is not frontmatter
+	return "", text
+}
+
+// replaceBlock swaps the web-log block in body for block, or appends block
+// when the note has none yet.
+func replaceBlock(body, block string) string {
+	start := strings.Index(body, blockStart)
+	if start >= 0 {
+		if end := strings.Index(body[start:], blockEnd); end >= 0 {
+			return body[:start] + block + body[start+end+len(blockEnd):]
+		}
+	}
+	trimmed := strings.TrimRight(body, "\n")
+	if trimmed == "" {
+		return block + "\n"
+	}
+	return trimmed + "\n\n" + block + "\n"
+}
+
+// renderBlock is the summary in Markdown with Obsidian tags: subtags are
+// nested under their parent, as in #ai-agents/clawdbot.
+func renderBlock(s *summary.Summary) string {
+	var b strings.Builder
+	if s.Days == 1 {
+		b.WriteString("## Browsing\n")
+	} else {
+		fmt.Fprintf(&b, "## Browsing %s to %s (%d days)\n", s.StartDate, s.EndDate, s.Days)
+	}
+	for _, section := range s.Sections {
+		fmt.Fprintf(&b, "\n**%s**\n", section.Name)
+		for _, tag := range section.Tags {
+			name := tagName(tag.Name)
+			b.WriteString("- " + tagLine(name, tag) + "\n")
+			for _, sub := range tag.SubTags {
+				b.WriteString("  - " + tagLine(name+"/"+tagName(sub.Name), sub) + "\n")
+			}
+		}
+	}
+	return strings.TrimRight(b.String(), "\n")
+}
+
+func tagLine(name string, tag summary.Tag) string {
+	line := fmt.Sprintf("#%s (%d)", name, tag.Count)
+	if tag.Description != "" {
+		line += " " + tag.Description
+	}
+	if len(tag.Sites) > 0 {
+		line += " [" + strings.Join(tag.Sites, ", ") + "]"
+	}
+	return line
+}
+
+// noteTags lists the note's tags for the frontmatter, parents before their
+// subtags.
+func noteTags(s *summary.Summary) []string {
+	seen := map[string]bool{}
+	tags := []string{}
+	add := func(tag string) {
+		if !seen[tag] {
+			seen[tag] = true
+			tags = append(tags, tag)
+		}
+	}
+	for _, section := range s.Sections {
+		for _, tag := range section.Tags {
+			name := tagName(tag.Name)
+			add(name)
+			for _, sub := range tag.SubTags {
+				add(name + "/" + tagName(sub.Name))
+			}
+		}
+	}
+	return tags
+}
+
+// tagName makes name a valid Obsidian tag: letters, digits, "_", "-" and
+// "/" only, and not all digits, so "node.js" becomes "node-js" and "2026"
+// becomes "_2026".
+func tagName(name string) string {
+	var b strings.Builder
+	dash := false
+	for _, r := range strings.ToLower(name) {
+		switch {
+		case r == '_' || r == '-' || r == '/' || unicode.IsLetter(r) || unicode.IsDigit(r):
+			b.WriteRune(r)
+			dash = false
+		case !dash:
+			b.WriteByte('-')
+			dash = true
+		}
+	}
+	tag := strings.Trim(b.String(), "-/")
+	if strings.Trim(tag, "0123456789") == "" {
+		tag = "_" + tag
+	}
+	return tag
+}
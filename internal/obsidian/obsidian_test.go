@@ -0,0 +1,129 @@
+package obsidian
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"web-log/internal/history"
+	"web-log/internal/report"
+	"web-log/internal/summary"
+)
+
+func TestSplitFrontmatter(t *testing.T) {
+	tests := []struct {
+		name        string
+		text        string
+		front, body string
+	}{
+		{"none", "# Notes\n", "", "# Notes\n"},
+		{"empty note", "", "", ""},
+		{"frontmatter", "---\ndate: 2026-10-17\n---\n# Notes\n", "date: 2026-10-17\n", "# Notes\n"},
+		{"windows line endings", "---\r\ndate: 2026-10-17\r\n---\r\nbody", "date: 2026-10-17\r\n", "body"},
+		{"byte order mark", "\ufeff---\ntags: [a]\n---\n", "tags: [a]\n", ""},
+		{"empty frontmatter", "---\n---\nbody", "", "body"},
+		{"unterminated", "---\ndate: 2026-10-17\n# Notes\n", "", "---\ndate: 2026-10-17\n# Notes\n"},
+		{"rule later in the note", "# Notes\n---\nmore\n", "", "# Notes\n---\nmore\n"},
+	}
+	for _, tt := range tests {
+		front, body := splitFrontmatter(tt.text)
+		if front != tt.front || body != tt.body {
+			t.Errorf("%s: got %q, %q; want %q, %q", tt.name, front, body, tt.front, tt.body)
+		}
+	}
+}
+
+func TestReplaceBlock(t *testing.T) {
+	block := blockStart + "\nnew\n" + blockEnd
+	tests := []struct {
+		name string
+		body string
+		want string
+	}{
+		{"empty note", "", block + "\n"},
+		{"appended", "# Day\n\nMeetings\n\n", "# Day\n\nMeetings\n\n" + block + "\n"},
+		{"replaced in place", "# Day\n\n" + blockStart + "\nold\n" + blockEnd + "\n\n## Later\n", "# Day\n\n" + block + "\n\n## Later\n"},
+		{"unterminated block is left alone", "# Day\n" + blockStart + "\nold\n", "# Day\n" + blockStart + "\nold\n\n" + block + "\n"},
+	}
+	for _, tt := range tests {
+		if got := replaceBlock(tt.body, block); got != tt.want {
+			t.Errorf("%s: got %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestTagName(t *testing.T) {
+	tests := map[string]string{
+		"ai-agents": "ai-agents",
+		"node.js":   "node-js",
+		"C++ tips":  "c-tips",
+		"2026":      "_2026",
+		"café":      "café",
+	}
+	for name, want := range tests {
+		if got := tagName(name); got != want {
+			t.Errorf("tagName(%q) = %q, want %q", name, got, want)
+		}
+	}
+}
+
+func TestWritePerDayKeepsNotesAndSubtagRules(t *testing.T) {
+	entries := []history.Entry{}
+	ids := []int{}
+	for i := 0; i < 14; i++ {
+		day := 12
+		if i >= 11 {
+			day = 13
+		}
+		entries = append(entries, history.Entry{URL: "https://example.com/", Source: "chrome", VisitTime: time.Date(2026, 10, day, 9, i, 0, 0, time.UTC)})
+		ids = append(ids, i+1)
+	}
+	s := &summary.Summary{
+		StartDate: "2026-10-12", EndDate: "2026-10-13", Days: 2, Entries: entries,
+		Sections: []summary.Section{{Name: "Development", Tags: []summary.Tag{
+			{Name: "ai-agents", Count: 14, Entries: ids, SubTags: []summary.Tag{
+				{Name: "clawdbot", Count: 6, Entries: []int{1, 2, 3, 12, 13, 14}},
+			}},
+		}}},
+	}
+	dir := t.TempDir()
+	existing := filepath.Join(dir, "2026-10-13.md")
+	if err := os.WriteFile(existing, []byte("---\ntags: [journal]\n---\n# Tuesday\n\nMy own notes.\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	vault := Vault{Dir: dir, PerDay: true}
+	paths, err := vault.Write(&report.Report{Summary: s, Generated: time.Date(2026, 10, 17, 9, 0, 0, 0, time.UTC)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(paths) != 2 {
+		t.Fatalf("wrote %v, want a note per day", paths)
+	}
+
+	first, _ := os.ReadFile(filepath.Join(dir, "2026-10-12.md"))
+	if !strings.Contains(string(first), "  - #ai-agents/clawdbot (3)") {
+		t.Errorf("first day note lacks the subtag:\n%s", first)
+	}
+	// ai-agents has 3 entries on the second day, too few for subtags
+	second, _ := os.ReadFile(existing)
+	if strings.Contains(string(second), "clawdbot") {
+		t.Errorf("second day note shows a subtag under a small tag:\n%s", second)
+	}
+	for _, want := range []string{"- journal", "- ai-agents", "# Tuesday\n\nMy own notes.\n", "#ai-agents (3)"} {
+		if !strings.Contains(string(second), want) {
+			t.Errorf("second day note lacks %q:\n%s", want, second)
+		}
+	}
+
+	// Running again changes nothing but the generated time
+	if _, err := vault.Write(&report.Report{Summary: s, Generated: time.Date(2026, 10, 17, 9, 0, 0, 0, time.UTC)}); err != nil {
+		t.Fatal(err)
+	}
+	again, _ := os.ReadFile(existing)
+	if string(again) != string(second) {
+		t.Errorf("second run changed the note:\n%s\nwant:\n%s", again, second)
+	}
+}
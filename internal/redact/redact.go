@@ -0,0 +1,324 @@
+// Package redact masks secrets and personal data in history entries before
+// they are put into a prompt.
+package redact
+
+import (
+	"fmt"
+	"math"
+	"net"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+
+	"web-log/internal/history"
+)
+
+// sensitiveParams are query parameters whose values are credentials or
+// one-time codes. Parameters containing one of sensitiveParamParts count
+// too, e.g. "x-amz-signature" or "reset_token".
+var sensitiveParams = map[string]bool{
+	"code": true, "key": true, "sig": true, "auth": true, "state": true, "nonce": true,
+	"otp": true, "pin": true, "pass": true, "pwd": true, "sid": true, "jwt": true,
+	"apikey": true, "api_key": true, "authorization": true, "ticket": true, "hash": true,
+	"credential": true, "x-amz-credential": true, "assertion": true, "samlresponse": true,
+}
+
+var sensitiveParamParts = []string{"token", "secret", "password", "passwd", "signature", "session", "auth_code"}
+
+// textRule masks a pattern wherever it appears in a URL or title.
+type textRule struct {
+	name        string
+	re          *regexp.Regexp
+	replacement string
+	// check, when set, must accept a match for it to be masked.
+	check func(string) bool
+	// valuesOnly limits the rule to the values of query and fragment
+	// parameters, where identifiers that are public in a path, such as
+	// commit hashes, are usually credentials.
+	valuesOnly bool
+}
+
+// builtinRules run in order; JWTs go first since they also look like long
+// tokens.
+var builtinRules = []textRule{
+	{
+		name:        "jwt",
+		re:          regexp.MustCompile(`eyJ[A-Za-z0-9_-]{5,}\.eyJ[A-Za-z0-9_-]{5,}\.[A-Za-z0-9_-]*`),
+		replacement: "[jwt]",
+	},
+	{
+		name:        "email",
+		re:          regexp.MustCompile(`(?i)[a-z0-9._%+-]+(?:@|%40)[a-z0-9.-]+\.[a-z]{2,}`),
+		replacement: "[email]",
+	},
+	{
+		name:        "hex",
+		re:          regexp.MustCompile(`\b[0-9a-fA-F]{24,}\b`),
+		replacement: "[hex]",
+		check:       hasDigitAndLetter,
+		valuesOnly:  true,
+	},
+	{
+		// Random-looking tokens such as reset-password path segments
+		name:        "token",
+		re:          regexp.MustCompile(`\b[A-Za-z0-9_]{32,}\b`),
+		replacement: "[token]",
+		check:       looksRandom,
+	},
+	{
+		// Grouped digits only, so dates and numeric ids are left alone
+		name:        "phone",
+		re:          regexp.MustCompile(`(?:\+\d{1,3}[\s.-]?)?\(?\b\d{2,4}\)?[\s.-]\d{3,4}[\s.-]\d{3,4}\b`),
+		replacement: "[phone]",
+	},
+}
+
+// internalSuffixes mark hostnames that only resolve inside a network.
+var internalSuffixes = []string{".local", ".internal", ".corp", ".lan", ".intranet", ".home.arpa", ".localdomain"}
+
+// Stats counts the redactions made per rule.
+type Stats map[string]int
+
+// Total is the number of redactions made.
+func (s Stats) Total() int {
+	total := 0
+	for _, n := range s {
+		total += n
+	}
+	return total
+}
+
+// String reports the total and the per-rule counts, largest first, e.g.
+// "12 (query-param 8, email 3, jwt 1)".
+func (s Stats) String() string {
+	rules := make([]string, 0, len(s))
+	for rule := range s {
+		rules = append(rules, rule)
+	}
+	sort.Slice(rules, func(i, j int) bool {
+		if s[rules[i]] != s[rules[j]] {
+			return s[rules[i]] > s[rules[j]]
+		}
+		return rules[i] < rules[j]
+	})
+	parts := make([]string, len(rules))
+	for i, rule := range rules {
+		parts[i] = fmt.Sprintf("%s %d", rule, s[rule])
+	}
+	if len(parts) == 0 {
+		return "0"
+	}
+	return fmt.Sprintf("%d (%s)", s.Total(), strings.Join(parts, ", "))
+}
+
+type Redactor struct {
+	rules []textRule
+}
+
+// New returns a Redactor with the built-in rules plus patterns, regular
+// expressions whose matches are replaced with "[redacted]".
+func New(patterns []string) (*Redactor, error) {
+	rules := append([]textRule{}, builtinRules...)
+	for _, pattern := range patterns {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("redaction pattern %q: %w", pattern, err)
+		}
+		rules = append(rules, textRule{name: "custom", re: re, replacement: "[redacted]"})
+	}
+	return &Redactor{rules: rules}, nil
+}
+
+// Entries returns copies of entries with their URLs, titles, referrers and
+// search terms redacted, and the redactions made.
+func (r *Redactor) Entries(entries []history.Entry) ([]history.Entry, Stats) {
+	stats := Stats{}
+	result := make([]history.Entry, len(entries))
+	for i, entry := range entries {
+		entry.URL = r.URL(entry.URL, stats)
+		entry.Referrer = r.URL(entry.Referrer, stats)
+		entry.Title = r.Text(entry.Title, stats)
+		entry.SearchTerm = r.Text(entry.SearchTerm, stats)
+		result[i] = entry
+	}
+	return result, stats
+}
+
+// URL strips credentials and sensitive query parameters from raw, hides
+// internal hostnames and then applies the text rules.
+func (r *Redactor) URL(raw string, stats Stats) string {
+	if raw == "" {
+		return raw
+	}
+	parsed, err := url.Parse(raw)
+	if err != nil || parsed.Host == "" {
+		return r.Text(raw, stats)
+	}
+	if parsed.User != nil {
+		parsed.User = nil
+		stats["credentials"]++
+	}
+	if isInternalHost(parsed.Hostname()) {
+		parsed.Host = "internal.host"
+		stats["internal-host"]++
+	}
+	parsed.RawQuery = r.stripParams(parsed.RawQuery, stats)
+	// OAuth implicit flows put tokens in the fragment. It is put back as is,
+	// like the query, so masks are not percent-encoded.
+	fragment := ""
+	if strings.Contains(parsed.Fragment, "=") {
+		if stripped := r.stripParams(parsed.EscapedFragment(), stats); stripped != "" {
+			fragment = "#" + stripped
+		}
+		parsed.Fragment, parsed.RawFragment = "", ""
+	}
+	return r.Text(parsed.String()+fragment, stats)
+}
+
+// Text applies the pattern rules to s, except those for parameter values.
+func (r *Redactor) Text(s string, stats Stats) string {
+	return r.apply(s, false, stats)
+}
+
+func (r *Redactor) apply(s string, value bool, stats Stats) string {
+	if s == "" {
+		return s
+	}
+	for _, rule := range r.rules {
+		if rule.valuesOnly != value {
+			continue
+		}
+		s = rule.re.ReplaceAllStringFunc(s, func(match string) string {
+			if rule.check != nil && !rule.check(match) {
+				return match
+			}
+			stats[rule.name]++
+			return rule.replacement
+		})
+	}
+	return s
+}
+
+// stripParams drops sensitive parameters from a query string and masks the
+// values of the rest with the rules for parameter values, keeping their
+// order and encoding.
+func (r *Redactor) stripParams(query string, stats Stats) string {
+	if query == "" {
+		return query
+	}
+	kept := []string{}
+	for _, pair := range strings.Split(query, "&") {
+		name, value, hasValue := strings.Cut(pair, "=")
+		if decoded, err := url.QueryUnescape(name); err == nil {
+			name = decoded
+		}
+		if isSensitiveParam(name) {
+			stats["query-param"]++
+			continue
+		}
+		if hasValue {
+			pair = pair[:len(pair)-len(value)] + r.apply(value, true, stats)
+		}
+		kept = append(kept, pair)
+	}
+	return strings.Join(kept, "&")
+}
+
+func isSensitiveParam(name string) bool {
+	name = strings.ToLower(name)
+	if sensitiveParams[name] {
+		return true
+	}
+	for _, part := range sensitiveParamParts {
+		if strings.Contains(name, part) {
+			return true
+		}
+	}
+	return false
+}
+
+// isInternalHost recognizes private and link-local addresses, single-label
+// hostnames and the usual internal domain suffixes. localhost is kept, since
+// it reveals nothing.
+func isInternalHost(host string) bool {
+	host = strings.ToLower(strings.TrimSuffix(host, "."))
+	if host == "" || host == "localhost" {
+		return false
+	}
+	if ip := net.ParseIP(host); ip != nil {
+		return ip.IsPrivate() || ip.IsLinkLocalUnicast()
+	}
+	if !strings.Contains(host, ".") {
+		return true
+	}
+	for _, suffix := range internalSuffixes {
+		if strings.HasSuffix(host, suffix) {
+			return true
+		}
+	}
+	return false
+}
+
+func hasDigitAndLetter(s string) bool {
+	digit, letter := false, false
+	for _, r := range s {
+		switch {
+		case r >= '0' && r <= '9':
+			digit = true
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z':
+			letter = true
+		}
+	}
+	return digit && letter
+}
+
+// looksRandom accepts tokens that mix letters and digits, are not all hex
+// (hashes in paths are usually public, see the hex rule), are not words
+// joined by underscores such as wiki slugs, and have the character variety
+// of generated text rather than a repeated pattern.
+func looksRandom(s string) bool {
+	if !hasDigitAndLetter(s) || isHex(s) || isJoinedWords(s) {
+		return false
+	}
+	return entropy(s) >= 3
+}
+
+func isHex(s string) bool {
+	for _, r := range s {
+		if !strings.ContainsRune("0123456789abcdefABCDEF", r) {
+			return false
+		}
+	}
+	return true
+}
+
+// isJoinedWords recognizes "2024_United_States_presidential_election": every
+// part between underscores is letters, digits, or letters then digits.
+func isJoinedWords(s string) bool {
+	parts := strings.Split(s, "_")
+	if len(parts) < 2 {
+		return false
+	}
+	for _, part := range parts {
+		digits := strings.TrimLeft(part, "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ")
+		if strings.Trim(digits, "0123456789") != "" {
+			return false
+		}
+	}
+	return true
+}
+
+// entropy is the Shannon entropy of s in bits per character.
+func entropy(s string) float64 {
+	counts := map[rune]int{}
+	for _, r := range s {
+		counts[r]++
+	}
+	bits := 0.0
+	for _, n := range counts {
+		p := float64(n) / float64(len(s))
+		bits -= p * math.Log2(p)
+	}
+	return bits
+}
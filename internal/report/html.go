@@ -0,0 +1,339 @@
+package report
+
+import (
+	"fmt"
+	"html"
+	"html/template"
+	"sort"
+	"strings"
+	"time"
+
+	"web-log/internal/summary"
+)
+
+type htmlData struct {
+	Summary     *summary.Summary
+	Timezone    string
+	Generated   string
+	Sources     []SourceCount
+	SourceChart template.HTML
+	HourChart   template.HTML
+	Days        []htmlDay
+	Sections    []htmlSection
+}
+
+type htmlSection struct {
+	Name  string
+	Count int
+	Tags  []htmlTag
+}
+
+type htmlTag struct {
+	Name        string
+	Count       int
+	Description string
+	Sites       []string
+	Entries     []htmlEntry
+	SubTags     []htmlTag
+}
+
+type htmlEntry struct {
+	at      time.Time
+	Date    string
+	Time    string
+	Title   string
+	URL     string
+	Source  string
+	Profile string
+}
+
+// htmlDay is one row of the timeline.
+type htmlDay struct {
+	Date    string
+	Weekday string
+	Count   int
+	Hours   template.HTML
+	Tags    []dayTag
+}
+
+type dayTag struct {
+	Name  string
+	Count int
+}
+
+// HTML renders the report as a single self-contained page: styles and
+// charts are inline and nothing is loaded from elsewhere. Every tag expands
+// to the pages behind it.
+func (r *Report) HTML() (string, error) {
+	s := r.Summary
+	data := htmlData{
+		Summary:   s,
+		Timezone:  r.Timezone,
+		Generated: r.Generated.Format("2006-01-02 15:04"),
+		Sources:   r.Sources(),
+	}
+	data.SourceChart = sourceChart(data.Sources)
+
+	var hours [24]int
+	byDay := map[string]*[24]int{}
+	for _, entry := range s.Entries {
+		hours[entry.VisitTime.Hour()]++
+		day := entry.VisitTime.Format("2006-01-02")
+		if byDay[day] == nil {
+			byDay[day] = &[24]int{}
+		}
+		byDay[day][entry.VisitTime.Hour()]++
+	}
+	data.HourChart = hourChart(hours)
+	data.Days = r.timeline(byDay)
+
+	for _, section := range s.Sections {
+		hs := htmlSection{Name: section.Name, Count: section.Count()}
+		for _, tag := range section.Tags {
+			hs.Tags = append(hs.Tags, r.htmlTag(tag))
+		}
+		data.Sections = append(data.Sections, hs)
+	}
+
+	var b strings.Builder
+	if err := htmlTemplate.Execute(&b, data); err != nil {
+		return "", err
+	}
+	return b.String(), nil
+}
+
+// htmlTag lists the pages behind a tag. Pages a subtag lists are left to
+// the subtag, so none is shown twice.
+func (r *Report) htmlTag(tag summary.Tag) htmlTag {
+	ht := htmlTag{Name: tag.Name, Count: tag.Count, Description: tag.Description, Sites: tag.Sites}
+	inSubTag := map[int]bool{}
+	for _, sub := range tag.SubTags {
+		for _, id := range sub.Entries {
+			inSubTag[id] = true
+		}
+	}
+	for _, id := range tag.Entries {
+		entry, ok := r.Summary.Entry(id)
+		if !ok || inSubTag[id] {
+			continue
+		}
+		title := strings.TrimSpace(entry.Title)
+		if title == "" {
+			title = entry.URL
+		}
+		ht.Entries = append(ht.Entries, htmlEntry{
+			at:      entry.VisitTime,
+			Date:    entry.VisitTime.Format("2006-01-02"),
+			Time:    entry.VisitTime.Format("15:04"),
+			Title:   title,
+			URL:     entry.URL,
+			Source:  entry.Source,
+			Profile: entry.Profile,
+		})
+	}
+	sort.SliceStable(ht.Entries, func(i, j int) bool {
+		return ht.Entries[i].at.Before(ht.Entries[j].at)
+	})
+	for _, sub := range tag.SubTags {
+		ht.SubTags = append(ht.SubTags, r.htmlTag(sub))
+	}
+	return ht
+}
+
+// timeline lists every day with visits, its activity by hour and the tags
+// its entries went into, largest first.
+func (r *Report) timeline(byDay map[string]*[24]int) []htmlDay {
+	tagsByDay := map[string]map[string]int{}
+	for _, section := range r.Summary.Sections {
+		for _, tag := range section.Tags {
+			for _, id := range tag.Entries {
+				entry, ok := r.Summary.Entry(id)
+				if !ok {
+					continue
+				}
+				day := entry.VisitTime.Format("2006-01-02")
+				if tagsByDay[day] == nil {
+					tagsByDay[day] = map[string]int{}
+				}
+				tagsByDay[day][tag.Name]++
+			}
+		}
+	}
+
+	busiest := 0
+	for _, hours := range byDay {
+		for _, n := range hours {
+			busiest = max(busiest, n)
+		}
+	}
+	dates := make([]string, 0, len(byDay))
+	for date := range byDay {
+		dates = append(dates, date)
+	}
+	sort.Strings(dates)
+
+	days := make([]htmlDay, 0, len(dates))
+	for _, date := range dates {
+		day := htmlDay{Date: date, Hours: dayStrip(byDay[date], busiest)}
+		if t, err := time.Parse("2006-01-02", date); err == nil {
+			day.Weekday = t.Format("Mon")
+		}
+		for _, n := range byDay[date] {
+			day.Count += n
+		}
+		for name, n := range tagsByDay[date] {
+			day.Tags = append(day.Tags, dayTag{Name: name, Count: n})
+		}
+		sort.Slice(day.Tags, func(i, j int) bool {
+			if day.Tags[i].Count != day.Tags[j].Count {
+				return day.Tags[i].Count > day.Tags[j].Count
+			}
+			return day.Tags[i].Name < day.Tags[j].Name
+		})
+		days = append(days, day)
+	}
+	return days
+}
+
+// sourceChart draws one horizontal bar per source: entries read, with the
+// part left after filtering filled in.
+func sourceChart(sources []SourceCount) template.HTML {
+	const width, label, row = 520, 90, 24
+	most := 1
+	for _, source := range sources {
+		most = max(most, source.Read, source.Included)
+	}
+	scale := float64(width-label-60) / float64(most)
+	var b strings.Builder
+	fmt.Fprintf(&b, `<svg class="chart" viewBox="0 0 %d %d" role="img" aria-label="Entries per source">`, width, row*len(sources)+4)
+	for i, source := range sources {
+		y := i*row + 2
+		fmt.Fprintf(&b, `<text x="%d" y="%d" class="label" text-anchor="end">%s</text>`, label-8, y+15, html.EscapeString(source.Name))
+		fmt.Fprintf(&b, `<rect x="%d" y="%d" width="%.1f" height="18" class="bar-read"><title>%d read</title></rect>`, label, y+2, float64(source.Read)*scale, source.Read)
+		fmt.Fprintf(&b, `<rect x="%d" y="%d" width="%.1f" height="18" class="bar"><title>%d included</title></rect>`, label, y+2, float64(source.Included)*scale, source.Included)
+		fmt.Fprintf(&b, `<text x="%.1f" y="%d" class="value">%d / %d</text>`, float64(label)+float64(max(source.Read, source.Included))*scale+6, y+15, source.Included, source.Read)
+	}
+	b.WriteString(`</svg>`)
+	return template.HTML(b.String())
+}
+
+// hourChart draws the entries per hour of the day as columns.
+func hourChart(hours [24]int) template.HTML {
+	const width, height, bottom, column = 520, 150, 20, 20
+	most := 1
+	for _, n := range hours {
+		most = max(most, n)
+	}
+	left := (width - 24*column) / 2
+	scale := float64(height-bottom-14) / float64(most)
+	var b strings.Builder
+	fmt.Fprintf(&b, `<svg class="chart" viewBox="0 0 %d %d" role="img" aria-label="Entries per hour of the day">`, width, height)
+	for hour, n := range hours {
+		x := left + hour*column
+		h := float64(n) * scale
+		fmt.Fprintf(&b, `<rect x="%d" y="%.1f" width="%d" height="%.1f" class="bar"><title>%02d:00 – %d entries</title></rect>`, x+2, float64(height-bottom)-h, column-4, h, hour, n)
+		if hour%3 == 0 {
+			fmt.Fprintf(&b, `<text x="%d" y="%d" class="label" text-anchor="middle">%02d</text>`, x+column/2, height-5, hour)
+		}
+	}
+	fmt.Fprintf(&b, `<line x1="%d" y1="%d" x2="%d" y2="%d" class="axis"/>`, left, height-bottom, left+24*column, height-bottom)
+	b.WriteString(`</svg>`)
+	return template.HTML(b.String())
+}
+
+// dayStrip shades the 24 hours of a day by how busy they were, relative to
+// the busiest hour of the period.
+func dayStrip(hours *[24]int, busiest int) template.HTML {
+	const cell = 12
+	var b strings.Builder
+	fmt.Fprintf(&b, `<svg class="strip" viewBox="0 0 %d 14" role="img" aria-label="Entries by hour">`, 24*cell)
+	for hour, n := range hours {
+		opacity := 0.08
+		if n > 0 && busiest > 0 {
+			opacity = 0.25 + 0.75*float64(n)/float64(busiest)
+		}
+		fmt.Fprintf(&b, `<rect x="%d" y="0" width="%d" height="14" class="cell" fill-opacity="%.2f"><title>%02d:00 – %d</title></rect>`, hour*cell, cell-1, opacity, hour, n)
+	}
+	b.WriteString(`</svg>`)
+	return template.HTML(b.String())
+}
+
+var htmlTemplate = template.Must(template.New("report").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>Browsing Summary {{.Summary.StartDate}} to {{.Summary.EndDate}}</title>
+<style>
+:root { --fg: #1d1d1f; --muted: #6e6e73; --bg: #fff; --panel: #f5f5f7; --accent: #0a66c2; --faint: #d2d2d7; }
+@media (prefers-color-scheme: dark) {
+  :root { --fg: #f5f5f7; --muted: #a1a1a6; --bg: #1c1c1e; --panel: #2c2c2e; --accent: #4ea1f3; --faint: #48484a; }
+}
+body { font: 15px/1.5 -apple-system, BlinkMacSystemFont, "Segoe UI", Roboto, sans-serif; color: var(--fg); background: var(--bg); max-width: 960px; margin: 2rem auto; padding: 0 1rem; }
+h1 { font-size: 1.6rem; margin-bottom: 0.2rem; }
+h2 { font-size: 1.2rem; margin-top: 2rem; border-bottom: 1px solid var(--faint); padding-bottom: 0.3rem; }
+.meta, .count, .sites, .when, .source { color: var(--muted); }
+.meta { margin-top: 0; }
+.charts { display: grid; grid-template-columns: repeat(auto-fit, minmax(300px, 1fr)); gap: 1rem; }
+.panel { background: var(--panel); border-radius: 8px; padding: 0.8rem 1rem; }
+.panel h3 { margin: 0 0 0.5rem; font-size: 0.95rem; }
+svg.chart { width: 100%; height: auto; }
+svg .bar, svg .cell { fill: var(--accent); }
+svg .bar-read { fill: var(--faint); }
+svg .label, svg .value { fill: var(--muted); font-size: 11px; }
+svg .axis { stroke: var(--faint); }
+table.timeline { border-collapse: collapse; width: 100%; }
+.timeline td { padding: 0.25rem 0.5rem 0.25rem 0; vertical-align: middle; }
+.timeline td.date { white-space: nowrap; }
+svg.strip { width: 288px; height: 14px; display: block; }
+.tag { display: inline-block; margin-right: 0.5rem; }
+details { margin: 0.2rem 0; }
+details details { margin-left: 1.4rem; }
+summary { cursor: pointer; }
+summary .name { font-weight: 600; }
+ul.entries { list-style: none; padding-left: 1.4rem; margin: 0.3rem 0 0.6rem; }
+ul.entries li { overflow: hidden; text-overflow: ellipsis; white-space: nowrap; }
+a { color: var(--accent); text-decoration: none; }
+a:hover { text-decoration: underline; }
+footer { margin: 3rem 0 1rem; color: var(--muted); font-size: 0.85rem; }
+</style>
+</head>
+<body>
+<h1>Browsing Summary</h1>
+<p class="meta">{{.Summary.StartDate}} to {{.Summary.EndDate}} ({{.Summary.Days}} days) · {{len .Summary.Entries}} entries · {{.Timezone}}</p>
+
+<div class="charts">
+<div class="panel"><h3>Entries per source (included / read)</h3>{{.SourceChart}}</div>
+<div class="panel"><h3>Entries per hour</h3>{{.HourChart}}</div>
+</div>
+
+<h2>Timeline</h2>
+<table class="timeline">
+{{- range .Days}}
+<tr><td class="date">{{.Weekday}} {{.Date}}</td><td class="count">{{.Count}}</td><td>{{.Hours}}</td><td>{{range .Tags}}<span class="tag">#{{.Name}} <span class="count">{{.Count}}</span></span>{{end}}</td></tr>
+{{- end}}
+</table>
+
+{{- define "tag"}}
+<details>
+<summary><span class="name">#{{.Name}}</span> <span class="count">({{.Count}})</span> {{.Description}}{{if .Sites}} <span class="sites">[{{range $i, $site := .Sites}}{{if $i}}, {{end}}{{$site}}{{end}}]</span>{{end}}</summary>
+{{- range .SubTags}}{{template "tag" .}}{{end}}
+{{- if .Entries}}
+<ul class="entries">
+{{- range .Entries}}
+<li><span class="when">{{.Date}} {{.Time}}</span> <a href="{{.URL}}" title="{{.URL}}">{{.Title}}</a> <span class="source">{{.Source}}{{if .Profile}} · {{.Profile}}{{end}}</span></li>
+{{- end}}
+</ul>
+{{- end}}
+</details>
+{{- end}}
+
+{{- range .Sections}}
+<h2>{{.Name}} <span class="count">({{.Count}})</span></h2>
+{{- range .Tags}}{{template "tag" .}}{{end}}
+{{- end}}
+
+<footer>Generated by web-log on {{.Generated}}.</footer>
+</body>
+</html>
+`))
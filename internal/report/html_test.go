@@ -0,0 +1,82 @@
+package report
+
+import (
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func TestHTMLEscapes(t *testing.T) {
+	page, err := sampleReport().HTML()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, bad := range []string{"<script>", "<b>agents</b>", `href="javascript:`} {
+		if strings.Contains(page, bad) {
+			t.Errorf("page contains %q unescaped", bad)
+		}
+	}
+	for _, want := range []string{
+		"&lt;script&gt;alert(&#34;x&#34;)&lt;/script&gt; &amp; more",
+		"compared &lt;b&gt;agents&lt;/b&gt;",
+		`href="https://example.com/a?x=%3cb%3e&amp;y=%22q%22"`,
+	} {
+		if !strings.Contains(page, want) {
+			t.Errorf("page lacks %q", want)
+		}
+	}
+}
+
+func TestHTMLSelfContained(t *testing.T) {
+	page, err := sampleReport().HTML()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, asset := range []string{"src=", "<link", "<script", "@import", "url("} {
+		if strings.Contains(page, asset) {
+			t.Errorf("page loads something with %q", asset)
+		}
+	}
+	// The only links are the entries themselves
+	links := regexp.MustCompile(`href="([^"]*)"`).FindAllStringSubmatch(page, -1)
+	if len(links) != 5 {
+		t.Errorf("got %d links, want one per entry", len(links))
+	}
+	for _, link := range links {
+		if !strings.HasPrefix(link[1], "https://") && link[1] != "#ZgotmplZ" {
+			t.Errorf("unexpected link %q", link[1])
+		}
+	}
+}
+
+func TestHTMLTags(t *testing.T) {
+	page, err := sampleReport().HTML()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := strings.Count(page, "<details>"); n != 3 || strings.Count(page, "</details>") != 3 {
+		t.Errorf("got %d <details>, want one per tag and subtag", n)
+	}
+	// ai-agents shows its own entry and nests clawdbot, which lists the
+	// other two
+	agents := page[strings.Index(page, `<span class="name">#ai-agents</span>`):strings.Index(page, "<h2>Shopping")]
+	start := strings.Index(agents, "<details>")
+	end := strings.Index(agents, "</details>") + len("</details>")
+	clawdbot, own := agents[start:end], agents[:start]+agents[end:]
+	if !strings.Contains(clawdbot, "#clawdbot") || !strings.Contains(clawdbot, "Go docs") || !strings.Contains(clawdbot, "*Bold* start") {
+		t.Errorf("clawdbot does not list its entries:\n%s", clawdbot)
+	}
+	if !strings.Contains(own, "&amp; more") {
+		t.Errorf("ai-agents does not list its own entry:\n%s", own)
+	}
+	if strings.Contains(own, "Go docs") {
+		t.Error("an entry of a subtag is listed under its parent too")
+	}
+	if !strings.Contains(page, "(3)") || !strings.Contains(page, "[github.com/steipete/bird]") {
+		t.Error("tag count or sites missing")
+	}
+	// An entry without a title shows its URL
+	if !strings.Contains(page, ">javascript:alert(1)</a>") {
+		t.Error("untitled entry does not show its URL")
+	}
+}
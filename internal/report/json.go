@@ -0,0 +1,204 @@
+package report
+
+import (
+	"encoding/json"
+	"time"
+
+	"web-log/internal/history"
+	"web-log/internal/summary"
+)
+
+// SchemaVersion is the version of the JSON document. Within a version,
+// fields are only ever added; renaming or removing a field, or changing its
+// meaning, bumps the version.
+const SchemaVersion = 1
+
+type jsonDocument struct {
+	SchemaVersion int           `json:"schema_version"`
+	GeneratedAt   string        `json:"generated_at"`
+	Period        jsonPeriod    `json:"period"`
+	Sources       []jsonSource  `json:"sources"`
+	Filters       jsonFilters   `json:"filters"`
+	Usage         jsonUsage     `json:"usage"`
+	Sections      []jsonSection `json:"sections"`
+	Searches      []jsonSearch  `json:"searches"`
+	// Entries is only present when asked for.
+	Entries []jsonEntry `json:"entries,omitempty"`
+}
+
+type jsonPeriod struct {
+	Start    string `json:"start"`
+	End      string `json:"end"`
+	Days     int    `json:"days"`
+	Timezone string `json:"timezone"`
+}
+
+type jsonSource struct {
+	Name     string `json:"name"`
+	Read     int    `json:"read"`
+	Included int    `json:"included"`
+}
+
+type jsonFilters struct {
+	Read       int            `json:"read"`
+	Included   int            `json:"included"`
+	Removed    []jsonRemoved  `json:"removed"`
+	Redactions map[string]int `json:"redactions"`
+}
+
+type jsonRemoved struct {
+	Rule  string `json:"rule"`
+	Count int    `json:"count"`
+}
+
+type jsonUsage struct {
+	Calls            int      `json:"calls"`
+	CachedCalls      int      `json:"cached_calls"`
+	PromptTokens     int      `json:"prompt_tokens"`
+	CompletionTokens int      `json:"completion_tokens"`
+	CostUSD          *float64 `json:"cost_usd"`
+}
+
+type jsonSection struct {
+	Name  string    `json:"name"`
+	Count int       `json:"count"`
+	Tags  []jsonTag `json:"tags"`
+}
+
+type jsonTag struct {
+	Tag         string    `json:"tag"`
+	Count       int       `json:"count"`
+	Description string    `json:"description"`
+	Sites       []string  `json:"sites"`
+	Entries     []int     `json:"entries"`
+	SubTags     []jsonTag `json:"subtags"`
+}
+
+type jsonSearch struct {
+	VisitTime string `json:"visit_time"`
+	Engine    string `json:"engine"`
+	Query     string `json:"query"`
+	URL       string `json:"url"`
+	Source    string `json:"source"`
+	Profile   string `json:"profile,omitempty"`
+}
+
+type jsonEntry struct {
+	ID              int    `json:"id"`
+	URL             string `json:"url"`
+	Title           string `json:"title"`
+	VisitTime       string `json:"visit_time"`
+	Source          string `json:"source"`
+	Profile         string `json:"profile,omitempty"`
+	Transition      string `json:"transition,omitempty"`
+	DurationSeconds int    `json:"duration_seconds,omitempty"`
+	SearchTerm      string `json:"search_term,omitempty"`
+}
+
+// JSON renders the report as an indented JSON document; see SchemaVersion.
+// Tags reference entries by id, and withEntries adds the entries themselves.
+func (r *Report) JSON(withEntries bool) ([]byte, error) {
+	s := r.Summary
+	doc := jsonDocument{
+		SchemaVersion: SchemaVersion,
+		GeneratedAt:   r.Generated.Format(time.RFC3339),
+		Period: jsonPeriod{
+			Start:    s.StartDate,
+			End:      s.EndDate,
+			Days:     s.Days,
+			Timezone: r.Timezone,
+		},
+		Sources: []jsonSource{},
+		Filters: jsonFilters{
+			Included:   len(s.Entries),
+			Removed:    []jsonRemoved{},
+			Redactions: map[string]int{},
+		},
+		Sections: []jsonSection{},
+		Searches: []jsonSearch{},
+	}
+	for _, source := range r.Sources() {
+		doc.Sources = append(doc.Sources, jsonSource{Name: source.Name, Read: source.Read, Included: source.Included})
+		doc.Filters.Read += source.Read
+	}
+	for _, filter := range r.Filtered {
+		doc.Filters.Removed = append(doc.Filters.Removed, jsonRemoved{Rule: filter.Rule, Count: filter.Removed})
+	}
+	for rule, n := range r.Redactions {
+		doc.Filters.Redactions[rule] = n
+	}
+
+	usage := s.TotalUsage()
+	doc.Usage = jsonUsage{
+		Calls:            len(s.Calls),
+		PromptTokens:     usage.PromptTokens,
+		CompletionTokens: usage.CompletionTokens,
+		CostUSD:          usage.Cost,
+	}
+	for _, call := range s.Calls {
+		if call.Usage.Cached {
+			doc.Usage.CachedCalls++
+		}
+	}
+
+	for _, section := range s.Sections {
+		js := jsonSection{Name: section.Name, Count: section.Count(), Tags: []jsonTag{}}
+		for _, tag := range section.Tags {
+			js.Tags = append(js.Tags, newJSONTag(tag))
+		}
+		doc.Sections = append(doc.Sections, js)
+	}
+
+	for _, search := range history.ExtractSearches(s.Entries) {
+		doc.Searches = append(doc.Searches, jsonSearch{
+			VisitTime: search.VisitTime.Format(time.RFC3339),
+			Engine:    search.Engine,
+			Query:     search.Query,
+			URL:       search.URL,
+			Source:    search.Source,
+			Profile:   search.Profile,
+		})
+	}
+
+	if withEntries {
+		doc.Entries = make([]jsonEntry, len(s.Entries))
+		for i, entry := range s.Entries {
+			doc.Entries[i] = jsonEntry{
+				ID:              i + 1,
+				URL:             entry.URL,
+				Title:           entry.Title,
+				VisitTime:       entry.VisitTime.Format(time.RFC3339),
+				Source:          entry.Source,
+				Profile:         entry.Profile,
+				Transition:      string(entry.Transition),
+				DurationSeconds: int(entry.Duration.Seconds()),
+				SearchTerm:      searchTerm(entry),
+			}
+		}
+	}
+	return json.MarshalIndent(doc, "", "  ")
+}
+
+func newJSONTag(tag summary.Tag) jsonTag {
+	jt := jsonTag{
+		Tag:         tag.Name,
+		Count:       tag.Count,
+		Description: tag.Description,
+		Sites:       append([]string{}, tag.Sites...),
+		Entries:     append([]int{}, tag.Entries...),
+		SubTags:     []jsonTag{},
+	}
+	for _, sub := range tag.SubTags {
+		jt.SubTags = append(jt.SubTags, newJSONTag(sub))
+	}
+	return jt
+}
+
+// searchTerm is the query behind a search results page, whether the browser
+// recorded it or it was read from the URL.
+func searchTerm(entry history.Entry) string {
+	if search, ok := history.ExtractSearch(entry); ok {
+		return search.Query
+	}
+	return ""
+}
@@ -0,0 +1,132 @@
+package report
+
+import (
+	"encoding/json"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+// keys returns the sorted field names of a JSON object.
+func keys(value any) []string {
+	object, _ := value.(map[string]any)
+	names := []string{}
+	for name := range object {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// first returns the first element of a JSON array.
+func first(value any) any {
+	if array, _ := value.([]any); len(array) > 0 {
+		return array[0]
+	}
+	return nil
+}
+
+// TestJSONSchema pins the field names of schema version 1. A failure here
+// means the schema changed: fields may be added within a version, but
+// renaming or removing one needs a new SchemaVersion and README update.
+func TestJSONSchema(t *testing.T) {
+	if SchemaVersion != 1 {
+		t.Fatalf("SchemaVersion = %d; update this test along with the README", SchemaVersion)
+	}
+	data, err := sampleReport().JSON(true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var doc map[string]any
+	if err := json.Unmarshal(data, &doc); err != nil {
+		t.Fatal(err)
+	}
+	if doc["schema_version"] != float64(1) {
+		t.Errorf("schema_version = %v, want 1", doc["schema_version"])
+	}
+
+	sections := doc["sections"]
+	tag := first(first(sections).(map[string]any)["tags"])
+	entries := doc["entries"].([]any)
+	tests := []struct {
+		name   string
+		object any
+		want   []string
+	}{
+		{"document", doc, []string{"entries", "filters", "generated_at", "period", "schema_version", "searches", "sections", "sources", "usage"}},
+		{"period", doc["period"], []string{"days", "end", "start", "timezone"}},
+		{"sources[]", first(doc["sources"]), []string{"included", "name", "read"}},
+		{"filters", doc["filters"], []string{"included", "read", "redactions", "removed"}},
+		{"filters.removed[]", first(doc["filters"].(map[string]any)["removed"]), []string{"count", "rule"}},
+		{"usage", doc["usage"], []string{"cached_calls", "calls", "completion_tokens", "cost_usd", "prompt_tokens"}},
+		{"sections[]", first(sections), []string{"count", "name", "tags"}},
+		{"tags[]", tag, []string{"count", "description", "entries", "sites", "subtags", "tag"}},
+		{"subtags[]", first(tag.(map[string]any)["subtags"]), []string{"count", "description", "entries", "sites", "subtags", "tag"}},
+		{"searches[]", first(doc["searches"]), []string{"engine", "profile", "query", "source", "url", "visit_time"}},
+		{"entries[] with everything known", entries[0], []string{"duration_seconds", "id", "profile", "source", "title", "transition", "url", "visit_time"}},
+		{"entries[] of a search", entries[3], []string{"id", "profile", "search_term", "source", "title", "url", "visit_time"}},
+		{"entries[] with little known", entries[4], []string{"id", "source", "title", "url", "visit_time"}},
+	}
+	for _, tt := range tests {
+		if got := keys(tt.object); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s fields = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestJSONValues(t *testing.T) {
+	data, err := sampleReport().JSON(false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var doc struct {
+		GeneratedAt string `json:"generated_at"`
+		Period      struct {
+			Start, End, Timezone string
+			Days                 int
+		} `json:"period"`
+		Filters struct {
+			Read, Included int
+			Redactions     map[string]int
+		} `json:"filters"`
+		Usage struct {
+			Calls   int      `json:"calls"`
+			CostUSD *float64 `json:"cost_usd"`
+		} `json:"usage"`
+		Sections []struct {
+			Count int `json:"count"`
+			Tags  []struct {
+				Entries []int `json:"entries"`
+				SubTags []any `json:"subtags"`
+			} `json:"tags"`
+		} `json:"sections"`
+		Searches []struct {
+			Query string `json:"query"`
+		} `json:"searches"`
+		Entries []any `json:"entries"`
+	}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		t.Fatal(err)
+	}
+	if doc.GeneratedAt != "2026-10-17T09:30:00+02:00" || doc.Period.Start != "2026-10-12" || doc.Period.Days != 2 || doc.Period.Timezone != "Europe/Zurich" {
+		t.Errorf("header = %s %+v", doc.GeneratedAt, doc.Period)
+	}
+	if doc.Filters.Read != 15 || doc.Filters.Included != 5 || doc.Filters.Redactions["email"] != 1 {
+		t.Errorf("filters = %+v", doc.Filters)
+	}
+	if doc.Usage.Calls != 1 || doc.Usage.CostUSD != nil {
+		t.Errorf("usage = %+v, want one call and a null cost", doc.Usage)
+	}
+	if len(doc.Sections) != 2 || doc.Sections[0].Count != 3 || !reflect.DeepEqual(doc.Sections[0].Tags[0].Entries, []int{1, 2, 3}) {
+		t.Errorf("sections = %+v", doc.Sections)
+	}
+	if doc.Sections[1].Tags[0].SubTags == nil {
+		t.Error("subtags is null, want an empty list")
+	}
+	if len(doc.Searches) != 1 || doc.Searches[0].Query != "garmin venu" {
+		t.Errorf("searches = %+v", doc.Searches)
+	}
+	if doc.Entries != nil {
+		t.Error("entries present without asking for them")
+	}
+}
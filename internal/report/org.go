@@ -0,0 +1,167 @@
+package report
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+
+	"web-log/internal/summary"
+)
+
+const (
+	// sessionGap ends a browsing session: visits further apart than this
+	// are clocked separately.
+	sessionGap = 30 * time.Minute
+	// minVisit is how long a visit without a known duration is clocked for.
+	minVisit = time.Minute
+)
+
+// Org renders the report as an Org-mode outline: a heading for the period,
+// one per section and one per tag with subtags below it. Counts, sites and
+// sources go in properties drawers, tags become Org tags, and the browsing
+// sessions behind each tag are logged as CLOCK lines, so clock reports and
+// agenda views pick them up.
+func (r *Report) Org() string {
+	s := r.Summary
+	var b strings.Builder
+	fmt.Fprintf(&b, "#+TITLE: Browsing Summary - %s to %s\n\n", s.StartDate, s.EndDate)
+	fmt.Fprintf(&b, "* Browsing Summary - %s to %s (%d days)\n", s.StartDate, s.EndDate, s.Days)
+	sources := []string{}
+	for _, source := range r.Sources() {
+		if source.Included > 0 {
+			sources = append(sources, fmt.Sprintf("%s=%d", source.Name, source.Included))
+		}
+	}
+	orgDrawer(&b, [][2]string{
+		{"START_DATE", s.StartDate},
+		{"END_DATE", s.EndDate},
+		{"DAYS", fmt.Sprint(s.Days)},
+		{"TIMEZONE", r.Timezone},
+		{"ENTRIES", fmt.Sprint(len(s.Entries))},
+		{"SOURCES", strings.Join(sources, " ")},
+		{"GENERATED", orgTime(r.Generated)},
+	})
+
+	for _, section := range s.Sections {
+		fmt.Fprintf(&b, "** %s\n", section.Name)
+		orgDrawer(&b, [][2]string{{"COUNT", fmt.Sprint(section.Count())}})
+		for _, tag := range section.Tags {
+			// Clock time adds up over a subtree, so entries a subtag
+			// covers are clocked there only
+			inSubTag := map[int]bool{}
+			for _, sub := range tag.SubTags {
+				for _, id := range sub.Entries {
+					inSubTag[id] = true
+				}
+			}
+			own := []int{}
+			for _, id := range tag.Entries {
+				if !inSubTag[id] {
+					own = append(own, id)
+				}
+			}
+			r.orgTag(&b, "***", tag, own)
+			for _, sub := range tag.SubTags {
+				r.orgTag(&b, "****", sub, sub.Entries)
+			}
+		}
+	}
+	return strings.TrimRight(b.String(), "\n")
+}
+
+// orgTag writes a tag heading, clocking the sessions of the entries in ids.
+// Subtag headings inherit their parent's Org tag.
+func (r *Report) orgTag(b *strings.Builder, stars string, tag summary.Tag, ids []int) {
+	fmt.Fprintf(b, "%s %s :%s:\n", stars, tag.Name, orgTagName(tag.Name))
+	props := [][2]string{{"COUNT", fmt.Sprint(tag.Count)}}
+	if len(tag.Sites) > 0 {
+		props = append(props, [2]string{"SITES", strings.Join(tag.Sites, " ")})
+	}
+	orgDrawer(b, props)
+
+	clocks := r.sessions(ids)
+	if len(clocks) > 0 {
+		b.WriteString(":LOGBOOK:\n")
+		// Org keeps the newest clock line first
+		for i := len(clocks) - 1; i >= 0; i-- {
+			c := clocks[i]
+			d := c.end.Sub(c.start)
+			fmt.Fprintf(b, "CLOCK: %s--%s => %2d:%02d\n", orgTime(c.start), orgTime(c.end), int(d.Hours()), int(d.Minutes())%60)
+		}
+		b.WriteString(":END:\n")
+	}
+	if tag.Description != "" {
+		b.WriteString(orgText(tag.Description) + "\n")
+	}
+}
+
+// orgText keeps model-written text on one line and stops it from starting
+// with "*", which would make it a heading, or "#", a comment or keyword.
+// Org's escape for that is a zero-width space in front.
+func orgText(text string) string {
+	text = strings.Join(strings.Fields(text), " ")
+	if strings.HasPrefix(text, "*") || strings.HasPrefix(text, "#") {
+		text = "\u200b" + text
+	}
+	return text
+}
+
+type session struct {
+	start, end time.Time
+}
+
+// sessions groups the visits behind ids into sessions, starting a new one
+// after sessionGap without a visit. A session ends when its last visit
+// does, taking at least minVisit.
+func (r *Report) sessions(ids []int) []session {
+	type visit struct{ start, end time.Time }
+	visits := []visit{}
+	for _, id := range ids {
+		entry, ok := r.Summary.Entry(id)
+		if !ok {
+			continue
+		}
+		visits = append(visits, visit{entry.VisitTime, entry.VisitTime.Add(max(entry.Duration, minVisit))})
+	}
+	sort.Slice(visits, func(i, j int) bool { return visits[i].start.Before(visits[j].start) })
+
+	sessions := []session{}
+	for _, v := range visits {
+		if n := len(sessions); n > 0 && v.start.Sub(sessions[n-1].end) <= sessionGap {
+			if v.end.After(sessions[n-1].end) {
+				sessions[n-1].end = v.end
+			}
+			continue
+		}
+		sessions = append(sessions, session{start: v.start, end: v.end})
+	}
+	return sessions
+}
+
+func orgDrawer(b *strings.Builder, props [][2]string) {
+	b.WriteString(":PROPERTIES:\n")
+	for _, prop := range props {
+		if prop[1] != "" {
+			fmt.Fprintf(b, ":%s: %s\n", prop[0], prop[1])
+		}
+	}
+	b.WriteString(":END:\n")
+}
+
+// orgTime formats an inactive Org timestamp such as [2026-10-17 Sat 21:04].
+func orgTime(t time.Time) string {
+	return t.Format("[2006-01-02 Mon 15:04]")
+}
+
+// orgTagName makes name a valid Org tag, which allows letters, digits, "_",
+// "@", "#" and "%": "ai-agents" becomes "ai_agents".
+func orgTagName(name string) string {
+	return strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) || strings.ContainsRune("_@#%", r) {
+			return r
+		}
+		return '_'
+	}, name)
+}
@@ -0,0 +1,111 @@
+package report
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestOrg(t *testing.T) {
+	org := sampleReport().Org()
+	want := `#+TITLE: Browsing Summary - 2026-10-12 to 2026-10-13
+
+* Browsing Summary - 2026-10-12 to 2026-10-13 (2 days)
+:PROPERTIES:
+:START_DATE: 2026-10-12
+:END_DATE: 2026-10-13
+:DAYS: 2
+:TIMEZONE: Europe/Zurich
+:ENTRIES: 5
+:SOURCES: chrome=3 firefox=1 safari=1
+:GENERATED: [2026-10-17 Sat 09:30]
+:END:
+** Development
+:PROPERTIES:
+:COUNT: 3
+:END:
+*** ai-agents :ai_agents:
+:PROPERTIES:
+:COUNT: 3
+:SITES: github.com/steipete/bird
+:END:
+:LOGBOOK:
+CLOCK: [2026-10-12 Mon 09:00]--[2026-10-12 Mon 09:05] =>  0:05
+:END:
+compared <b>agents</b>
+**** clawdbot :clawdbot:
+:PROPERTIES:
+:COUNT: 2
+:END:
+:LOGBOOK:
+CLOCK: [2026-10-12 Mon 11:00]--[2026-10-12 Mon 11:01] =>  0:01
+CLOCK: [2026-10-12 Mon 09:10]--[2026-10-12 Mon 09:11] =>  0:01
+:END:
+` + "\u200b" + `* set up clawdbot
+** Shopping
+:PROPERTIES:
+:COUNT: 2
+:END:
+*** garmin :garmin:
+:PROPERTIES:
+:COUNT: 2
+:END:
+:LOGBOOK:
+CLOCK: [2026-10-13 Tue 20:00]--[2026-10-13 Tue 20:06] =>  0:06
+:END:
+` + "\u200b" + `# compared watches`
+	if org != want {
+		t.Errorf("got:\n%s\nwant:\n%s", org, want)
+	}
+}
+
+func TestOrgHeadings(t *testing.T) {
+	r := sampleReport()
+	r.Summary.Sections[0].Tags[0].Description = "first line\n* not a heading"
+	for _, line := range strings.Split(r.Org(), "\n") {
+		stars := len(line) - len(strings.TrimLeft(line, "*"))
+		if stars == 0 || !strings.HasPrefix(line[stars:], " ") {
+			continue
+		}
+		switch heading := line[stars+1:]; {
+		case stars == 1 && strings.HasPrefix(heading, "Browsing Summary"):
+		case stars == 2 && (heading == "Development" || heading == "Shopping"):
+		case stars == 3 && (heading == "ai-agents :ai_agents:" || heading == "garmin :garmin:"):
+		case stars == 4 && heading == "clawdbot :clawdbot:":
+		default:
+			t.Errorf("unexpected heading %q", line)
+		}
+	}
+}
+
+func TestSessions(t *testing.T) {
+	r := sampleReport()
+	// 09:00 for 5 minutes and 09:10 are 5 minutes apart, 11:00 is after a
+	// longer gap than sessionGap
+	sessions := r.sessions([]int{3, 2, 1})
+	if len(sessions) != 2 {
+		t.Fatalf("got %d sessions, want 2: %+v", len(sessions), sessions)
+	}
+	if got := sessions[0].end.Sub(sessions[0].start); got != 11*minVisit {
+		t.Errorf("first session lasts %s, want 09:00 to 09:11", got)
+	}
+	if got := sessions[1].end.Sub(sessions[1].start); got != minVisit {
+		t.Errorf("a single visit without a duration lasts %s, want %s", got, minVisit)
+	}
+	if sessions := r.sessions([]int{99}); len(sessions) != 0 {
+		t.Errorf("unknown ids made sessions %+v", sessions)
+	}
+}
+
+func TestOrgTagName(t *testing.T) {
+	tests := map[string]string{
+		"ai-agents": "ai_agents",
+		"node.js":   "node_js",
+		"c#":        "c#",
+		"café":      "café",
+	}
+	for name, want := range tests {
+		if got := orgTagName(name); got != want {
+			t.Errorf("orgTagName(%q) = %q, want %q", name, got, want)
+		}
+	}
+}
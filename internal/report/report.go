@@ -0,0 +1,61 @@
+// Package report renders a summary together with how it came about: the
+// period, what each source contributed and what the filters removed.
+package report
+
+import (
+	"sort"
+	"time"
+
+	"web-log/internal/summary"
+)
+
+// Report is a summary and the run that produced it.
+type Report struct {
+	Summary *summary.Summary
+	// Timezone is the IANA name days and times are given in.
+	Timezone string
+	// Read counts the entries read per source, before any filtering.
+	Read map[string]int
+	// Filtered lists what each filter rule removed, in the order the
+	// filters ran.
+	Filtered []Filter
+	// Redactions counts the redactions made per redaction rule.
+	Redactions map[string]int
+	Generated  time.Time
+}
+
+// Filter counts the entries one filter rule removed.
+type Filter struct {
+	Rule    string
+	Removed int
+}
+
+// SourceCount is what one source contributed.
+type SourceCount struct {
+	Name string
+	// Read is the number of entries read, Included the number left after
+	// filtering.
+	Read     int
+	Included int
+}
+
+// Sources returns the per-source counts ordered by name.
+func (r *Report) Sources() []SourceCount {
+	included := map[string]int{}
+	for _, entry := range r.Summary.Entries {
+		included[entry.Source]++
+	}
+	names := map[string]bool{}
+	for name := range r.Read {
+		names[name] = true
+	}
+	for name := range included {
+		names[name] = true
+	}
+	counts := make([]SourceCount, 0, len(names))
+	for name := range names {
+		counts = append(counts, SourceCount{Name: name, Read: r.Read[name], Included: included[name]})
+	}
+	sort.Slice(counts, func(i, j int) bool { return counts[i].Name < counts[j].Name })
+	return counts
+}
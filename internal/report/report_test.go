@@ -0,0 +1,61 @@
+package report
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"web-log/internal/history"
+	"web-log/internal/summary"
+)
+
+// sampleReport is a two-day report with a tag that has a subtag, a search,
+// and titles and URLs that need escaping.
+func sampleReport() *Report {
+	zone := time.FixedZone("CEST", 2*60*60)
+	at := func(day, hour, minute int) time.Time { return time.Date(2026, 10, day, hour, minute, 0, 0, zone) }
+	entries := []history.Entry{
+		{URL: `https://example.com/a?x=<b>&y="q"`, Title: `<script>alert("x")</script> & more`, VisitTime: at(12, 9, 0), Source: "chrome", Profile: "Work", Duration: 5 * time.Minute, Transition: history.TransitionLink},
+		{URL: "https://go.dev/doc/", Title: "Go docs", VisitTime: at(12, 9, 10), Source: "chrome", Profile: "Work"},
+		{URL: "https://news.example/", Title: "*Bold* start", VisitTime: at(12, 11, 0), Source: "firefox"},
+		{URL: "https://www.google.com/search?q=garmin+venu", Title: "garmin venu - Google Search", VisitTime: at(13, 20, 0), Source: "chrome", Profile: "Personal"},
+		{URL: "javascript:alert(1)", VisitTime: at(13, 20, 5), Source: "safari"},
+	}
+	s := &summary.Summary{
+		StartDate: "2026-10-12",
+		EndDate:   "2026-10-13",
+		Days:      2,
+		Entries:   entries,
+		Sections: []summary.Section{
+			{Name: "Development", Tags: []summary.Tag{
+				{Name: "ai-agents", Count: 3, Description: "compared <b>agents</b>", Sites: []string{"github.com/steipete/bird"}, Entries: []int{1, 2, 3}, SubTags: []summary.Tag{
+					{Name: "clawdbot", Count: 2, Description: "* set up clawdbot", Entries: []int{2, 3}},
+				}},
+			}},
+			{Name: "Shopping", Tags: []summary.Tag{
+				{Name: "garmin", Count: 2, Description: "# compared watches", Entries: []int{4, 5}},
+			}},
+		},
+		Calls: []summary.Call{{Provider: "fake", Model: "m", Usage: summary.Usage{PromptTokens: 100, CompletionTokens: 20}}},
+	}
+	return &Report{
+		Summary:    s,
+		Timezone:   "Europe/Zurich",
+		Read:       map[string]int{"chrome": 10, "firefox": 1, "edge": 4},
+		Filtered:   []Filter{{Rule: "redirect", Removed: 6}, {Rule: "duplicate", Removed: 3}},
+		Redactions: map[string]int{"email": 1},
+		Generated:  time.Date(2026, 10, 17, 9, 30, 0, 0, zone),
+	}
+}
+
+func TestSources(t *testing.T) {
+	want := []SourceCount{
+		{Name: "chrome", Read: 10, Included: 3},
+		{Name: "edge", Read: 4, Included: 0},
+		{Name: "firefox", Read: 1, Included: 1},
+		{Name: "safari", Read: 0, Included: 1},
+	}
+	if got := sampleReport().Sources(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Sources() = %+v, want %+v", got, want)
+	}
+}
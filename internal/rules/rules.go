@@ -0,0 +1,256 @@
+// Package rules decides which history entries are left out of a summary.
+//
+// Rules are checked in order and the first one matching an entry decides:
+// an "exclude" rule drops it, an "include" rule keeps it. Entries no rule
+// matches are kept. User rules come before the defaults, so an include rule
+// can make an exception to a default exclusion.
+package rules
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"web-log/internal/history"
+)
+
+const (
+	Exclude = "exclude"
+	Include = "include"
+)
+
+// Rule matches entries on every condition it sets; a rule without
+// conditions matches nothing.
+type Rule struct {
+	Name string `toml:"name,omitempty"`
+	// Action is Exclude (the default) or Include.
+	Action string `toml:"action,omitempty"`
+	// Domains are globs matched against the host without "www.". A leading
+	// "*." also matches the domain itself: "*.google.com" covers
+	// google.com and mail.google.com.
+	Domains []string `toml:"domains,omitempty"`
+	// URL and Title are regular expressions.
+	URL   string `toml:"url,omitempty"`
+	Title string `toml:"title,omitempty"`
+	// PathPrefixes match the start of the URL path, e.g. "/settings".
+	PathPrefixes []string `toml:"path_prefixes,omitempty"`
+	// Hours is a time-of-day window such as "09:00-17:30" in the Set's
+	// location; windows may wrap past midnight ("22:00-06:00").
+	Hours string `toml:"hours,omitempty"`
+}
+
+// Defaults leave out pages that say nothing about what was done: mail,
+// sign-in, consent and unsubscribe pages.
+var Defaults = []Rule{
+	{Name: "mail", Domains: []string{"mail.google.com"}},
+	{Name: "sign-in", Domains: []string{"accounts.google.com", "myaccount.google.com", "auth.*", "login.*", "sso.*"}},
+	{Name: "consent", Domains: []string{"consent.*"}},
+	{Name: "unsubscribe", URL: `(?i)unsubscribe|email-preferences|manage-subscription`},
+}
+
+type compiled struct {
+	Rule
+	url   *regexp.Regexp
+	title *regexp.Regexp
+	// from and to are minutes since midnight; from == to means no window.
+	from, to int
+}
+
+// Set is an ordered list of compiled rules.
+type Set struct {
+	rules    []compiled
+	location *time.Location
+}
+
+// New compiles rules in order. Time windows are read in location, or
+// time.Local when nil.
+func New(rules []Rule, location *time.Location) (*Set, error) {
+	if location == nil {
+		location = time.Local
+	}
+	set := &Set{location: location}
+	for i, rule := range rules {
+		c, err := compile(rule)
+		if err != nil {
+			name := rule.Name
+			if name == "" {
+				name = "#" + strconv.Itoa(i+1)
+			}
+			return nil, fmt.Errorf("rule %s: %w", name, err)
+		}
+		set.rules = append(set.rules, c)
+	}
+	return set, nil
+}
+
+func compile(rule Rule) (compiled, error) {
+	c := compiled{Rule: rule}
+	switch rule.Action {
+	case "":
+		c.Action = Exclude
+	case Exclude, Include:
+	default:
+		return c, fmt.Errorf("action %q (want %s or %s)", rule.Action, Exclude, Include)
+	}
+	if c.Name == "" {
+		c.Name = rule.Conditions()
+	}
+	for _, domain := range rule.Domains {
+		if _, err := path.Match(domain, ""); err != nil {
+			return c, fmt.Errorf("domain glob %q: %w", domain, err)
+		}
+	}
+	var err error
+	if rule.URL != "" {
+		if c.url, err = regexp.Compile(rule.URL); err != nil {
+			return c, fmt.Errorf("url: %w", err)
+		}
+	}
+	if rule.Title != "" {
+		if c.title, err = regexp.Compile(rule.Title); err != nil {
+			return c, fmt.Errorf("title: %w", err)
+		}
+	}
+	if rule.Hours != "" {
+		if c.from, c.to, err = parseHours(rule.Hours); err != nil {
+			return c, err
+		}
+	}
+	if len(rule.Domains) == 0 && rule.URL == "" && rule.Title == "" && len(rule.PathPrefixes) == 0 && rule.Hours == "" {
+		return c, errors.New("no conditions")
+	}
+	return c, nil
+}
+
+// Conditions describes what the rule matches, e.g.
+// "domains auth.*, login.*; hours 09:00-17:00".
+func (r Rule) Conditions() string {
+	parts := []string{}
+	if len(r.Domains) > 0 {
+		parts = append(parts, "domains "+strings.Join(r.Domains, ", "))
+	}
+	if len(r.PathPrefixes) > 0 {
+		parts = append(parts, "path "+strings.Join(r.PathPrefixes, ", "))
+	}
+	if r.URL != "" {
+		parts = append(parts, "url /"+r.URL+"/")
+	}
+	if r.Title != "" {
+		parts = append(parts, "title /"+r.Title+"/")
+	}
+	if r.Hours != "" {
+		parts = append(parts, "hours "+r.Hours)
+	}
+	return strings.Join(parts, "; ")
+}
+
+// parseHours reads "HH:MM-HH:MM" into minutes since midnight.
+func parseHours(hours string) (int, int, error) {
+	start, end, ok := strings.Cut(hours, "-")
+	if !ok {
+		return 0, 0, fmt.Errorf("hours %q (want HH:MM-HH:MM)", hours)
+	}
+	from, err1 := time.Parse("15:04", strings.TrimSpace(start))
+	to, err2 := time.Parse("15:04", strings.TrimSpace(end))
+	if err1 != nil || err2 != nil {
+		return 0, 0, fmt.Errorf("hours %q (want HH:MM-HH:MM)", hours)
+	}
+	return from.Hour()*60 + from.Minute(), to.Hour()*60 + to.Minute(), nil
+}
+
+// Rules returns the rules in the order they are checked.
+func (s *Set) Rules() []Rule {
+	rules := make([]Rule, len(s.rules))
+	for i, c := range s.rules {
+		rules[i] = c.Rule
+	}
+	return rules
+}
+
+// Match returns the first rule matching entry.
+func (s *Set) Match(entry history.Entry) (Rule, bool) {
+	parsed, err := url.Parse(entry.URL)
+	if err != nil {
+		parsed = &url.URL{}
+	}
+	host := strings.TrimPrefix(strings.ToLower(parsed.Hostname()), "www.")
+	for _, c := range s.rules {
+		if c.matches(entry, parsed, host, s.location) {
+			return c.Rule, true
+		}
+	}
+	return Rule{}, false
+}
+
+// Filter drops the entries an exclude rule matches and returns the rest with
+// the number removed per rule name.
+func (s *Set) Filter(entries []history.Entry) ([]history.Entry, map[string]int) {
+	removed := map[string]int{}
+	kept := make([]history.Entry, 0, len(entries))
+	for _, entry := range entries {
+		if rule, ok := s.Match(entry); ok && rule.Action == Exclude {
+			removed[rule.Name]++
+			continue
+		}
+		kept = append(kept, entry)
+	}
+	return kept, removed
+}
+
+func (c compiled) matches(entry history.Entry, parsed *url.URL, host string, location *time.Location) bool {
+	if len(c.Domains) > 0 && !matchDomain(c.Domains, host) {
+		return false
+	}
+	if len(c.PathPrefixes) > 0 && !hasAnyPrefix(parsed.Path, c.PathPrefixes) {
+		return false
+	}
+	if c.url != nil && !c.url.MatchString(entry.URL) {
+		return false
+	}
+	if c.title != nil && !c.title.MatchString(entry.Title) {
+		return false
+	}
+	if c.from != c.to {
+		local := entry.VisitTime.In(location)
+		minute := local.Hour()*60 + local.Minute()
+		if c.from < c.to {
+			if minute < c.from || minute >= c.to {
+				return false
+			}
+		} else if minute < c.from && minute >= c.to {
+			// The window wraps past midnight
+			return false
+		}
+	}
+	return true
+}
+
+func matchDomain(globs []string, host string) bool {
+	if host == "" {
+		return false
+	}
+	for _, glob := range globs {
+		glob = strings.ToLower(glob)
+		if ok, _ := path.Match(glob, host); ok {
+			return true
+		}
+		if strings.HasPrefix(glob, "*.") && host == glob[2:] {
+			return true
+		}
+	}
+	return false
+}
+
+func hasAnyPrefix(s string, prefixes []string) bool {
+	for _, prefix := range prefixes {
+		if strings.HasPrefix(s, prefix) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,122 @@
+package rules
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"web-log/internal/history"
+)
+
+func at(hour, minute int) time.Time {
+	return time.Date(2026, 10, 17, hour, minute, 0, 0, time.UTC)
+}
+
+func TestMatchPrecedence(t *testing.T) {
+	user := []Rule{
+		{Name: "work-mail", Action: Include, Domains: []string{"mail.google.com"}, PathPrefixes: []string{"/mail/u/1"}},
+		{Name: "news", Domains: []string{"*.news.example"}},
+		{Name: "news-tech", Action: Include, Domains: []string{"tech.news.example"}},
+	}
+	set, err := New(append(user, Defaults...), time.UTC)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		url  string
+		rule string
+		kept bool
+	}{
+		{"https://mail.google.com/mail/u/0/#inbox", "mail", false},
+		{"https://mail.google.com/mail/u/1/#inbox", "work-mail", true},
+		// The earlier exclusion wins over the later include
+		{"https://tech.news.example/today", "news", false},
+		{"https://news.example/", "news", false},
+		{"https://login.example.com/", "sign-in", false},
+		{"https://shop.example/unsubscribe?id=1", "unsubscribe", false},
+		{"https://www.example.com/", "", true},
+		{"not a url", "", true},
+	}
+	for _, tt := range tests {
+		entry := history.Entry{URL: tt.url, VisitTime: at(12, 0)}
+		rule, ok := set.Match(entry)
+		if rule.Name != tt.rule || ok != (tt.rule != "") {
+			t.Errorf("Match(%q) = %q, %v; want %q", tt.url, rule.Name, ok, tt.rule)
+		}
+		kept, _ := set.Filter([]history.Entry{entry})
+		if (len(kept) == 1) != tt.kept {
+			t.Errorf("Filter(%q) kept = %v, want %v", tt.url, len(kept) == 1, tt.kept)
+		}
+	}
+}
+
+func TestMatchHours(t *testing.T) {
+	zurich, err := time.LoadLocation("Europe/Zurich")
+	if err != nil {
+		t.Skip(err)
+	}
+	tests := []struct {
+		hours string
+		visit time.Time
+		want  bool
+	}{
+		{"09:00-17:30", at(7, 0), true},    // 09:00 in Zurich
+		{"09:00-17:30", at(6, 59), false},  // 08:59
+		{"09:00-17:30", at(15, 30), false}, // 17:30, the end is exclusive
+		{"22:00-06:00", at(20, 0), true},   // 22:00
+		{"22:00-06:00", at(23, 30), true},  // 01:30
+		{"22:00-06:00", at(3, 59), true},   // 05:59
+		{"22:00-06:00", at(4, 0), false},   // 06:00
+		{"22:00-06:00", at(10, 0), false},  // 12:00
+	}
+	for _, tt := range tests {
+		set, err := New([]Rule{{Name: "window", Hours: tt.hours}}, zurich)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, ok := set.Match(history.Entry{URL: "https://example.com/", VisitTime: tt.visit})
+		if ok != tt.want {
+			t.Errorf("%s at %s: matched %v, want %v", tt.hours, tt.visit.In(zurich).Format("15:04"), ok, tt.want)
+		}
+	}
+}
+
+func TestMatchAllConditions(t *testing.T) {
+	set, err := New([]Rule{{Name: "late-youtube", Domains: []string{"youtube.com"}, Title: "(?i)shorts", Hours: "23:00-05:00"}}, time.UTC)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		entry history.Entry
+		want  bool
+	}{
+		{history.Entry{URL: "https://www.youtube.com/shorts/x", Title: "Shorts", VisitTime: at(23, 30)}, true},
+		{history.Entry{URL: "https://www.youtube.com/shorts/x", Title: "Shorts", VisitTime: at(12, 0)}, false},
+		{history.Entry{URL: "https://www.youtube.com/watch?v=x", Title: "Talk", VisitTime: at(23, 30)}, false},
+		{history.Entry{URL: "https://vimeo.com/x", Title: "Shorts", VisitTime: at(23, 30)}, false},
+	}
+	for _, tt := range tests {
+		if _, ok := set.Match(tt.entry); ok != tt.want {
+			t.Errorf("Match(%s, %q, %s) = %v, want %v", tt.entry.URL, tt.entry.Title, tt.entry.VisitTime.Format("15:04"), ok, tt.want)
+		}
+	}
+}
+
+func TestNewErrors(t *testing.T) {
+	tests := []struct {
+		rule Rule
+		want string
+	}{
+		{Rule{Name: "empty"}, "rule empty: no conditions"},
+		{Rule{Domains: []string{"[a"}}, "rule #1: domain glob"},
+		{Rule{Name: "bad-url", URL: "("}, "rule bad-url: url"},
+		{Rule{Name: "bad-hours", Hours: "9-17"}, "rule bad-hours: hours"},
+		{Rule{Name: "bad-action", Action: "drop", URL: "x"}, `action "drop"`},
+	}
+	for _, tt := range tests {
+		_, err := New([]Rule{tt.rule}, time.UTC)
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("New(%+v) = %v, want %q", tt.rule, err, tt.want)
+		}
+	}
+}
@@ -0,0 +1,193 @@
+package summary
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+const anthropicVersion = "2023-06-01"
+
+// anthropicClient uses the Anthropic Messages API.
+type anthropicClient struct {
+	baseURL   string
+	apiKey    string
+	model     string
+	maxTokens int
+}
+
+type anthropicRequest struct {
+	Model     string    `json:"model"`
+	MaxTokens int       `json:"max_tokens"`
+	Messages  []message `json:"messages"`
+	Stream    bool      `json:"stream,omitempty"`
+}
+
+type anthropicResponse struct {
+	Model   string `json:"model"`
+	Content []struct {
+		Type string `json:"type"`
+		Text string `json:"text"`
+	} `json:"content"`
+	StopReason string          `json:"stop_reason"`
+	Usage      *anthropicUsage `json:"usage"`
+	Error      *struct {
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+type anthropicUsage struct {
+	InputTokens  int `json:"input_tokens"`
+	OutputTokens int `json:"output_tokens"`
+}
+
+// anthropicStreamEvent covers the fields web-log reads from the Messages API
+// stream events.
+type anthropicStreamEvent struct {
+	Type    string `json:"type"`
+	Message struct {
+		Model string          `json:"model"`
+		Usage *anthropicUsage `json:"usage"`
+	} `json:"message"`
+	Delta struct {
+		Type       string `json:"type"`
+		Text       string `json:"text"`
+		StopReason string `json:"stop_reason"`
+	} `json:"delta"`
+	// Usage on message_delta carries the output tokens so far.
+	Usage *anthropicUsage `json:"usage"`
+	Error *struct {
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+func (u *anthropicUsage) usage() Usage {
+	if u == nil {
+		return Usage{}
+	}
+	return Usage{PromptTokens: u.InputTokens, CompletionTokens: u.OutputTokens}
+}
+
+func newAnthropic(cfg ProviderConfig) *anthropicClient {
+	return &anthropicClient{
+		baseURL:   firstNonEmpty(cfg.BaseURL, os.Getenv("ANTHROPIC_BASE_URL"), "https://api.anthropic.com/v1"),
+		apiKey:    firstNonEmpty(cfg.APIKey, os.Getenv("ANTHROPIC_API_KEY")),
+		model:     firstNonEmpty(cfg.Model, os.Getenv("ANTHROPIC_MODEL"), "claude-3-5-haiku-latest"),
+		maxTokens: 8192,
+	}
+}
+
+func (c *anthropicClient) Name() string  { return "anthropic" }
+func (c *anthropicClient) Model() string { return c.model }
+
+// BaseURL is the API root requests go to.
+func (c *anthropicClient) BaseURL() string { return c.baseURL }
+
+func (c *anthropicClient) Complete(ctx context.Context, prompt string) (Completion, error) {
+	endpoint, headers, payload, err := c.request(prompt)
+	if err != nil {
+		return Completion{}, err
+	}
+
+	var parsed anthropicResponse
+	if err := postJSON(ctx, endpoint, headers, payload, &parsed); err != nil {
+		return Completion{}, err
+	}
+	if parsed.Error != nil {
+		return Completion{}, fmt.Errorf("API error: %s", parsed.Error.Message)
+	}
+	var text strings.Builder
+	for _, block := range parsed.Content {
+		if block.Type == "text" {
+			text.WriteString(block.Text)
+		}
+	}
+	result := strings.TrimSpace(text.String())
+	if result == "" {
+		return Completion{Model: firstNonEmpty(parsed.Model, c.model), Usage: parsed.Usage.usage()}, errors.New("empty response from anthropic")
+	}
+	return Completion{
+		Text:         result,
+		Model:        firstNonEmpty(parsed.Model, c.model),
+		FinishReason: parsed.StopReason,
+		Usage:        parsed.Usage.usage(),
+	}, nil
+}
+
+// CompleteStream streams the Messages API reply, copying text deltas to w.
+// A server that turns the streaming request down is asked again without it.
+func (c *anthropicClient) CompleteStream(ctx context.Context, prompt string, w io.Writer) (Completion, error) {
+	endpoint, headers, payload, err := c.request(prompt)
+	if err != nil {
+		return Completion{}, err
+	}
+	payload.Stream = true
+
+	var text strings.Builder
+	completion := Completion{Model: c.model}
+	err = postStream(ctx, endpoint, headers, payload, func(_ string, data string) error {
+		var event anthropicStreamEvent
+		if err := json.Unmarshal([]byte(data), &event); err != nil {
+			return fmt.Errorf("decoding stream: %w", err)
+		}
+		switch event.Type {
+		case "message_start":
+			completion.Model = firstNonEmpty(event.Message.Model, completion.Model)
+			if event.Message.Usage != nil {
+				completion.Usage.PromptTokens = event.Message.Usage.InputTokens
+			}
+		case "content_block_delta":
+			if event.Delta.Type == "text_delta" {
+				text.WriteString(event.Delta.Text)
+				if _, err := io.WriteString(w, event.Delta.Text); err != nil {
+					return err
+				}
+			}
+		case "message_delta":
+			completion.FinishReason = firstNonEmpty(event.Delta.StopReason, completion.FinishReason)
+			if event.Usage != nil {
+				completion.Usage.CompletionTokens = event.Usage.OutputTokens
+			}
+		case "message_stop":
+			return errStreamDone
+		case "error":
+			if event.Error != nil {
+				return fmt.Errorf("API error: %s", event.Error.Message)
+			}
+		}
+		return nil
+	})
+	if streamRejected(err) {
+		return completeUnstreamed(ctx, c, prompt, w)
+	}
+	if err != nil {
+		return Completion{Model: completion.Model, Usage: completion.Usage}, err
+	}
+	completion.Text = strings.TrimSpace(text.String())
+	if completion.Text == "" {
+		return completion, errors.New("empty response from anthropic")
+	}
+	return completion, nil
+}
+
+func (c *anthropicClient) request(prompt string) (string, map[string]string, anthropicRequest, error) {
+	if c.apiKey == "" {
+		return "", nil, anthropicRequest{}, &missingKeyError{env: "ANTHROPIC_API_KEY"}
+	}
+
+	endpoint := strings.TrimRight(c.baseURL, "/") + "/messages"
+	payload := anthropicRequest{
+		Model:     c.model,
+		MaxTokens: c.maxTokens,
+		Messages:  []message{{Role: "user", Content: prompt}},
+	}
+	headers := map[string]string{
+		"x-api-key":         c.apiKey,
+		"anthropic-version": anthropicVersion,
+	}
+	return endpoint, headers, payload, nil
+}
@@ -0,0 +1,134 @@
+package summary
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestAnthropicStream(t *testing.T) {
+	const plain = `{"model":"claude","content":[{"type":"text","text":"plain answer"}],"stop_reason":"end_turn","usage":{"input_tokens":5,"output_tokens":2}}`
+	start := "event: message_start\ndata: {\"type\":\"message_start\",\"message\":{\"model\":\"claude-x\",\"usage\":{\"input_tokens\":5}}}\n\n"
+	delta := func(text string) string {
+		return "event: content_block_delta\ndata: {\"type\":\"content_block_delta\",\"delta\":{\"type\":\"text_delta\",\"text\":\"" + text + "\"}}\n\n"
+	}
+	end := "event: message_delta\ndata: {\"type\":\"message_delta\",\"delta\":{\"stop_reason\":\"end_turn\"},\"usage\":{\"output_tokens\":2}}\n\n" +
+		"event: message_stop\ndata: {\"type\":\"message_stop\"}\n\n"
+	tests := []struct {
+		name         string
+		streamStatus int
+		stream       string
+		text         string
+		wantErr      string
+	}{
+		{name: "full stream", stream: start + "event: ping\ndata: {\"type\":\"ping\"}\n\n" + delta("Hel") + delta("lo") + end, text: "Hello"},
+		{
+			name: "event split over data lines",
+			stream: start + "event: content_block_delta\ndata: {\"type\":\"content_block_delta\",\ndata: \"delta\":{\"type\":\"text_delta\",\"text\":\"split\"}}\n\n" +
+				end,
+			text: "split",
+		},
+		{name: "cut before message_stop", stream: start + delta("Hel"), wantErr: errStreamCut.Error()},
+		{
+			name:    "error event mid-stream",
+			stream:  start + delta("Hel") + "event: error\ndata: {\"type\":\"error\",\"error\":{\"type\":\"overloaded_error\",\"message\":\"Overloaded\"}}\n\n",
+			wantErr: "Overloaded",
+		},
+		{name: "streaming turned down", streamStatus: http.StatusNotFound, text: "plain answer"},
+	}
+	for _, tt := range tests {
+		server := streamingServer(t, tt.streamStatus, tt.stream, plain)
+		client := newAnthropic(ProviderConfig{BaseURL: server.URL, APIKey: "sk-ant-test", Model: "claude"})
+		var streamed strings.Builder
+		completion, err := client.CompleteStream(context.Background(), "prompt", &streamed)
+		if tt.wantErr != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("%s: err = %v, want %q", tt.name, err, tt.wantErr)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		if completion.Text != tt.text || streamed.String() != tt.text || completion.FinishReason != "end_turn" {
+			t.Errorf("%s: got %q (%s), streamed %q; want %q", tt.name, completion.Text, completion.FinishReason, streamed.String(), tt.text)
+		}
+		if completion.Usage.PromptTokens != 5 || completion.Usage.CompletionTokens != 2 {
+			t.Errorf("%s: usage = %+v, want 5 and 2 tokens", tt.name, completion.Usage)
+		}
+	}
+}
+
+func TestAnthropicRequest(t *testing.T) {
+	server, got := recordingServer(t, http.StatusOK, `{"model":"claude-x","content":[{"type":"thinking","text":"hmm"},{"type":"text","text":"Hel"},{"type":"text","text":"lo"}],"stop_reason":"max_tokens","usage":{"input_tokens":9,"output_tokens":4}}`)
+	client := newAnthropic(ProviderConfig{BaseURL: server.URL + "/v1", APIKey: "sk-ant-test", Model: "claude"})
+	completion, err := client.Complete(context.Background(), "summarize this")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.path != "/v1/messages" {
+		t.Errorf("path = %s, want /v1/messages", got.path)
+	}
+	if key := got.headers.Get("x-api-key"); key != "sk-ant-test" {
+		t.Errorf("x-api-key = %q", key)
+	}
+	if version := got.headers.Get("anthropic-version"); version != anthropicVersion {
+		t.Errorf("anthropic-version = %q, want %s", version, anthropicVersion)
+	}
+	if auth := got.headers.Get("Authorization"); auth != "" {
+		t.Errorf("Authorization = %q, want none", auth)
+	}
+	if got.body["model"] != "claude" || got.body["max_tokens"] != float64(8192) || got.body["stream"] != nil {
+		t.Errorf("body = %v", got.body)
+	}
+	if completion.Text != "Hello" || completion.Model != "claude-x" || completion.FinishReason != "max_tokens" {
+		t.Errorf("completion = %+v, want the text blocks joined", completion)
+	}
+	if completion.Usage.PromptTokens != 9 || completion.Usage.CompletionTokens != 4 {
+		t.Errorf("usage = %+v", completion.Usage)
+	}
+}
+
+func TestAnthropicBaseURL(t *testing.T) {
+	t.Setenv("ANTHROPIC_BASE_URL", "https://proxy.example/v1")
+	if got := newAnthropic(ProviderConfig{}).BaseURL(); got != "https://proxy.example/v1" {
+		t.Errorf("base URL = %s, want the environment's", got)
+	}
+	t.Setenv("ANTHROPIC_BASE_URL", "")
+	if got := newAnthropic(ProviderConfig{}).BaseURL(); got != "https://api.anthropic.com/v1" {
+		t.Errorf("base URL = %s, want the default", got)
+	}
+}
+
+func TestAnthropicMissingKey(t *testing.T) {
+	t.Setenv("ANTHROPIC_API_KEY", "")
+	_, err := newAnthropic(ProviderConfig{}).Complete(context.Background(), "prompt")
+	var keyErr *missingKeyError
+	if !errors.As(err, &keyErr) || keyErr.env != "ANTHROPIC_API_KEY" {
+		t.Errorf("err = %v, want ANTHROPIC_API_KEY reported missing", err)
+	}
+}
+
+func TestAnthropicErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		status  int
+		reply   string
+		wantErr string
+	}{
+		{"overloaded", 529, `{"type":"error","error":{"type":"overloaded_error","message":"Overloaded"}}`, "HTTP 529: Overloaded"},
+		{"bad key", http.StatusUnauthorized, `{"type":"error","error":{"type":"authentication_error","message":"invalid x-api-key"}}`, "HTTP 401 Unauthorized: invalid x-api-key"},
+		{"no text", http.StatusOK, `{"content":[],"stop_reason":"end_turn"}`, "empty response from anthropic"},
+	}
+	for _, tt := range tests {
+		server, _ := recordingServer(t, tt.status, tt.reply)
+		client := newAnthropic(ProviderConfig{BaseURL: server.URL, APIKey: "sk-ant-test", Model: "claude"})
+		_, err := client.Complete(context.Background(), "prompt")
+		if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+			t.Errorf("%s: err = %v, want %q", tt.name, err, tt.wantErr)
+		}
+	}
+}
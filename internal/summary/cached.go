@@ -0,0 +1,101 @@
+package summary
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"time"
+
+	"web-log/internal/cache"
+)
+
+// PromptVersion identifies the prompt templates and the reply format they
+// ask for. Bump it whenever either changes, so cached responses made for
+// the old format are not reused.
+const PromptVersion = "2"
+
+// cachedProvider answers repeated prompts from an on-disk cache.
+type cachedProvider struct {
+	provider Provider
+	store    *cache.Store
+	log      func(string)
+}
+
+// NewCachedProvider wraps provider so that responses are stored in store and
+// reused for the same provider, model list, prompt and PromptVersion. log,
+// when set, is told about cache hits and failed writes.
+func NewCachedProvider(provider Provider, store *cache.Store, log func(string)) Provider {
+	return &cachedProvider{provider: provider, store: store, log: log}
+}
+
+func (c *cachedProvider) Name() string  { return c.provider.Name() }
+func (c *cachedProvider) Model() string { return c.provider.Model() }
+
+func (c *cachedProvider) Complete(ctx context.Context, prompt string) (Completion, error) {
+	return c.complete(ctx, prompt, nil)
+}
+
+func (c *cachedProvider) CompleteStream(ctx context.Context, prompt string, w io.Writer) (Completion, error) {
+	return c.complete(ctx, prompt, w)
+}
+
+func (c *cachedProvider) complete(ctx context.Context, prompt string, w io.Writer) (Completion, error) {
+	key := cache.Key{
+		Provider:        c.provider.Name(),
+		Model:           c.provider.Model(),
+		Prompt:          prompt,
+		TemplateVersion: PromptVersion,
+	}
+	if entry, ok := c.store.Get(key); ok {
+		if usable(entry.Response, entry.FinishReason) {
+			c.logf("%s: using cached response from %s", key.Provider, entry.CreatedAt.Local().Format("2006-01-02 15:04"))
+			return Completion{
+				Text:         entry.Response,
+				Model:        entry.ResponseModel,
+				FinishReason: entry.FinishReason,
+				Usage:        Usage{Cached: true},
+			}, nil
+		}
+		// Left by an earlier version that cached replies unchecked
+		if err := c.store.Delete(key); err != nil {
+			c.logf("cache: %v", err)
+		}
+	}
+
+	completion, err := complete(ctx, c.provider, prompt, w)
+	if err != nil {
+		return completion, err
+	}
+	// A reply that does not parse fails the run; caching it would fail
+	// every run after it too
+	if !usable(completion.Text, completion.FinishReason) {
+		return completion, nil
+	}
+	err = c.store.Put(key, cache.Entry{
+		CreatedAt:     time.Now().UTC(),
+		ResponseModel: completion.Model,
+		FinishReason:  completion.FinishReason,
+		Response:      completion.Text,
+	})
+	if err != nil {
+		// A cache that cannot be written only costs the next run a call
+		c.logf("cache: %v", err)
+	}
+	return completion, nil
+}
+
+// usable reports whether a reply is a complete summary document worth
+// answering the same prompt with again.
+func usable(text, finishReason string) bool {
+	if isTruncated(finishReason) {
+		return false
+	}
+	_, err := parseSections(text)
+	return err == nil
+}
+
+func (c *cachedProvider) logf(format string, args ...any) {
+	if c.log != nil {
+		c.log(fmt.Sprintf(format, args...))
+	}
+}
@@ -0,0 +1,59 @@
+package summary
+
+import (
+	"context"
+	"testing"
+
+	"web-log/internal/cache"
+)
+
+const validReply = `{"sections":[{"name":"Dev","tags":[{"tag":"go","entries":[1]}]}]}`
+
+func TestCachedProviderStoresOnlyValidReplies(t *testing.T) {
+	tests := []struct {
+		name   string
+		reply  scriptedReply
+		cached bool
+	}{
+		{"valid", answer(validReply), true},
+		{"not JSON", answer("Sorry, I cannot help with that."), false},
+		{"no sections", answer(`{"sections":[]}`), false},
+		{"truncated", scriptedReply{completion: Completion{Text: validReply, FinishReason: "length"}}, false},
+	}
+	for _, tt := range tests {
+		inner := &scriptedProvider{model: "m", replies: []scriptedReply{tt.reply}}
+		provider := NewCachedProvider(inner, cache.Open(t.TempDir()), nil)
+		for i := 0; i < 2; i++ {
+			if _, err := provider.Complete(context.Background(), "prompt"); err != nil {
+				t.Fatalf("%s: %v", tt.name, err)
+			}
+		}
+		want := 2
+		if tt.cached {
+			want = 1
+		}
+		if inner.calls != want {
+			t.Errorf("%s: provider called %d times, want %d", tt.name, inner.calls, want)
+		}
+	}
+}
+
+func TestCachedProviderEvictsUnusableEntries(t *testing.T) {
+	store := cache.Open(t.TempDir())
+	inner := &scriptedProvider{model: "m", replies: []scriptedReply{answer(validReply)}}
+	key := cache.Key{Provider: inner.Name(), Model: inner.Model(), Prompt: "prompt", TemplateVersion: PromptVersion}
+	if err := store.Put(key, cache.Entry{Response: "{not json", FinishReason: "stop"}); err != nil {
+		t.Fatal(err)
+	}
+
+	completion, err := NewCachedProvider(inner, store, nil).Complete(context.Background(), "prompt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if inner.calls != 1 || completion.Text != validReply || completion.Usage.Cached {
+		t.Errorf("got %q (cached %v) after %d calls, want a fresh answer", completion.Text, completion.Usage.Cached, inner.calls)
+	}
+	if entry, ok := store.Get(key); !ok || entry.Response != validReply {
+		t.Errorf("cache holds %q, want the fresh answer", entry.Response)
+	}
+}
@@ -0,0 +1,240 @@
+package summary
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// DefaultMaxPromptTokens keeps a prompt well inside the context window of
+// the default model while leaving room for the answer.
+const DefaultMaxPromptTokens = 120000
+
+// EstimateTokens approximates the token count of text without a tokenizer:
+// about four ASCII characters per token, and one token per non-ASCII rune
+// since CJK text and emoji tokenize far less densely.
+func EstimateTokens(text string) int {
+	ascii, other := 0, 0
+	for _, r := range text {
+		if r < utf8.RuneSelf {
+			ascii++
+		} else {
+			other++
+		}
+	}
+	return (ascii+3)/4 + other
+}
+
+// chunk is a run of consecutive days summarized in one call.
+type chunk struct {
+	startDate string
+	endDate   string
+	items     []item
+}
+
+func (c chunk) days() int {
+	start, err1 := time.Parse("2006-01-02", c.startDate)
+	end, err2 := time.Parse("2006-01-02", c.endDate)
+	if err1 != nil || err2 != nil {
+		return 1
+	}
+	return int(end.Sub(start).Hours()/24) + 1
+}
+
+func (c chunk) prompt(index, total int, startDate, endDate string, rules string) string {
+	prompt := promptIntro + fmt.Sprintf(`
+
+This is part %d of %d of the period {periodStart} to {periodEnd}. Summarize only this part; the parts will be merged afterwards, so keep tag names consistent and reference entries exactly.
+
+`, index, total) + rules + `
+Now produce the summary based on the browsing history below.
+
+` + buildActivity(c.items, c.startDate, c.endDate, c.days())
+	prompt = strings.ReplaceAll(prompt, "{periodStart}", startDate)
+	prompt = strings.ReplaceAll(prompt, "{periodEnd}", endDate)
+	return fillPeriod(prompt, c.startDate, c.endDate, c.days())
+}
+
+// splitChunks packs the history into chunks whose prompts fit maxTokens. It
+// prefers whole weeks, falls back to single days, and splits a day that is
+// too large on its own into parts.
+func splitChunks(items []item, maxTokens int, rules string) []chunk {
+	byDay := map[string][]item{}
+	for _, it := range items {
+		day := it.entry.VisitTime.Format("2006-01-02")
+		byDay[day] = append(byDay[day], it)
+	}
+	days := make([]string, 0, len(byDay))
+	for day := range byDay {
+		days = append(days, day)
+	}
+	sort.Strings(days)
+
+	fits := func(c chunk) bool {
+		return EstimateTokens(c.prompt(1, 1, c.startDate, c.endDate, rules)) <= maxTokens
+	}
+	weekOf := func(day string) string {
+		t, _ := time.Parse("2006-01-02", day)
+		year, week := t.ISOWeek()
+		return fmt.Sprintf("%d-%02d", year, week)
+	}
+
+	// Units are whole weeks when they fit, otherwise their days.
+	units := []chunk{}
+	for i := 0; i < len(days); {
+		j := i
+		week := chunk{startDate: days[i]}
+		for ; j < len(days) && weekOf(days[j]) == weekOf(days[i]); j++ {
+			week.endDate = days[j]
+			week.items = append(week.items, byDay[days[j]]...)
+		}
+		if fits(week) {
+			units = append(units, week)
+		} else {
+			for _, day := range days[i:j] {
+				units = append(units, splitDay(chunk{startDate: day, endDate: day, items: byDay[day]}, fits)...)
+			}
+		}
+		i = j
+	}
+
+	// Greedily merge consecutive units while the prompt still fits.
+	chunks := []chunk{}
+	for _, unit := range units {
+		if len(chunks) > 0 {
+			last := chunks[len(chunks)-1]
+			merged := chunk{
+				startDate: last.startDate,
+				endDate:   unit.endDate,
+				items:     append(append([]item{}, last.items...), unit.items...),
+			}
+			if fits(merged) {
+				chunks[len(chunks)-1] = merged
+				continue
+			}
+		}
+		chunks = append(chunks, unit)
+	}
+	return chunks
+}
+
+// splitDay halves a day's entries until every part fits. A single entry is
+// always its own part, however long.
+func splitDay(c chunk, fits func(chunk) bool) []chunk {
+	if len(c.items) <= 1 || fits(c) {
+		return []chunk{c}
+	}
+	sorted := append([]item{}, c.items...)
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].entry.VisitTime.Before(sorted[j].entry.VisitTime)
+	})
+	mid := len(sorted) / 2
+	first := chunk{startDate: c.startDate, endDate: c.endDate, items: sorted[:mid]}
+	second := chunk{startDate: c.startDate, endDate: c.endDate, items: sorted[mid:]}
+	return append(splitDay(first, fits), splitDay(second, fits)...)
+}
+
+// mapReduce summarizes each chunk and merges the partial summaries. Merges
+// that would themselves exceed the budget are done in groups, level by level.
+// Partials are validated and re-encoded before merging, so the merge prompt
+// only ever sees well-formed JSON, but the size rules wait for the final
+// result: a tag can reach them only once its parts are merged.
+func mapReduce(ctx context.Context, provider Provider, chunks []chunk, startDate, endDate string, days int, maxID int, opts Options) ([]Section, error) {
+	partials := make([]string, 0, len(chunks))
+	for i, c := range chunks {
+		opts.progress("summarizing part %d/%d (%s to %s, %d entries)", i+1, len(chunks), c.startDate, c.endDate, len(c.items))
+		completion, err := provider.Complete(ctx, c.prompt(i+1, len(chunks), startDate, endDate, opts.promptRules()))
+		if err != nil {
+			return nil, fmt.Errorf("part %d/%d: %w", i+1, len(chunks), err)
+		}
+		partial, err := reencode(completion.Text, maxID)
+		if err != nil {
+			return nil, fmt.Errorf("part %d/%d: %w", i+1, len(chunks), err)
+		}
+		partials = append(partials, partial)
+	}
+
+	for len(partials) > 1 {
+		groups := groupPartials(partials, opts.maxPromptTokens(), startDate, endDate, days, opts.promptRules())
+		next := make([]string, 0, len(groups))
+		for i, group := range groups {
+			if len(group) == 1 {
+				next = append(next, group[0])
+				continue
+			}
+			opts.progress("merging %d partial summaries (%d/%d)", len(group), i+1, len(groups))
+			// Only the final merge is worth streaming
+			var stream io.Writer
+			if len(groups) == 1 {
+				stream = opts.Stream
+			}
+			completion, err := complete(ctx, provider, mergePrompt(group, startDate, endDate, days, opts.promptRules()), stream)
+			if err != nil {
+				return nil, fmt.Errorf("merge: %w", err)
+			}
+			merged, err := reencode(completion.Text, maxID)
+			if err != nil {
+				return nil, fmt.Errorf("merge: %w", err)
+			}
+			next = append(next, merged)
+		}
+		partials = next
+	}
+	return parseSections(partials[0])
+}
+
+// reencode validates a model reply and returns it as compact JSON, with
+// unknown ids dropped and counts recomputed.
+func reencode(text string, maxID int) (string, error) {
+	sections, err := parseSections(text)
+	if err != nil {
+		return "", err
+	}
+	data, err := json.Marshal(document{Sections: cleanSections(sections, maxID)})
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+// groupPartials packs partial summaries into merge groups that fit the
+// budget, always pairing at least two so every level makes progress.
+func groupPartials(partials []string, maxTokens int, startDate, endDate string, days int, rules string) [][]string {
+	groups := [][]string{}
+	current := []string{}
+	for _, partial := range partials {
+		candidate := append(append([]string{}, current...), partial)
+		if len(current) >= 2 && EstimateTokens(mergePrompt(candidate, startDate, endDate, days, rules)) > maxTokens {
+			groups = append(groups, current)
+			current = []string{partial}
+			continue
+		}
+		current = candidate
+	}
+	return append(groups, current)
+}
+
+func mergePrompt(partials []string, startDate, endDate string, days int, rules string) string {
+	var b strings.Builder
+	b.WriteString(`You are merging partial browsing summaries of consecutive parts of one period into a single structured tag summary for personal journaling.
+
+Merging:
+- Each part is a JSON summary in the format below. Entry ids are shared across parts.
+- Tags about the same topic in different parts become ONE tag whose "entries" is the union of the parts' entries; subtags are merged the same way.
+- Keep the specific details from every part in the merged description, dropping exact repeats.
+- Merge the sites lists without duplicates.
+- Ignore the "count" fields in the parts; counts are recomputed from entries.
+
+`)
+	b.WriteString(rules)
+	b.WriteString("\nNow produce the merged summary from the partial summaries below.\n")
+	for i, partial := range partials {
+		fmt.Fprintf(&b, "\n--- Part %d ---\n%s\n", i+1, partial)
+	}
+	return fillPeriod(b.String(), startDate, endDate, days)
+}
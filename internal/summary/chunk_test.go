@@ -0,0 +1,161 @@
+package summary
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"web-log/internal/history"
+)
+
+func TestEstimateTokens(t *testing.T) {
+	tests := []struct {
+		text string
+		want int
+	}{
+		{"", 0},
+		{"a", 1},
+		{"abcd", 1},
+		{"abcde", 2},
+		{"日本語", 3},
+		{"go 日本", 3},
+		{"👍", 1},
+	}
+	for _, tt := range tests {
+		if got := EstimateTokens(tt.text); got != tt.want {
+			t.Errorf("EstimateTokens(%q) = %d, want %d", tt.text, got, tt.want)
+		}
+	}
+}
+
+// fakeItems makes perDay entries on each of days consecutive days starting
+// Monday 2026-10-05.
+func fakeItems(days, perDay int) []item {
+	start := time.Date(2026, 10, 5, 9, 0, 0, 0, time.UTC)
+	entries := []history.Entry{}
+	for d := 0; d < days; d++ {
+		for i := 0; i < perDay; i++ {
+			entries = append(entries, history.Entry{
+				URL:       fmt.Sprintf("https://example.com/%d/%d", d, i),
+				Title:     fmt.Sprintf("Page %d of day %d", i, d),
+				VisitTime: start.AddDate(0, 0, d).Add(time.Duration(i) * time.Minute),
+				Source:    "chrome",
+			})
+		}
+	}
+	return numberEntries(entries)
+}
+
+func TestSplitChunks(t *testing.T) {
+	rules := Options{}.promptRules()
+	items := fakeItems(10, 4)
+	size := func(c chunk) int { return EstimateTokens(c.prompt(1, 1, c.startDate, c.endDate, rules)) }
+	// Later entries have longer ids, so size the budgets on the largest part
+	largest := func(n int) int {
+		max := 0
+		for i := 0; i < len(items); i += n {
+			day := items[i].entry.VisitTime.Format("2006-01-02")
+			end := items[min(i+n, len(items))-1].entry.VisitTime.Format("2006-01-02")
+			if tokens := size(chunk{startDate: day, endDate: end, items: items[i:min(i+n, len(items))]}); tokens > max {
+				max = tokens
+			}
+		}
+		return max
+	}
+
+	tests := []struct {
+		name      string
+		maxTokens int
+		chunks    int
+	}{
+		{"everything fits", size(chunk{startDate: "2026-10-05", endDate: "2026-10-14", items: items}), 1},
+		{"a week at a time", size(chunk{startDate: "2026-10-05", endDate: "2026-10-11", items: items[:28]}), 2},
+		{"a day at a time", largest(4), 10},
+		{"days split in halves", largest(2), 20},
+		{"single entries that do not fit", 1, 40},
+	}
+	for _, tt := range tests {
+		chunks := splitChunks(items, tt.maxTokens, rules)
+		if len(chunks) != tt.chunks {
+			t.Errorf("%s: got %d chunks, want %d", tt.name, len(chunks), tt.chunks)
+		}
+		next := 1
+		for _, c := range chunks {
+			if len(c.items) > 1 && size(c) > tt.maxTokens {
+				t.Errorf("%s: chunk %s to %s has %d tokens, over %d", tt.name, c.startDate, c.endDate, size(c), tt.maxTokens)
+			}
+			for _, it := range c.items {
+				if it.id != next {
+					t.Errorf("%s: got entry %d, want %d; entries must be covered once, in order", tt.name, it.id, next)
+				}
+				next = it.id + 1
+			}
+		}
+		if next != len(items)+1 {
+			t.Errorf("%s: chunks end at entry %d, want %d", tt.name, next-1, len(items))
+		}
+	}
+}
+
+func TestSplitChunksKeepsWeeksTogether(t *testing.T) {
+	rules := Options{}.promptRules()
+	items := fakeItems(14, 2)
+	week := chunk{startDate: "2026-10-05", endDate: "2026-10-11", items: items[:14]}
+	// Room for a week and a bit must not pull days of the second week in
+	maxTokens := EstimateTokens(week.prompt(1, 1, week.startDate, week.endDate, rules)) + 50
+	chunks := splitChunks(items, maxTokens, rules)
+	if len(chunks) != 2 || chunks[0].endDate != "2026-10-11" || chunks[1].startDate != "2026-10-12" {
+		for _, c := range chunks {
+			t.Logf("%s to %s: %d entries", c.startDate, c.endDate, len(c.items))
+		}
+		t.Errorf("want the two ISO weeks as chunks")
+	}
+}
+
+func TestMapReduceAppliesSizeRulesAfterMerging(t *testing.T) {
+	items := fakeItems(2, 8)
+	chunks := []chunk{
+		{startDate: "2026-10-05", endDate: "2026-10-05", items: items[:8]},
+		{startDate: "2026-10-06", endDate: "2026-10-06", items: items[8:]},
+	}
+	// Each part alone is under every threshold: 6 ai-agents entries with a
+	// 2-entry subtag, and a 2- or 3-entry Reading section.
+	first := `{"sections":[
+		{"name":"Development","tags":[{"tag":"ai-agents","entries":[1,2,3,4,5,6],"subtags":[{"tag":"ralph-loop","entries":[1,2]}]}]},
+		{"name":"Reading","tags":[{"tag":"news","entries":[7,8]}]}]}`
+	second := `{"sections":[
+		{"name":"Development","tags":[{"tag":"ai-agents","entries":[9,10,11,12,13],"subtags":[{"tag":"ralph-loop","entries":[9]}]}]},
+		{"name":"Reading","tags":[{"tag":"news","entries":[14,15,16]}]}]}`
+	merged := `{"sections":[
+		{"name":"Development","tags":[{"tag":"ai-agents","entries":[1,2,3,4,5,6,9,10,11,12,13],"subtags":[{"tag":"ralph-loop","entries":[1,2,9]}]}]},
+		{"name":"Reading","tags":[{"tag":"news","entries":[7,8,14,15,16]}]}]}`
+	provider := &scriptedProvider{model: "m", replies: []scriptedReply{answer(first), answer(second), answer(merged)}}
+
+	sections, err := mapReduce(context.Background(), provider, chunks, "2026-10-05", "2026-10-06", 2, len(items), Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if provider.calls != 3 {
+		t.Fatalf("made %d calls, want two parts and a merge", provider.calls)
+	}
+	mergePrompt := provider.prompts[2]
+	for _, want := range []string{`"ralph-loop"`, `"Reading"`, `"news"`} {
+		if !strings.Contains(mergePrompt, want) {
+			t.Errorf("merge prompt lost %s before merging", want)
+		}
+	}
+	if strings.Contains(mergePrompt, `"Other"`) {
+		t.Error("merge prompt has sections folded into Other")
+	}
+
+	final := normalizeSections(sections, len(items))
+	if len(final) != 2 || final[0].Name != "Development" || final[1].Name != "Reading" {
+		t.Fatalf("sections = %+v, want Development and Reading", final)
+	}
+	agents := final[0].Tags[0]
+	if agents.Count != 11 || len(agents.SubTags) != 1 || agents.SubTags[0].Count != 3 {
+		t.Errorf("ai-agents = %+v, want 11 entries with the 3-entry ralph-loop subtag", agents)
+	}
+}
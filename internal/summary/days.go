@@ -0,0 +1,85 @@
+package summary
+
+import "sort"
+
+// Dates returns the days, as YYYY-MM-DD, that have entries referenced by a
+// tag, in order.
+func (s *Summary) Dates() []string {
+	seen := map[string]bool{}
+	dates := []string{}
+	for _, section := range s.Sections {
+		for _, tag := range section.Tags {
+			for _, id := range tag.Entries {
+				entry, ok := s.Entry(id)
+				if !ok {
+					continue
+				}
+				date := entry.VisitTime.Format("2006-01-02")
+				if !seen[date] {
+					seen[date] = true
+					dates = append(dates, date)
+				}
+			}
+		}
+	}
+	sort.Strings(dates)
+	return dates
+}
+
+// ForDay narrows the summary to the entries visited on date (YYYY-MM-DD).
+// Tags keep their descriptions and sites from the whole period; counts come
+// from the day's entries, and tags and sections left without any are
+// dropped. The subtag rules normalizeSections enforces are applied again to
+// the day's counts. Entries is shared so ids stay valid, and Calls is left
+// out.
+func (s *Summary) ForDay(date string) *Summary {
+	onDay := func(ids []int) []int {
+		kept := []int{}
+		for _, id := range ids {
+			if entry, ok := s.Entry(id); ok && entry.VisitTime.Format("2006-01-02") == date {
+				kept = append(kept, id)
+			}
+		}
+		return kept
+	}
+	var narrow func(tags []Tag, minCount int) []Tag
+	narrow = func(tags []Tag, minCount int) []Tag {
+		result := []Tag{}
+		for _, tag := range tags {
+			tag.Entries = onDay(tag.Entries)
+			tag.Count = len(tag.Entries)
+			if tag.Count < minCount {
+				continue
+			}
+			if tag.Count < minSubTagParent {
+				tag.SubTags = nil
+			} else if tag.SubTags != nil {
+				tag.SubTags = narrow(tag.SubTags, minSubTagEntries)
+			}
+			result = append(result, tag)
+		}
+		sort.SliceStable(result, func(i, j int) bool {
+			if result[i].Count != result[j].Count {
+				return result[i].Count > result[j].Count
+			}
+			return result[i].Name < result[j].Name
+		})
+		return result
+	}
+
+	day := &Summary{StartDate: date, EndDate: date, Days: 1, Entries: s.Entries}
+	for _, section := range s.Sections {
+		tags := narrow(section.Tags, 1)
+		if len(tags) == 0 {
+			continue
+		}
+		day.Sections = append(day.Sections, Section{Name: section.Name, Tags: tags})
+	}
+	sort.SliceStable(day.Sections, func(i, j int) bool {
+		if (day.Sections[i].Name == otherSection) != (day.Sections[j].Name == otherSection) {
+			return day.Sections[j].Name == otherSection
+		}
+		return day.Sections[i].Count() > day.Sections[j].Count()
+	})
+	return day
+}
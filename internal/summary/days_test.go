@@ -0,0 +1,81 @@
+package summary
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"web-log/internal/history"
+)
+
+// twoDays has entries 1-12 on 2026-10-12 and 13-20 on 2026-10-13.
+func twoDays() *Summary {
+	entries := []history.Entry{}
+	for i := 0; i < 20; i++ {
+		day := 12
+		if i >= 12 {
+			day = 13
+		}
+		entries = append(entries, history.Entry{URL: "https://example.com/", VisitTime: time.Date(2026, 10, day, 9, i, 0, 0, time.UTC)})
+	}
+	return &Summary{
+		StartDate: "2026-10-12",
+		EndDate:   "2026-10-13",
+		Days:      2,
+		Entries:   entries,
+		Sections: []Section{
+			{Name: "Development", Tags: []Tag{
+				{Name: "ai-agents", Count: 16, Entries: append(ids(1, 10), ids(13, 18)...), SubTags: []Tag{
+					{Name: "clawdbot", Count: 5, Entries: []int{1, 2, 3, 13, 14}},
+					{Name: "ralphy", Count: 4, Entries: []int{4, 5, 15, 16}},
+				}},
+			}},
+			{Name: "Reading", Tags: []Tag{
+				{Name: "news", Count: 2, Entries: []int{11, 12}},
+			}},
+			{Name: "Other", Tags: []Tag{
+				{Name: "weather", Count: 2, Entries: []int{19, 20}},
+			}},
+		},
+	}
+}
+
+func TestDates(t *testing.T) {
+	if got := twoDays().Dates(); !reflect.DeepEqual(got, []string{"2026-10-12", "2026-10-13"}) {
+		t.Errorf("Dates() = %v", got)
+	}
+}
+
+func TestForDay(t *testing.T) {
+	s := twoDays()
+
+	first := s.ForDay("2026-10-12")
+	if first.StartDate != "2026-10-12" || first.Days != 1 || len(first.Sections) != 2 {
+		t.Fatalf("first day = %+v", first)
+	}
+	agents := first.Sections[0].Tags[0]
+	if agents.Count != 10 {
+		t.Errorf("ai-agents has %d entries on the first day, want 10", agents.Count)
+	}
+	// ralphy has 2 entries that day, under minSubTagEntries
+	if len(agents.SubTags) != 1 || agents.SubTags[0].Name != "clawdbot" || agents.SubTags[0].Count != 3 {
+		t.Errorf("first day subtags = %+v, want clawdbot with 3", agents.SubTags)
+	}
+
+	// ai-agents has 6 entries on the second day, under minSubTagParent
+	second := s.ForDay("2026-10-13")
+	if names := []string{second.Sections[0].Name, second.Sections[1].Name}; !reflect.DeepEqual(names, []string{"Development", "Other"}) {
+		t.Errorf("second day sections = %v, want Reading dropped and Other last", names)
+	}
+	if agents := second.Sections[0].Tags[0]; agents.Count != 6 || agents.SubTags != nil {
+		t.Errorf("second day ai-agents = %d entries with subtags %+v, want 6 and none", agents.Count, agents.SubTags)
+	}
+
+	if empty := s.ForDay("2026-10-14"); len(empty.Sections) != 0 {
+		t.Errorf("day without entries has sections %+v", empty.Sections)
+	}
+	// The period's summary is left alone
+	if len(s.Sections[0].Tags[0].SubTags) != 2 || s.Sections[0].Tags[0].Count != 16 {
+		t.Errorf("ForDay changed the summary: %+v", s.Sections[0].Tags[0])
+	}
+}
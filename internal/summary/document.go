@@ -0,0 +1,297 @@
+package summary
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html"
+	"sort"
+	"strings"
+
+	"web-log/internal/history"
+)
+
+// Rules the prompt used to ask the model to follow, now enforced here.
+const (
+	// minSectionEntries is the smallest section kept on its own.
+	minSectionEntries = 5
+	// minSubTagParent is the smallest tag allowed to have subtags.
+	minSubTagParent = 10
+	// minSubTagEntries drops minor subtags.
+	minSubTagEntries = 3
+	// otherSection collects sections too small to stand alone.
+	otherSection = "Other"
+)
+
+// Summary is a validated tag summary of a period.
+type Summary struct {
+	StartDate string
+	EndDate   string
+	Days      int
+	Sections  []Section
+	// Entries are the entries the model saw; tag entry ids index into it
+	// starting at 1.
+	Entries []history.Entry
+	// Calls are the completions the summary took, in order.
+	Calls []Call
+}
+
+type Section struct {
+	Name string `json:"name"`
+	Tags []Tag  `json:"tags"`
+}
+
+type Tag struct {
+	Name        string   `json:"tag"`
+	Count       int      `json:"count"`
+	Description string   `json:"description"`
+	Sites       []string `json:"sites"`
+	Entries     []int    `json:"entries"`
+	SubTags     []Tag    `json:"subtags,omitempty"`
+}
+
+// document is the JSON object the model replies with.
+type document struct {
+	Sections []Section `json:"sections"`
+}
+
+// Entry returns the entry behind an id from Tag.Entries.
+func (s *Summary) Entry(id int) (history.Entry, bool) {
+	if id < 1 || id > len(s.Entries) {
+		return history.Entry{}, false
+	}
+	return s.Entries[id-1], true
+}
+
+// Count is the number of distinct entries referenced by the section.
+func (s Section) Count() int {
+	seen := map[int]bool{}
+	for _, tag := range s.Tags {
+		for _, id := range tag.Entries {
+			seen[id] = true
+		}
+	}
+	return len(seen)
+}
+
+// parseSections extracts the JSON document from a model reply, tolerating
+// code fences and chatter around the object.
+func parseSections(text string) ([]Section, error) {
+	start := strings.Index(text, "{")
+	end := strings.LastIndex(text, "}")
+	if start == -1 || end < start {
+		return nil, errors.New("model reply contains no JSON summary")
+	}
+	var doc document
+	if err := json.Unmarshal([]byte(text[start:end+1]), &doc); err != nil {
+		return nil, fmt.Errorf("model reply is not a valid JSON summary: %w", err)
+	}
+	if len(doc.Sections) == 0 {
+		return nil, errors.New("model reply has no sections")
+	}
+	return doc.Sections, nil
+}
+
+// normalizeSections cleans up what the model returned and enforces the
+// summary rules: small subtags and subtags of small tags are removed,
+// sections under minSectionEntries are folded into "Other", and everything
+// is ordered by descending count. It is only meant for the final summary;
+// partial summaries of a chunked run go through cleanSections alone.
+func normalizeSections(sections []Section, maxID int) []Section {
+	result := cleanSections(sections, maxID)
+
+	// Fold small sections into Other, unless that would leave nothing else
+	kept := []Section{}
+	other := Section{Name: otherSection}
+	for _, section := range result {
+		if section.Count() < minSectionEntries || section.Name == otherSection {
+			other.Tags = append(other.Tags, section.Tags...)
+			continue
+		}
+		kept = append(kept, section)
+	}
+	other.Tags = mergeTags(other.Tags)
+	switch {
+	case len(other.Tags) == 0:
+	case len(kept) == 0 || other.Count() >= minSectionEntries:
+		kept = append(kept, other)
+	default:
+		// Other is still too small to stand alone: give it to the largest
+		largest := 0
+		for i := range kept {
+			if kept[i].Count() > kept[largest].Count() {
+				largest = i
+			}
+		}
+		kept[largest].Tags = mergeTags(append(kept[largest].Tags, other.Tags...))
+	}
+
+	for i := range kept {
+		pruneSubTags(kept[i].Tags)
+	}
+	sort.SliceStable(kept, func(i, j int) bool {
+		if (kept[i].Name == otherSection) != (kept[j].Name == otherSection) {
+			return kept[j].Name == otherSection
+		}
+		return kept[i].Count() > kept[j].Count()
+	})
+	return kept
+}
+
+// cleanSections tidies names, drops unknown entry ids, recomputes counts
+// from the referenced entries and merges sections and tags that share a
+// name. Unlike normalizeSections it applies none of the size rules, so a
+// tag or section that is small in one part of a chunked run survives until
+// the parts are merged.
+func cleanSections(sections []Section, maxID int) []Section {
+	byName := map[string]int{}
+	result := []Section{}
+	for _, section := range sections {
+		name := strings.TrimSpace(strings.Trim(section.Name, "*#"))
+		if name == "" {
+			name = otherSection
+		}
+		tags := cleanTags(section.Tags, maxID, true)
+		if len(tags) == 0 {
+			continue
+		}
+		if i, ok := byName[strings.ToLower(name)]; ok {
+			result[i].Tags = mergeTags(append(result[i].Tags, tags...))
+			continue
+		}
+		byName[strings.ToLower(name)] = len(result)
+		result = append(result, Section{Name: name, Tags: tags})
+	}
+	return result
+}
+
+func cleanTags(tags []Tag, maxID int, allowSubTags bool) []Tag {
+	result := make([]Tag, 0, len(tags))
+	for _, tag := range tags {
+		tag.Name = normalizeTagName(tag.Name)
+		if tag.Name == "" {
+			continue
+		}
+		tag.Description = strings.TrimSpace(html.UnescapeString(tag.Description))
+		tag.Sites = uniqueStrings(tag.Sites)
+		tag.Entries = validIDs(tag.Entries, maxID)
+
+		if allowSubTags {
+			tag.SubTags = cleanTags(tag.SubTags, maxID, false)
+			for _, sub := range tag.SubTags {
+				tag.Entries = validIDs(append(tag.Entries, sub.Entries...), maxID)
+			}
+		} else {
+			tag.SubTags = nil
+		}
+		tag.Count = len(tag.Entries)
+		if tag.Count == 0 {
+			continue
+		}
+		result = append(result, tag)
+	}
+	return mergeTags(result)
+}
+
+// pruneSubTags drops the subtags of tags under minSubTagParent and subtags
+// under minSubTagEntries, and lists sites shared with the parent on the
+// parent only.
+func pruneSubTags(tags []Tag) {
+	for i := range tags {
+		tag := &tags[i]
+		if tag.Count < minSubTagParent {
+			tag.SubTags = nil
+			continue
+		}
+		parentSites := map[string]bool{}
+		for _, site := range tag.Sites {
+			parentSites[site] = true
+		}
+		subTags := []Tag{}
+		for _, sub := range tag.SubTags {
+			if sub.Count < minSubTagEntries {
+				continue
+			}
+			sites := []string{}
+			for _, site := range sub.Sites {
+				if !parentSites[site] {
+					sites = append(sites, site)
+				}
+			}
+			sub.Sites = sites
+			subTags = append(subTags, sub)
+		}
+		tag.SubTags = subTags
+	}
+}
+
+// mergeTags combines tags with the same name and orders them by descending
+// count, then name.
+func mergeTags(tags []Tag) []Tag {
+	byName := map[string]int{}
+	result := []Tag{}
+	for _, tag := range tags {
+		i, ok := byName[tag.Name]
+		if !ok {
+			byName[tag.Name] = len(result)
+			result = append(result, tag)
+			continue
+		}
+		existing := &result[i]
+		if tag.Description != "" && !strings.Contains(existing.Description, tag.Description) {
+			if existing.Description == "" {
+				existing.Description = tag.Description
+			} else {
+				existing.Description += "; " + tag.Description
+			}
+		}
+		existing.Sites = uniqueStrings(append(existing.Sites, tag.Sites...))
+		existing.Entries = validIDs(append(existing.Entries, tag.Entries...), 0)
+		existing.SubTags = mergeTags(append(existing.SubTags, tag.SubTags...))
+		existing.Count = len(existing.Entries)
+	}
+	sort.SliceStable(result, func(i, j int) bool {
+		if result[i].Count != result[j].Count {
+			return result[i].Count > result[j].Count
+		}
+		return result[i].Name < result[j].Name
+	})
+	return result
+}
+
+// normalizeTagName turns "#AI Agents" into "ai-agents".
+func normalizeTagName(name string) string {
+	name = strings.ToLower(strings.TrimSpace(name))
+	name = strings.TrimLeft(name, "#")
+	return strings.Join(strings.Fields(name), "-")
+}
+
+// validIDs returns the distinct ids in 1..maxID in ascending order. A maxID of
+// 0 skips the range check for ids that were validated already.
+func validIDs(ids []int, maxID int) []int {
+	seen := map[int]bool{}
+	result := []int{}
+	for _, id := range ids {
+		if id < 1 || (maxID > 0 && id > maxID) || seen[id] {
+			continue
+		}
+		seen[id] = true
+		result = append(result, id)
+	}
+	sort.Ints(result)
+	return result
+}
+
+func uniqueStrings(values []string) []string {
+	seen := map[string]bool{}
+	result := []string{}
+	for _, value := range values {
+		value = strings.TrimSpace(value)
+		if value == "" || seen[strings.ToLower(value)] {
+			continue
+		}
+		seen[strings.ToLower(value)] = true
+		result = append(result, value)
+	}
+	return result
+}
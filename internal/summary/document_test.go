@@ -0,0 +1,129 @@
+package summary
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func ids(from, to int) []int {
+	result := []int{}
+	for id := from; id <= to; id++ {
+		result = append(result, id)
+	}
+	return result
+}
+
+func TestParseSections(t *testing.T) {
+	tests := []struct {
+		name    string
+		text    string
+		tags    int
+		wantErr string
+	}{
+		{"bare object", `{"sections":[{"name":"Dev","tags":[{"tag":"go","entries":[1]}]}]}`, 1, ""},
+		{"code fence", "```json\n{\"sections\":[{\"name\":\"Dev\",\"tags\":[{\"tag\":\"go\"},{\"tag\":\"rust\"}]}]}\n```", 2, ""},
+		{"chatter around it", "Here you go:\n{\"sections\":[{\"name\":\"Dev\",\"tags\":[]}]}\nHope this helps!", 0, ""},
+		{"no object", "I could not summarize this.", 0, "contains no JSON"},
+		{"broken JSON", `{"sections":[{"name":"Dev",}]}`, 0, "not a valid JSON"},
+		{"no sections", `{"sections":[]}`, 0, "no sections"},
+		{"truncated", `{"sections":[{"name":"Dev","tags":[{"tag":"go"`, 0, "contains no JSON"},
+	}
+	for _, tt := range tests {
+		sections, err := parseSections(tt.text)
+		if tt.wantErr != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("%s: err = %v, want %q", tt.name, err, tt.wantErr)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		if len(sections) != 1 || len(sections[0].Tags) != tt.tags {
+			t.Errorf("%s: got %+v, want one section with %d tags", tt.name, sections, tt.tags)
+		}
+	}
+}
+
+func TestNormalizeSections(t *testing.T) {
+	sections := []Section{
+		{Name: "**Development**", Tags: []Tag{
+			{Name: "#AI Agents", Count: 99, Description: "agents &amp; tools", Sites: []string{"x.com", " x.com ", "github.com"}, Entries: ids(1, 8), SubTags: []Tag{
+				{Name: "clawdbot", Entries: []int{9, 10, 11}, Sites: []string{"x.com", "clawd.bot"}},
+				{Name: "minor", Entries: []int{12, 13}},
+			}},
+			{Name: "go", Entries: []int{20, 21, 0, 500, 21}},
+		}},
+		{Name: "development", Tags: []Tag{
+			{Name: "ai-agents", Description: "more agents", Entries: []int{14}},
+		}},
+		{Name: "Shopping", Tags: []Tag{
+			{Name: "headphones", Entries: ids(30, 35), SubTags: []Tag{{Name: "sony", Entries: []int{30, 31, 32}}}},
+		}},
+		{Name: "Tiny", Tags: []Tag{{Name: "weather", Entries: []int{40}}}},
+		{Name: "Empty", Tags: []Tag{{Name: "nothing", Entries: []int{999}}}},
+	}
+	got := normalizeSections(sections, 100)
+
+	names := []string{}
+	for _, section := range got {
+		names = append(names, section.Name)
+	}
+	// Tiny has one entry, and Other (Tiny's) would be too small, so it goes
+	// to the largest section; Empty references no valid entries.
+	if want := []string{"Development", "Shopping"}; !reflect.DeepEqual(names, want) {
+		t.Fatalf("sections = %v, want %v", names, want)
+	}
+
+	dev := got[0]
+	agents := dev.Tags[0]
+	if agents.Name != "ai-agents" || agents.Count != 14 {
+		t.Errorf("ai-agents = %q with %d entries, want the merged tag with 14", agents.Name, agents.Count)
+	}
+	if agents.Description != "agents & tools; more agents" {
+		t.Errorf("description = %q", agents.Description)
+	}
+	if !reflect.DeepEqual(agents.Sites, []string{"x.com", "github.com"}) {
+		t.Errorf("sites = %v", agents.Sites)
+	}
+	if len(agents.SubTags) != 1 || agents.SubTags[0].Name != "clawdbot" {
+		t.Fatalf("subtags = %+v, want only clawdbot", agents.SubTags)
+	}
+	if !reflect.DeepEqual(agents.SubTags[0].Sites, []string{"clawd.bot"}) {
+		t.Errorf("subtag sites = %v, want the parent's sites left out", agents.SubTags[0].Sites)
+	}
+	if tags := []string{dev.Tags[1].Name, dev.Tags[2].Name}; !reflect.DeepEqual(tags, []string{"go", "weather"}) {
+		t.Errorf("other tags = %v, want go then weather by count", tags)
+	}
+	if !reflect.DeepEqual(dev.Tags[1].Entries, []int{20, 21}) {
+		t.Errorf("go entries = %v, want unknown and repeated ids dropped", dev.Tags[1].Entries)
+	}
+
+	// Subtags need a parent of at least minSubTagParent entries
+	if shopping := got[1]; shopping.Tags[0].SubTags != nil {
+		t.Errorf("headphones (%d entries) kept subtags %+v", shopping.Tags[0].Count, shopping.Tags[0].SubTags)
+	}
+}
+
+func TestNormalizeSectionsOther(t *testing.T) {
+	sections := []Section{
+		{Name: "Other", Tags: []Tag{{Name: "misc", Entries: ids(1, 3)}}},
+		{Name: "Reading", Tags: []Tag{{Name: "news", Entries: ids(10, 11)}}},
+		{Name: "Work", Tags: []Tag{{Name: "jira", Entries: ids(20, 25)}}},
+	}
+	got := normalizeSections(sections, 100)
+	if len(got) != 2 || got[0].Name != "Work" || got[1].Name != "Other" {
+		t.Fatalf("got %+v, want Work then Other", got)
+	}
+	if got[1].Count() != 5 {
+		t.Errorf("Other has %d entries, want 5 from Other and Reading", got[1].Count())
+	}
+
+	// With nothing large enough, everything ends up in Other
+	got = normalizeSections([]Section{{Name: "Reading", Tags: []Tag{{Name: "news", Entries: []int{1}}}}}, 100)
+	if len(got) != 1 || got[0].Name != "Other" {
+		t.Errorf("got %+v, want a single Other section", got)
+	}
+}
@@ -0,0 +1,227 @@
+package summary
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// requestTimeout bounds a single provider call; retries get their own.
+	requestTimeout = 180 * time.Second
+	// streamIdleTimeout bounds the gap between two streamed events.
+	streamIdleTimeout = 60 * time.Second
+)
+
+// HTTPError is a non-2xx reply from a provider.
+type HTTPError struct {
+	StatusCode int
+	Message    string
+	// RetryAfter is the delay the server asked for, zero when it gave none.
+	RetryAfter time.Duration
+}
+
+func (e *HTTPError) Error() string {
+	msg := fmt.Sprintf("HTTP %d", e.StatusCode)
+	// Anthropic's 529 has no standard text
+	if text := http.StatusText(e.StatusCode); text != "" {
+		msg += " " + text
+	}
+	if e.Message != "" {
+		msg += ": " + e.Message
+	}
+	return msg
+}
+
+// Temporary reports whether the same request may succeed later: rate limits,
+// timeouts and server errors (including Anthropic's 529 "overloaded").
+func (e *HTTPError) Temporary() bool {
+	return e.StatusCode == http.StatusTooManyRequests || e.StatusCode == http.StatusRequestTimeout || e.StatusCode >= 500
+}
+
+// missingKeyError is returned before any request when a provider needs an
+// API key that is not configured.
+type missingKeyError struct {
+	env string
+}
+
+func (e *missingKeyError) Error() string {
+	return e.env + " is not set"
+}
+
+// postJSON sends payload to endpoint and decodes a 2xx reply into out. Other
+// statuses become an *HTTPError carrying the API's error message, if any.
+func postJSON(ctx context.Context, endpoint string, headers map[string]string, payload any, out any) error {
+	resp, err := post(ctx, &http.Client{Timeout: requestTimeout}, endpoint, headers, payload)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("decoding response: %w", err)
+	}
+	return nil
+}
+
+// errStreamDone is returned by a postStream callback for the event that ends
+// the reply, such as OpenAI's [DONE] or Anthropic's message_stop.
+var errStreamDone = errors.New("stream done")
+
+// errStreamCut means the connection closed before the event that ends the
+// reply, so the answer may be missing its end.
+var errStreamCut = errors.New("stream ended before the reply was complete")
+
+// postStream sends payload to endpoint and hands every server-sent event of
+// the reply to onEvent, until onEvent returns errStreamDone. A stream that
+// ends before that fails with errStreamCut. Instead of an overall deadline,
+// the stream fails when the server goes quiet for streamIdleTimeout.
+func postStream(ctx context.Context, endpoint string, headers map[string]string, payload any, onEvent func(event, data string) error) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	idle := time.AfterFunc(streamIdleTimeout, cancel)
+	defer idle.Stop()
+
+	headers["Accept"] = "text/event-stream"
+	resp, err := post(ctx, &http.Client{}, endpoint, headers, payload)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	reader := bufio.NewReader(resp.Body)
+	event, data := "", []string{}
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil && (err != io.EOF || line == "") {
+			if err == io.EOF {
+				if len(data) > 0 {
+					if err := onEvent(event, strings.Join(data, "\n")); err != nil {
+						return ignoreDone(err)
+					}
+				}
+				return errStreamCut
+			}
+			if ctx.Err() != nil && !idle.Stop() {
+				return fmt.Errorf("stream stalled for %s", streamIdleTimeout)
+			}
+			return err
+		}
+		idle.Reset(streamIdleTimeout)
+
+		line = strings.TrimRight(line, "\r\n")
+		switch {
+		case line == "":
+			// A blank line ends the event
+			if len(data) > 0 {
+				if err := onEvent(event, strings.Join(data, "\n")); err != nil {
+					return ignoreDone(err)
+				}
+			}
+			event, data = "", data[:0]
+		case strings.HasPrefix(line, ":"):
+			// Comment, e.g. OpenRouter's keep-alive ": OPENROUTER PROCESSING"
+		case strings.HasPrefix(line, "event:"):
+			event = strings.TrimSpace(strings.TrimPrefix(line, "event:"))
+		case strings.HasPrefix(line, "data:"):
+			data = append(data, strings.TrimPrefix(strings.TrimPrefix(line, "data:"), " "))
+		}
+	}
+}
+
+// streamRejected reports whether err is a server turning down a streaming
+// request, as servers without streaming support do with a 4xx. The same
+// request without streaming may well succeed.
+func streamRejected(err error) bool {
+	var httpErr *HTTPError
+	if !errors.As(err, &httpErr) {
+		return false
+	}
+	switch httpErr.StatusCode {
+	case http.StatusBadRequest, http.StatusNotFound, http.StatusMethodNotAllowed, http.StatusNotAcceptable, http.StatusUnsupportedMediaType, http.StatusUnprocessableEntity:
+		return true
+	}
+	return false
+}
+
+func ignoreDone(err error) error {
+	if errors.Is(err, errStreamDone) {
+		return nil
+	}
+	return err
+}
+
+func post(ctx context.Context, client *http.Client, endpoint string, headers map[string]string, payload any) (*http.Response, error) {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, "POST", endpoint, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for key, value := range headers {
+		req.Header.Set(key, value)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		defer resp.Body.Close()
+		data, _ := io.ReadAll(io.LimitReader(resp.Body, 64<<10))
+		return nil, &HTTPError{
+			StatusCode: resp.StatusCode,
+			Message:    errorMessage(data),
+			RetryAfter: parseRetryAfter(resp.Header.Get("Retry-After"), time.Now()),
+		}
+	}
+	return resp, nil
+}
+
+// errorMessage extracts the message from OpenAI- and Anthropic-style error
+// bodies ({"error": {"message": ...}}), falling back to the raw text.
+func errorMessage(data []byte) string {
+	var parsed struct {
+		Error *struct {
+			Message string `json:"message"`
+		} `json:"error"`
+	}
+	if json.Unmarshal(data, &parsed) == nil && parsed.Error != nil && parsed.Error.Message != "" {
+		return parsed.Error.Message
+	}
+	text := strings.Join(strings.Fields(string(data)), " ")
+	if len(text) > 200 {
+		text = text[:200] + "..."
+	}
+	return text
+}
+
+// parseRetryAfter reads a Retry-After header in either of its forms: a
+// number of seconds or an HTTP date.
+func parseRetryAfter(value string, now time.Time) time.Duration {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(value); err == nil {
+		if seconds < 0 {
+			return 0
+		}
+		return time.Duration(seconds) * time.Second
+	}
+	if date, err := http.ParseTime(value); err == nil && date.After(now) {
+		return date.Sub(now)
+	}
+	return 0
+}
@@ -0,0 +1,97 @@
+package summary
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+// sseServer replies to every request with body as a server-sent event
+// stream.
+func sseServer(t *testing.T, body string) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		_, _ = io.WriteString(w, body)
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestPostStream(t *testing.T) {
+	tests := []struct {
+		name    string
+		body    string
+		events  []string
+		wantErr error
+	}{
+		{
+			name:   "events until done",
+			body:   "data: a\n\nevent: delta\ndata: b\n\ndata: stop\n\ndata: ignored\n\n",
+			events: []string{":a", "delta:b", ":stop"},
+		},
+		{
+			name:   "multi-line data and comments",
+			body:   ": keep-alive\n\ndata: {\"x\":\ndata: 1}\n\ndata: stop\n\n",
+			events: []string{":{\"x\":\n1}", ":stop"},
+		},
+		{
+			name:   "CRLF line endings and no space after the colon",
+			body:   "data:a\r\n\r\ndata: stop\r\n\r\n",
+			events: []string{":a", ":stop"},
+		},
+		{
+			name:   "last event without a blank line",
+			body:   "data: a\n\ndata: stop",
+			events: []string{":a", ":stop"},
+		},
+		{
+			name:    "cut before the end",
+			body:    "data: a\n\ndata: b\n\n",
+			events:  []string{":a", ":b"},
+			wantErr: errStreamCut,
+		},
+		{
+			name:    "empty reply",
+			body:    "",
+			wantErr: errStreamCut,
+		},
+	}
+	for _, tt := range tests {
+		server := sseServer(t, tt.body)
+		events := []string{}
+		err := postStream(context.Background(), server.URL, map[string]string{}, struct{}{}, func(event, data string) error {
+			events = append(events, event+":"+data)
+			if data == "stop" {
+				return errStreamDone
+			}
+			return nil
+		})
+		if !errors.Is(err, tt.wantErr) {
+			t.Errorf("%s: err = %v, want %v", tt.name, err, tt.wantErr)
+		}
+		if len(events) == 0 {
+			events = nil
+		}
+		if !reflect.DeepEqual(events, tt.events) {
+			t.Errorf("%s: events = %q, want %q", tt.name, events, tt.events)
+		}
+	}
+}
+
+func TestPostStreamCallbackError(t *testing.T) {
+	server := sseServer(t, "data: a\n\ndata: b\n\n")
+	calls := 0
+	failed := errors.New("bad event")
+	err := postStream(context.Background(), server.URL, map[string]string{}, struct{}{}, func(event, data string) error {
+		calls++
+		return failed
+	})
+	if !errors.Is(err, failed) || calls != 1 {
+		t.Errorf("err = %v after %d events, want the callback's error after the first", err, calls)
+	}
+}
@@ -0,0 +1,39 @@
+package summary
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Markdown renders the summary in the journal format:
+//
+//	# Browsing Summary - 2026-01-19 to 2026-01-22 (3 days)
+//
+//	**Development**
+//	#ai-agents (48) explored various AI agents and tools
+//	  - #clawdbot (21) researched personal AI assistant [x.com/clawdbot]
+func (s *Summary) Markdown() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "# Browsing Summary - %s to %s (%d days)\n", s.StartDate, s.EndDate, s.Days)
+	for _, section := range s.Sections {
+		fmt.Fprintf(&b, "\n**%s**\n", section.Name)
+		for _, tag := range section.Tags {
+			b.WriteString(markdownTagLine(tag) + "\n")
+			for _, sub := range tag.SubTags {
+				b.WriteString("  - " + markdownTagLine(sub) + "\n")
+			}
+		}
+	}
+	return strings.TrimRight(b.String(), "\n")
+}
+
+func markdownTagLine(tag Tag) string {
+	line := fmt.Sprintf("#%s (%d)", tag.Name, tag.Count)
+	if tag.Description != "" {
+		line += " " + tag.Description
+	}
+	if len(tag.Sites) > 0 {
+		line += " [" + strings.Join(tag.Sites, ", ") + "]"
+	}
+	return line
+}
@@ -0,0 +1,15 @@
+package summary
+
+import "os"
+
+// newOllama targets a local OpenAI-compatible server. Ollama serves one at
+// http://localhost:11434/v1; point OLLAMA_BASE_URL at llama.cpp's server
+// (http://localhost:8080/v1) or any other stand-in. No API key is needed.
+func newOllama(cfg ProviderConfig) *chatClient {
+	return &chatClient{
+		name:    "ollama",
+		baseURL: firstNonEmpty(cfg.BaseURL, os.Getenv("OLLAMA_BASE_URL"), "http://localhost:11434/v1"),
+		apiKey:  firstNonEmpty(cfg.APIKey, os.Getenv("OLLAMA_API_KEY")),
+		model:   firstNonEmpty(cfg.Model, os.Getenv("OLLAMA_MODEL"), "llama3.1"),
+	}
+}
@@ -0,0 +1,44 @@
+package summary
+
+import (
+	"context"
+	"net/http"
+	"testing"
+)
+
+func TestOllama(t *testing.T) {
+	t.Setenv("OLLAMA_BASE_URL", "")
+	t.Setenv("OLLAMA_API_KEY", "")
+	t.Setenv("OLLAMA_MODEL", "")
+	client := newOllama(ProviderConfig{})
+	if client.BaseURL() != "http://localhost:11434/v1" || client.Model() != "llama3.1" {
+		t.Errorf("defaults = %s, %s", client.BaseURL(), client.Model())
+	}
+
+	server, got := recordingServer(t, http.StatusOK, `{"choices":[{"message":{"content":"answer"},"finish_reason":"stop"}]}`)
+	t.Setenv("OLLAMA_BASE_URL", server.URL+"/v1")
+	completion, err := newOllama(ProviderConfig{Model: "qwen3"}).Complete(context.Background(), "prompt")
+	if err != nil {
+		t.Fatalf("no API key needed: %v", err)
+	}
+	if got.path != "/v1/chat/completions" || got.body["model"] != "qwen3" {
+		t.Errorf("request = %s %v", got.path, got.body)
+	}
+	if auth := got.headers.Get("Authorization"); auth != "" {
+		t.Errorf("Authorization = %q, want none without a key", auth)
+	}
+	if _, ok := got.body["max_tokens"]; ok {
+		t.Errorf("max_tokens sent: %v, want the server's default", got.body["max_tokens"])
+	}
+	if completion.Text != "answer" {
+		t.Errorf("text = %q", completion.Text)
+	}
+
+	t.Setenv("OLLAMA_API_KEY", "local-secret")
+	if _, err := newOllama(ProviderConfig{}).Complete(context.Background(), "prompt"); err != nil {
+		t.Fatal(err)
+	}
+	if auth := got.headers.Get("Authorization"); auth != "Bearer local-secret" {
+		t.Errorf("Authorization = %q, want the optional key", auth)
+	}
+}
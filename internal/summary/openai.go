@@ -0,0 +1,223 @@
+package summary
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// chatClient talks to any OpenAI-compatible /chat/completions endpoint:
+// OpenAI itself, OpenRouter, and local servers such as Ollama or llama.cpp.
+type chatClient struct {
+	name      string
+	baseURL   string
+	apiKey    string
+	apiKeyEnv string
+	model     string
+	maxTokens int
+	// reportsCost is set for OpenRouter, which returns the cost of a call
+	// when asked to.
+	reportsCost bool
+}
+
+type chatRequest struct {
+	Model       string    `json:"model"`
+	Messages    []message `json:"messages"`
+	MaxTokens   int       `json:"max_tokens,omitempty"`
+	Temperature float64   `json:"temperature"`
+	Stream      bool      `json:"stream,omitempty"`
+	// StreamOptions asks for a final chunk carrying the usage.
+	StreamOptions *streamOptions `json:"stream_options,omitempty"`
+	// Usage asks OpenRouter to include the cost in the usage block.
+	Usage *usageOptions `json:"usage,omitempty"`
+}
+
+type streamOptions struct {
+	IncludeUsage bool `json:"include_usage"`
+}
+
+type usageOptions struct {
+	Include bool `json:"include"`
+}
+
+// chatUsage is the usage block of OpenAI-compatible replies. OpenRouter adds
+// the cost in credits (USD).
+type chatUsage struct {
+	PromptTokens     int      `json:"prompt_tokens"`
+	CompletionTokens int      `json:"completion_tokens"`
+	Cost             *float64 `json:"cost"`
+}
+
+func (u *chatUsage) usage() Usage {
+	if u == nil {
+		return Usage{}
+	}
+	return Usage{PromptTokens: u.PromptTokens, CompletionTokens: u.CompletionTokens, Cost: u.Cost}
+}
+
+type message struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+type chatResponse struct {
+	Model   string `json:"model"`
+	Choices []struct {
+		Message struct {
+			Content string `json:"content"`
+		} `json:"message"`
+		FinishReason string `json:"finish_reason"`
+	} `json:"choices"`
+	Usage *chatUsage `json:"usage"`
+	Error *struct {
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+// chatStreamChunk is one event of a streamed chat completion.
+type chatStreamChunk struct {
+	Model   string `json:"model"`
+	Choices []struct {
+		Delta struct {
+			Content string `json:"content"`
+		} `json:"delta"`
+		FinishReason string `json:"finish_reason"`
+	} `json:"choices"`
+	Usage *chatUsage `json:"usage"`
+	Error *struct {
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+func newOpenAI(cfg ProviderConfig) *chatClient {
+	return &chatClient{
+		name:      "openai",
+		baseURL:   firstNonEmpty(cfg.BaseURL, os.Getenv("OPENAI_BASE_URL"), "https://api.openai.com/v1"),
+		apiKey:    firstNonEmpty(cfg.APIKey, os.Getenv("OPENAI_API_KEY")),
+		apiKeyEnv: "OPENAI_API_KEY",
+		model:     firstNonEmpty(cfg.Model, os.Getenv("OPENAI_MODEL"), "gpt-4o-mini"),
+	}
+}
+
+func (c *chatClient) Name() string  { return c.name }
+func (c *chatClient) Model() string { return c.model }
+
+// BaseURL is the API root requests go to.
+func (c *chatClient) BaseURL() string { return c.baseURL }
+
+func (c *chatClient) Complete(ctx context.Context, prompt string) (Completion, error) {
+	endpoint, headers, payload, err := c.request(prompt)
+	if err != nil {
+		return Completion{}, err
+	}
+
+	var parsed chatResponse
+	if err := postJSON(ctx, endpoint, headers, payload, &parsed); err != nil {
+		return Completion{}, err
+	}
+	if parsed.Error != nil {
+		return Completion{}, fmt.Errorf("API error: %s", parsed.Error.Message)
+	}
+	used := Completion{Model: firstNonEmpty(parsed.Model, c.model), Usage: parsed.Usage.usage()}
+	if len(parsed.Choices) == 0 {
+		return used, fmt.Errorf("empty response from %s", c.name)
+	}
+	text := strings.TrimSpace(parsed.Choices[0].Message.Content)
+	if text == "" {
+		return used, fmt.Errorf("empty response from %s", c.name)
+	}
+	return Completion{
+		Text:         text,
+		Model:        firstNonEmpty(parsed.Model, c.model),
+		FinishReason: parsed.Choices[0].FinishReason,
+		Usage:        parsed.Usage.usage(),
+	}, nil
+}
+
+// CompleteStream asks for a server-sent event stream and copies each content
+// delta to w as it arrives. A server that turns the streaming request down
+// is asked again without it.
+func (c *chatClient) CompleteStream(ctx context.Context, prompt string, w io.Writer) (Completion, error) {
+	endpoint, headers, payload, err := c.request(prompt)
+	if err != nil {
+		return Completion{}, err
+	}
+	payload.Stream = true
+	payload.StreamOptions = &streamOptions{IncludeUsage: true}
+
+	var text strings.Builder
+	completion := Completion{Model: c.model}
+	err = postStream(ctx, endpoint, headers, payload, func(_ string, data string) error {
+		if data == "[DONE]" {
+			return errStreamDone
+		}
+		var chunk chatStreamChunk
+		if err := json.Unmarshal([]byte(data), &chunk); err != nil {
+			return fmt.Errorf("decoding stream: %w", err)
+		}
+		if chunk.Error != nil {
+			return fmt.Errorf("API error: %s", chunk.Error.Message)
+		}
+		if chunk.Model != "" {
+			completion.Model = chunk.Model
+		}
+		if chunk.Usage != nil {
+			completion.Usage = chunk.Usage.usage()
+		}
+		for _, choice := range chunk.Choices {
+			if choice.Delta.Content != "" {
+				text.WriteString(choice.Delta.Content)
+				if _, err := io.WriteString(w, choice.Delta.Content); err != nil {
+					return err
+				}
+			}
+			if choice.FinishReason != "" {
+				completion.FinishReason = choice.FinishReason
+			}
+		}
+		return nil
+	})
+	// Some compatible servers close the stream after the finish reason
+	// without sending [DONE]
+	if errors.Is(err, errStreamCut) && completion.FinishReason != "" {
+		err = nil
+	}
+	if streamRejected(err) {
+		return completeUnstreamed(ctx, c, prompt, w)
+	}
+	if err != nil {
+		return Completion{Model: completion.Model, Usage: completion.Usage}, err
+	}
+	completion.Text = strings.TrimSpace(text.String())
+	if completion.Text == "" {
+		return completion, fmt.Errorf("empty response from %s", c.name)
+	}
+	return completion, nil
+}
+
+func (c *chatClient) request(prompt string) (string, map[string]string, chatRequest, error) {
+	if c.apiKeyEnv != "" && c.apiKey == "" {
+		return "", nil, chatRequest{}, &missingKeyError{env: c.apiKeyEnv}
+	}
+
+	endpoint := strings.TrimRight(c.baseURL, "/") + "/chat/completions"
+	payload := chatRequest{
+		Model: c.model,
+		Messages: []message{
+			{Role: "user", Content: prompt},
+		},
+		MaxTokens: c.maxTokens,
+	}
+	if c.reportsCost {
+		payload.Usage = &usageOptions{Include: true}
+	}
+	headers := map[string]string{}
+	if c.apiKey != "" {
+		headers["Authorization"] = "Bearer " + c.apiKey
+	}
+	return endpoint, headers, payload, nil
+}
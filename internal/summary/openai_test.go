@@ -0,0 +1,267 @@
+package summary
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// streamingServer answers streaming requests with stream, an event stream
+// body, or with streamStatus when it is set, and plain requests with reply.
+func streamingServer(t *testing.T, streamStatus int, stream, reply string) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var payload struct {
+			Stream bool `json:"stream"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+			t.Errorf("decoding request: %v", err)
+		}
+		switch {
+		case payload.Stream && streamStatus != 0:
+			w.WriteHeader(streamStatus)
+			_, _ = io.WriteString(w, `{"error":{"message":"stream is not supported"}}`)
+		case payload.Stream:
+			w.Header().Set("Content-Type", "text/event-stream")
+			_, _ = io.WriteString(w, stream)
+		default:
+			w.Header().Set("Content-Type", "application/json")
+			_, _ = io.WriteString(w, reply)
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestChatClientStream(t *testing.T) {
+	const plain = `{"model":"m","choices":[{"message":{"content":"plain answer"},"finish_reason":"stop"}]}`
+	tests := []struct {
+		name         string
+		streamStatus int
+		stream       string
+		text         string
+		finish       string
+		wantErr      string
+	}{
+		{
+			name: "deltas, usage and done",
+			stream: "data: {\"model\":\"m-1\",\"choices\":[{\"delta\":{\"content\":\"Hel\"}}]}\n\n" +
+				": OPENROUTER PROCESSING\n\n" +
+				"data: {\"choices\":[{\"delta\":{\"content\":\"lo\"},\"finish_reason\":\"stop\"}]}\n\n" +
+				"data: {\"choices\":[],\"usage\":{\"prompt_tokens\":7,\"completion_tokens\":2}}\n\n" +
+				"data: [DONE]\n\n",
+			text:   "Hello",
+			finish: "stop",
+		},
+		{
+			name:   "chunk split over data lines",
+			stream: "data: {\"choices\":[{\"delta\":\ndata: {\"content\":\"split\"},\"finish_reason\":\"stop\"}]}\n\ndata: [DONE]\n\n",
+			text:   "split",
+			finish: "stop",
+		},
+		{
+			name:   "finished without done",
+			stream: "data: {\"choices\":[{\"delta\":{\"content\":\"ok\"},\"finish_reason\":\"stop\"}]}\n\n",
+			text:   "ok",
+			finish: "stop",
+		},
+		{
+			name:    "cut off mid-answer",
+			stream:  "data: {\"choices\":[{\"delta\":{\"content\":\"{\\\"sections\\\":\"}}]}\n\n",
+			wantErr: errStreamCut.Error(),
+		},
+		{
+			name:    "error event mid-stream",
+			stream:  "data: {\"choices\":[{\"delta\":{\"content\":\"Hel\"}}]}\n\ndata: {\"error\":{\"message\":\"upstream overloaded\"}}\n\n",
+			wantErr: "upstream overloaded",
+		},
+		{
+			name:         "streaming turned down",
+			streamStatus: http.StatusBadRequest,
+			text:         "plain answer",
+			finish:       "stop",
+		},
+	}
+	for _, tt := range tests {
+		server := streamingServer(t, tt.streamStatus, tt.stream, plain)
+		client := newOpenAI(ProviderConfig{BaseURL: server.URL, APIKey: "sk-test", Model: "m"})
+		var streamed strings.Builder
+		completion, err := client.CompleteStream(context.Background(), "prompt", &streamed)
+		if tt.wantErr != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("%s: err = %v, want %q", tt.name, err, tt.wantErr)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		if completion.Text != tt.text || completion.FinishReason != tt.finish || streamed.String() != tt.text {
+			t.Errorf("%s: got %q (%s), streamed %q; want %q (%s)", tt.name, completion.Text, completion.FinishReason, streamed.String(), tt.text, tt.finish)
+		}
+	}
+}
+
+func TestChatClientStreamUsage(t *testing.T) {
+	stream := "data: {\"model\":\"m-1\",\"choices\":[{\"delta\":{\"content\":\"Hi\"},\"finish_reason\":\"stop\"}]}\n\n" +
+		"data: {\"choices\":[],\"usage\":{\"prompt_tokens\":7,\"completion_tokens\":2}}\n\ndata: [DONE]\n\n"
+	server := streamingServer(t, 0, stream, "")
+	client := newOpenAI(ProviderConfig{BaseURL: server.URL, APIKey: "sk-test", Model: "m"})
+	completion, err := client.CompleteStream(context.Background(), "prompt", io.Discard)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if completion.Model != "m-1" || completion.Usage.PromptTokens != 7 || completion.Usage.CompletionTokens != 2 {
+		t.Errorf("completion = %+v, want the streamed model and usage", completion)
+	}
+}
+
+func TestChatClientStreamKeepsAuthErrors(t *testing.T) {
+	server := streamingServer(t, http.StatusUnauthorized, "", `{"choices":[{"message":{"content":"should not be asked"}}]}`)
+	client := newOpenAI(ProviderConfig{BaseURL: server.URL, APIKey: "sk-test", Model: "m"})
+	_, err := client.CompleteStream(context.Background(), "prompt", io.Discard)
+	var httpErr *HTTPError
+	if !errors.As(err, &httpErr) || httpErr.StatusCode != http.StatusUnauthorized {
+		t.Errorf("err = %v, want the 401", err)
+	}
+}
+
+// recordedRequest is what a recordingServer received.
+type recordedRequest struct {
+	path    string
+	headers http.Header
+	body    map[string]any
+}
+
+// recordingServer replies to every request with status and reply, and keeps
+// the last request.
+func recordingServer(t *testing.T, status int, reply string) (*httptest.Server, *recordedRequest) {
+	t.Helper()
+	got := &recordedRequest{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got.path = r.URL.Path
+		got.headers = r.Header.Clone()
+		got.body = map[string]any{}
+		if err := json.NewDecoder(r.Body).Decode(&got.body); err != nil {
+			t.Errorf("decoding request: %v", err)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		_, _ = io.WriteString(w, reply)
+	}))
+	t.Cleanup(server.Close)
+	return server, got
+}
+
+func TestChatClientRequest(t *testing.T) {
+	server, got := recordingServer(t, http.StatusOK, `{"model":"gpt-x-2026","choices":[{"message":{"content":" answer "},"finish_reason":"stop"}],"usage":{"prompt_tokens":12,"completion_tokens":3}}`)
+	client := newOpenAI(ProviderConfig{BaseURL: server.URL + "/v1/", APIKey: "sk-test", Model: "gpt-x"})
+	completion, err := client.Complete(context.Background(), "summarize this")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.path != "/v1/chat/completions" {
+		t.Errorf("path = %s, want /v1/chat/completions", got.path)
+	}
+	if auth := got.headers.Get("Authorization"); auth != "Bearer sk-test" {
+		t.Errorf("Authorization = %q", auth)
+	}
+	if ct := got.headers.Get("Content-Type"); ct != "application/json" {
+		t.Errorf("Content-Type = %q", ct)
+	}
+	if got.body["model"] != "gpt-x" || got.body["stream"] != nil || got.body["usage"] != nil {
+		t.Errorf("body = %v, want the model and no stream or usage options", got.body)
+	}
+	messages, _ := got.body["messages"].([]any)
+	if len(messages) != 1 || messages[0].(map[string]any)["role"] != "user" || messages[0].(map[string]any)["content"] != "summarize this" {
+		t.Errorf("messages = %v, want the prompt as one user message", got.body["messages"])
+	}
+	if completion.Text != "answer" || completion.Model != "gpt-x-2026" || completion.FinishReason != "stop" {
+		t.Errorf("completion = %+v", completion)
+	}
+	if completion.Usage.PromptTokens != 12 || completion.Usage.CompletionTokens != 3 || completion.Usage.Cost != nil {
+		t.Errorf("usage = %+v", completion.Usage)
+	}
+}
+
+func TestOpenRouterRequest(t *testing.T) {
+	server, got := recordingServer(t, http.StatusOK, `{"choices":[{"message":{"content":"answer"}}],"usage":{"prompt_tokens":1,"completion_tokens":1,"cost":0.0021}}`)
+	client := newOpenRouter(ProviderConfig{BaseURL: server.URL, APIKey: "sk-or-test", Model: "google/gemini-2.5-flash"})
+	completion, err := client.Complete(context.Background(), "prompt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.body["max_tokens"] != float64(100000) {
+		t.Errorf("max_tokens = %v", got.body["max_tokens"])
+	}
+	if usage, _ := got.body["usage"].(map[string]any); usage["include"] != true {
+		t.Errorf("usage = %v, want the cost asked for", got.body["usage"])
+	}
+	if completion.Usage.Cost == nil || *completion.Usage.Cost != 0.0021 {
+		t.Errorf("cost = %v, want 0.0021", completion.Usage.Cost)
+	}
+	if completion.Model != "google/gemini-2.5-flash" {
+		t.Errorf("model = %q, want the requested model when the reply names none", completion.Model)
+	}
+}
+
+func TestChatClientBaseURL(t *testing.T) {
+	t.Setenv("OPENAI_BASE_URL", "https://env.example/v1")
+	if got := newOpenAI(ProviderConfig{}).BaseURL(); got != "https://env.example/v1" {
+		t.Errorf("base URL = %s, want the environment's", got)
+	}
+	if got := newOpenAI(ProviderConfig{BaseURL: "https://flag.example/v1"}).BaseURL(); got != "https://flag.example/v1" {
+		t.Errorf("base URL = %s, want the configured one", got)
+	}
+	t.Setenv("OPENAI_BASE_URL", "")
+	if got := newOpenAI(ProviderConfig{}).BaseURL(); got != "https://api.openai.com/v1" {
+		t.Errorf("base URL = %s, want the default", got)
+	}
+}
+
+func TestChatClientMissingKey(t *testing.T) {
+	t.Setenv("OPENAI_API_KEY", "")
+	t.Setenv("OPENROUTER_API_KEY", "")
+	for _, client := range []*chatClient{newOpenAI(ProviderConfig{}), newOpenRouter(ProviderConfig{})} {
+		_, err := client.Complete(context.Background(), "prompt")
+		var keyErr *missingKeyError
+		if !errors.As(err, &keyErr) || keyErr.env != client.apiKeyEnv {
+			t.Errorf("%s: err = %v, want %s reported missing", client.name, err, client.apiKeyEnv)
+		}
+	}
+}
+
+func TestChatClientErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		status  int
+		reply   string
+		wantErr string
+	}{
+		{"API error body", http.StatusBadRequest, `{"error":{"message":"model not found","type":"invalid_request_error"}}`, "HTTP 400 Bad Request: model not found"},
+		{"plain text body", http.StatusBadGateway, "upstream   connect\nerror", "HTTP 502 Bad Gateway: upstream connect error"},
+		{"empty body", http.StatusServiceUnavailable, "", "HTTP 503 Service Unavailable"},
+		{"error in a 200 reply", http.StatusOK, `{"error":{"message":"rate limited upstream"}}`, "API error: rate limited upstream"},
+		{"no choices", http.StatusOK, `{"choices":[]}`, "empty response from openai"},
+		{"blank answer", http.StatusOK, `{"choices":[{"message":{"content":"  "}}]}`, "empty response from openai"},
+		{"not JSON", http.StatusOK, `<html>`, "decoding response"},
+	}
+	for _, tt := range tests {
+		server, _ := recordingServer(t, tt.status, tt.reply)
+		client := newOpenAI(ProviderConfig{BaseURL: server.URL, APIKey: "sk-test", Model: "m"})
+		_, err := client.Complete(context.Background(), "prompt")
+		if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+			t.Errorf("%s: err = %v, want %q", tt.name, err, tt.wantErr)
+		}
+		var httpErr *HTTPError
+		if tt.status != http.StatusOK && (!errors.As(err, &httpErr) || httpErr.StatusCode != tt.status) {
+			t.Errorf("%s: err = %#v, want an *HTTPError with status %d", tt.name, err, tt.status)
+		}
+	}
+}
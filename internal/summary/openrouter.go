@@ -1,95 +1,15 @@
 package summary
 
-import (
-	"bytes"
-	"encoding/json"
-	"errors"
-	"fmt"
-	"net/http"
-	"os"
-	"strings"
-	"time"
-)
-
-// OpenRouter uses OpenAI-compatible API format
-type openRouterRequest struct {
-	Model       string    `json:"model"`
-	Messages    []message `json:"messages"`
-	MaxTokens   int       `json:"max_tokens,omitempty"`
-	Temperature float64   `json:"temperature"`
-}
-
-type message struct {
-	Role    string `json:"role"`
-	Content string `json:"content"`
-}
-
-type openRouterResponse struct {
-	Choices []struct {
-		Message struct {
-			Content string `json:"content"`
-		} `json:"message"`
-		FinishReason string `json:"finish_reason"`
-	} `json:"choices"`
-	Error *struct {
-		Message string `json:"message"`
-	} `json:"error"`
-}
-
-func callOpenRouter(prompt string) (string, error) {
-	model := os.Getenv("OPENROUTER_MODEL")
-	if model == "" {
-		model = "google/gemini-2.5-flash"
-	}
-	return callOpenRouterWithModel(prompt, model, 100000)
-}
-
-func callOpenRouterWithModel(prompt string, model string, maxTokens int) (string, error) {
-	apiKey := os.Getenv("OPENROUTER_API_KEY")
-	if apiKey == "" {
-		return "", errors.New("OPENROUTER_API_KEY is not set")
-	}
-
-	endpoint := "https://openrouter.ai/api/v1/chat/completions"
-	payload := openRouterRequest{
-		Model: model,
-		Messages: []message{
-			{Role: "user", Content: prompt},
-		},
-		MaxTokens: maxTokens,
-	}
-	body, err := json.Marshal(payload)
-	if err != nil {
-		return "", err
-	}
-
-	req, err := http.NewRequest("POST", endpoint, bytes.NewReader(body))
-	if err != nil {
-		return "", err
-	}
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("Authorization", "Bearer "+apiKey)
-
-	client := &http.Client{Timeout: 180 * time.Second}
-	resp, err := client.Do(req)
-	if err != nil {
-		return "", err
-	}
-	defer resp.Body.Close()
-
-	var parsed openRouterResponse
-	if err := json.NewDecoder(resp.Body).Decode(&parsed); err != nil {
-		return "", err
-	}
-	if parsed.Error != nil {
-		return "", fmt.Errorf("API error: %s", parsed.Error.Message)
-	}
-	if len(parsed.Choices) == 0 {
-		return "", errors.New("empty response from OpenRouter")
-	}
-	text := strings.TrimSpace(parsed.Choices[0].Message.Content)
-	if text == "" {
-		return "", errors.New("empty response from OpenRouter")
+import "os"
+
+func newOpenRouter(cfg ProviderConfig) *chatClient {
+	return &chatClient{
+		name:        "openrouter",
+		baseURL:     firstNonEmpty(cfg.BaseURL, os.Getenv("OPENROUTER_BASE_URL"), "https://openrouter.ai/api/v1"),
+		apiKey:      firstNonEmpty(cfg.APIKey, os.Getenv("OPENROUTER_API_KEY")),
+		apiKeyEnv:   "OPENROUTER_API_KEY",
+		model:       firstNonEmpty(cfg.Model, os.Getenv("OPENROUTER_MODEL"), "google/gemini-2.5-flash"),
+		maxTokens:   100000,
+		reportsCost: true,
 	}
-	return text, nil
 }
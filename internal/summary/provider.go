@@ -0,0 +1,251 @@
+package summary
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// Completion is a model's answer to a prompt. A provider that fails after
+// the API reported usage returns the Completion, with that usage, alongside
+// the error.
+type Completion struct {
+	Text         string
+	Model        string
+	FinishReason string
+	Usage        Usage
+	// Failed are earlier attempts at the same prompt that failed but were
+	// billed all the same, such as retries and truncated answers.
+	Failed []Call
+}
+
+// Usage is what a completion consumed, as reported by the API.
+type Usage struct {
+	PromptTokens     int
+	CompletionTokens int
+	// Cost is the charge in USD when the API reports one (OpenRouter does),
+	// nil otherwise.
+	Cost *float64
+	// Cached is set for answers served from the response cache, which cost
+	// nothing.
+	Cached bool
+}
+
+// reported reports whether the API said anything about usage.
+func (u Usage) reported() bool {
+	return u.PromptTokens > 0 || u.CompletionTokens > 0 || u.Cost != nil
+}
+
+// Add sums two usages; the cost stays unknown only if both are unknown.
+func (u Usage) Add(other Usage) Usage {
+	sum := Usage{
+		PromptTokens:     u.PromptTokens + other.PromptTokens,
+		CompletionTokens: u.CompletionTokens + other.CompletionTokens,
+	}
+	if u.Cost != nil || other.Cost != nil {
+		cost := 0.0
+		if u.Cost != nil {
+			cost += *u.Cost
+		}
+		if other.Cost != nil {
+			cost += *other.Cost
+		}
+		sum.Cost = &cost
+	}
+	return sum
+}
+
+// Provider sends a prompt to an LLM backend.
+type Provider interface {
+	// Name identifies the backend ("openrouter", "openai", ...).
+	Name() string
+	// Model is the model the provider asks for.
+	Model() string
+	Complete(ctx context.Context, prompt string) (Completion, error)
+}
+
+// StreamingProvider is a Provider that can deliver its answer as it is
+// generated.
+type StreamingProvider interface {
+	Provider
+	// CompleteStream writes the answer to w as it arrives and returns the
+	// whole completion at the end.
+	CompleteStream(ctx context.Context, prompt string, w io.Writer) (Completion, error)
+}
+
+// complete streams the answer to w when w is set and the provider can
+// stream, and makes a plain call otherwise.
+func complete(ctx context.Context, provider Provider, prompt string, w io.Writer) (Completion, error) {
+	if streaming, ok := provider.(StreamingProvider); ok && w != nil {
+		return streaming.CompleteStream(ctx, prompt, w)
+	}
+	return provider.Complete(ctx, prompt)
+}
+
+// completeUnstreamed asks provider again with a plain call after it turned
+// down a streaming request, and writes the answer to w in one piece.
+func completeUnstreamed(ctx context.Context, provider Provider, prompt string, w io.Writer) (Completion, error) {
+	completion, err := provider.Complete(ctx, prompt)
+	if err != nil {
+		return completion, err
+	}
+	if _, err := io.WriteString(w, completion.Text); err != nil {
+		return completion, err
+	}
+	return completion, nil
+}
+
+// ProviderConfig selects and configures a provider. Empty fields fall back
+// to the provider's environment variables, then to Defaults and finally to
+// the provider's built-in defaults.
+type ProviderConfig struct {
+	Name string
+	// Model may list several comma-separated models, tried in order; the
+	// provider's *_MODELS variable does the same when Model is empty.
+	Model   string
+	BaseURL string
+	APIKey  string
+	// Defaults come from the config file.
+	Defaults ProviderDefaults
+	// Log receives retry and fallback reports.
+	Log func(string)
+}
+
+// ProviderDefaults are provider settings below the environment in
+// precedence. Models and base URLs are keyed by provider name, so switching
+// providers never sends one provider's model name to another.
+type ProviderDefaults struct {
+	Name     string
+	Models   map[string]string
+	BaseURLs map[string]string
+}
+
+// Setting is a resolved value and where it came from: "flag", "env NAME",
+// "config" or "default".
+type Setting struct {
+	Value  string
+	Origin string
+}
+
+// ResolvedProvider is what NewProvider settles on for a ProviderConfig.
+type ResolvedProvider struct {
+	Name    Setting
+	Model   Setting
+	BaseURL Setting
+	// APIKeyEnv is the variable the API key is read from, and APIKeySet
+	// whether it holds one.
+	APIKeyEnv string
+	APIKeySet bool
+}
+
+// ProviderNames lists the supported --provider values.
+var ProviderNames = []string{"openrouter", "openai", "anthropic", "ollama"}
+
+var builders = map[string]func(ProviderConfig) Provider{
+	"openrouter": func(cfg ProviderConfig) Provider { return newOpenRouter(cfg) },
+	"openai":     func(cfg ProviderConfig) Provider { return newOpenAI(cfg) },
+	"anthropic":  func(cfg ProviderConfig) Provider { return newAnthropic(cfg) },
+	"ollama":     func(cfg ProviderConfig) Provider { return newOllama(cfg) },
+}
+
+// ResolveProvider works out the provider, models and base URL cfg selects,
+// and where each of them comes from.
+func ResolveProvider(cfg ProviderConfig) (ResolvedProvider, error) {
+	var r ResolvedProvider
+	r.Name = resolveSetting(cfg.Name, []string{"WEBLOG_PROVIDER"}, cfg.Defaults.Name)
+	if r.Name.Value == "" {
+		r.Name.Value = "openrouter"
+	}
+	name := strings.ToLower(r.Name.Value)
+	r.Name.Value = name
+	build, ok := builders[name]
+	if !ok {
+		return r, fmt.Errorf("unknown provider %q (want one of %s)", name, strings.Join(ProviderNames, ", "))
+	}
+
+	prefix := strings.ToUpper(name)
+	r.Model = resolveSetting(cfg.Model, []string{prefix + "_MODELS", prefix + "_MODEL"}, cfg.Defaults.Models[name])
+	r.BaseURL = resolveSetting(cfg.BaseURL, []string{prefix + "_BASE_URL"}, cfg.Defaults.BaseURLs[name])
+	if r.Model.Value == "" || r.BaseURL.Value == "" {
+		// The backend's own defaults apply
+		p := build(ProviderConfig{})
+		if r.Model.Value == "" {
+			r.Model.Value = p.Model()
+		}
+		if withURL, ok := p.(interface{ BaseURL() string }); ok && r.BaseURL.Value == "" {
+			r.BaseURL.Value = withURL.BaseURL()
+		}
+	}
+	r.APIKeyEnv = prefix + "_API_KEY"
+	r.APIKeySet = cfg.APIKey != "" || os.Getenv(r.APIKeyEnv) != ""
+	return r, nil
+}
+
+// resolveSetting picks value, then the first set environment variable, then
+// the config file's value.
+func resolveSetting(value string, envs []string, file string) Setting {
+	if value != "" {
+		return Setting{Value: value, Origin: "flag"}
+	}
+	for _, env := range envs {
+		if v := os.Getenv(env); v != "" {
+			return Setting{Value: v, Origin: "env " + env}
+		}
+	}
+	if file != "" {
+		return Setting{Value: file, Origin: "config"}
+	}
+	return Setting{Origin: "default"}
+}
+
+// NewProvider builds the provider ResolveProvider settles on: the one named
+// in cfg, WEBLOG_PROVIDER or the config file, or OpenRouter. Missing API
+// keys are only reported when the provider is called, so building a
+// provider never fails for lack of credentials.
+//
+// Calls are retried with backoff, as are replies that are not a summary
+// document, and when a model keeps failing or truncates its answer the next
+// model in the list is tried.
+func NewProvider(cfg ProviderConfig) (Provider, error) {
+	resolved, err := ResolveProvider(cfg)
+	if err != nil {
+		return nil, err
+	}
+	build := builders[resolved.Name.Value]
+	cfg.BaseURL = resolved.BaseURL.Value
+	models := splitList(resolved.Model.Value)
+	if len(models) == 0 {
+		models = []string{""}
+	}
+	providers := make([]Provider, len(models))
+	for i, model := range models {
+		modelCfg := cfg
+		modelCfg.Model = model
+		providers[i] = build(modelCfg)
+	}
+	fallback := newFallbackProvider(providers, cfg.Log)
+	fallback.validate = checkSummary
+	return fallback, nil
+}
+
+// splitList splits a comma-separated list, dropping empty items.
+func splitList(value string) []string {
+	items := []string{}
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, value := range values {
+		if value != "" {
+			return value
+		}
+	}
+	return ""
+}
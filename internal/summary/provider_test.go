@@ -0,0 +1,80 @@
+package summary
+
+import (
+	"testing"
+)
+
+func TestResolveProviderPrecedence(t *testing.T) {
+	defaults := ProviderDefaults{
+		Name:     "openai",
+		Models:   map[string]string{"openai": "config-model", "anthropic": "config-claude"},
+		BaseURLs: map[string]string{"openai": "https://config.example/v1"},
+	}
+	tests := []struct {
+		name    string
+		cfg     ProviderConfig
+		env     map[string]string
+		want    [3]string
+		origins [3]string
+	}{
+		{
+			name:    "config file",
+			cfg:     ProviderConfig{Defaults: defaults},
+			want:    [3]string{"openai", "config-model", "https://config.example/v1"},
+			origins: [3]string{"config", "config", "config"},
+		},
+		{
+			name:    "environment over config",
+			cfg:     ProviderConfig{Defaults: defaults},
+			env:     map[string]string{"WEBLOG_PROVIDER": "anthropic", "ANTHROPIC_MODEL": "env-claude"},
+			want:    [3]string{"anthropic", "env-claude", "https://api.anthropic.com/v1"},
+			origins: [3]string{"env WEBLOG_PROVIDER", "env ANTHROPIC_MODEL", "default"},
+		},
+		{
+			name:    "_MODELS over _MODEL",
+			cfg:     ProviderConfig{Defaults: defaults},
+			env:     map[string]string{"OPENAI_MODELS": "a,b", "OPENAI_MODEL": "c"},
+			want:    [3]string{"openai", "a,b", "https://config.example/v1"},
+			origins: [3]string{"config", "env OPENAI_MODELS", "config"},
+		},
+		{
+			name:    "flags over everything",
+			cfg:     ProviderConfig{Name: "OpenAI", Model: "flag-model", BaseURL: "http://flag.example/v1", Defaults: defaults},
+			env:     map[string]string{"WEBLOG_PROVIDER": "anthropic", "OPENAI_MODEL": "env-model", "OPENAI_BASE_URL": "http://env.example/v1"},
+			want:    [3]string{"openai", "flag-model", "http://flag.example/v1"},
+			origins: [3]string{"flag", "flag", "flag"},
+		},
+		{
+			name:    "built-in defaults",
+			cfg:     ProviderConfig{},
+			want:    [3]string{"openrouter", "", ""},
+			origins: [3]string{"default", "default", "default"},
+		},
+	}
+	for _, tt := range tests {
+		for _, env := range []string{"WEBLOG_PROVIDER", "OPENAI_MODELS", "OPENAI_MODEL", "OPENAI_BASE_URL", "ANTHROPIC_MODELS", "ANTHROPIC_MODEL", "ANTHROPIC_BASE_URL", "OPENROUTER_MODELS", "OPENROUTER_MODEL", "OPENROUTER_BASE_URL"} {
+			t.Setenv(env, tt.env[env])
+		}
+		r, err := ResolveProvider(tt.cfg)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		got := [3]Setting{r.Name, r.Model, r.BaseURL}
+		for i, setting := range got {
+			// Built-in model and URL defaults belong to the backends
+			if tt.want[i] != "" && setting.Value != tt.want[i] {
+				t.Errorf("%s: setting %d = %q, want %q", tt.name, i, setting.Value, tt.want[i])
+			}
+			if setting.Origin != tt.origins[i] {
+				t.Errorf("%s: setting %d from %q, want %q", tt.name, i, setting.Origin, tt.origins[i])
+			}
+		}
+	}
+}
+
+func TestResolveProviderUnknown(t *testing.T) {
+	t.Setenv("WEBLOG_PROVIDER", "")
+	if _, err := ResolveProvider(ProviderConfig{Name: "skynet"}); err == nil {
+		t.Error("want an error for an unknown provider")
+	}
+}
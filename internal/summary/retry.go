@@ -0,0 +1,280 @@
+package summary
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// ErrTruncated means the model stopped at its output token limit, so the
+// summary is incomplete.
+var ErrTruncated = errors.New("output truncated at the token limit")
+
+// retryPolicy controls how often one model is retried before falling back.
+type retryPolicy struct {
+	attempts int
+	// initial is the first backoff, doubled after every attempt up to max.
+	initial time.Duration
+	max     time.Duration
+	// maxRetryAfter is the longest Retry-After worth waiting for; a server
+	// asking for more gets the next model instead.
+	maxRetryAfter time.Duration
+}
+
+var defaultRetryPolicy = retryPolicy{
+	attempts:      4,
+	initial:       2 * time.Second,
+	max:           60 * time.Second,
+	maxRetryAfter: 2 * time.Minute,
+}
+
+// Attempt records one call made by a fallbackProvider.
+type Attempt struct {
+	Model    string
+	Number   int
+	Duration time.Duration
+	Err      error
+	// Usage is what the API reported for the call, failed or not.
+	Usage Usage
+}
+
+// fallbackProvider retries each of its providers with exponential backoff
+// and moves on to the next one (the next model) when a provider keeps
+// failing or truncates its answer.
+type fallbackProvider struct {
+	providers []Provider
+	policy    retryPolicy
+	log       func(string)
+	// validate, when set, rejects replies that arrived intact but are of
+	// no use, so they are retried like a failed call.
+	validate func(Completion) error
+}
+
+func newFallbackProvider(providers []Provider, log func(string)) *fallbackProvider {
+	return &fallbackProvider{
+		providers: providers,
+		policy:    defaultRetryPolicy,
+		log:       log,
+	}
+}
+
+func (f *fallbackProvider) Name() string { return f.providers[0].Name() }
+
+// Model lists the models in the order they are tried.
+func (f *fallbackProvider) Model() string {
+	models := make([]string, len(f.providers))
+	for i, p := range f.providers {
+		models[i] = p.Model()
+	}
+	return strings.Join(models, ",")
+}
+
+func (f *fallbackProvider) logf(format string, args ...any) {
+	if f.log != nil {
+		f.log(fmt.Sprintf(format, args...))
+	}
+}
+
+func (f *fallbackProvider) Complete(ctx context.Context, prompt string) (Completion, error) {
+	return f.complete(ctx, prompt, nil)
+}
+
+// CompleteStream streams through the providers that support it. A retry
+// after a partly streamed answer restarts w when it is a Restarter, and
+// starts on a fresh line otherwise.
+func (f *fallbackProvider) CompleteStream(ctx context.Context, prompt string, w io.Writer) (Completion, error) {
+	if w == nil {
+		return f.complete(ctx, prompt, nil)
+	}
+	return f.complete(ctx, prompt, &lineWriter{w: w})
+}
+
+func (f *fallbackProvider) complete(ctx context.Context, prompt string, w io.Writer) (Completion, error) {
+	attempts := []Attempt{}
+	for i, provider := range f.providers {
+		if i > 0 {
+			f.logf("%s: falling back to %s", provider.Name(), provider.Model())
+		}
+		completion, err := f.completeWithRetry(ctx, provider, prompt, w, &attempts)
+		if err == nil {
+			if len(attempts) > 1 {
+				f.logf("%s: %s answered after %d attempts", provider.Name(), provider.Model(), len(attempts))
+			}
+			completion.Failed = f.failedCalls(attempts)
+			return completion, nil
+		}
+		if ctx.Err() != nil {
+			return Completion{Failed: f.failedCalls(attempts)}, ctx.Err()
+		}
+		if !canFallBack(err) {
+			return Completion{Failed: f.failedCalls(attempts)}, fmt.Errorf("%s: %w", provider.Name(), err)
+		}
+	}
+	return Completion{Failed: f.failedCalls(attempts)}, attemptsError(f.Name(), len(f.providers), attempts)
+}
+
+// failedCalls lists the failed attempts the API reported usage for, which
+// count towards the bill like any other call.
+func (f *fallbackProvider) failedCalls(attempts []Attempt) []Call {
+	calls := []Call{}
+	for _, a := range attempts {
+		if a.Err != nil && a.Usage.reported() {
+			calls = append(calls, Call{Provider: f.Name(), Model: a.Model, Usage: a.Usage})
+		}
+	}
+	return calls
+}
+
+// completeWithRetry calls provider until it succeeds, fails permanently or
+// runs out of attempts, appending every call to attempts.
+func (f *fallbackProvider) completeWithRetry(ctx context.Context, provider Provider, prompt string, w io.Writer, attempts *[]Attempt) (Completion, error) {
+	backoff := f.policy.initial
+	var err error
+	for n := 1; n <= f.policy.attempts; n++ {
+		start := time.Now()
+		var completion Completion
+		completion, err = complete(ctx, provider, prompt, w)
+		if err == nil && isTruncated(completion.FinishReason) {
+			err = ErrTruncated
+		}
+		if err == nil && f.validate != nil {
+			err = f.validate(completion)
+		}
+		if lw, ok := w.(*lineWriter); ok {
+			lw.endAttempt(err != nil)
+		}
+		*attempts = append(*attempts, Attempt{Model: provider.Model(), Number: n, Duration: time.Since(start), Err: err, Usage: completion.Usage})
+		if err == nil {
+			return completion, nil
+		}
+		if ctx.Err() != nil {
+			return Completion{}, ctx.Err()
+		}
+		var keyErr *missingKeyError
+		if errors.As(err, &keyErr) {
+			return Completion{}, err
+		}
+
+		wait, retry := f.retryDelay(err, backoff)
+		if !retry || n == f.policy.attempts {
+			f.logf("%s: %s attempt %d failed after %s: %v", provider.Name(), provider.Model(), n, time.Since(start).Round(time.Millisecond), err)
+			return Completion{}, err
+		}
+		f.logf("%s: %s attempt %d failed after %s: %v; retrying in %s", provider.Name(), provider.Model(), n, time.Since(start).Round(time.Millisecond), err, wait)
+		if err := sleepContext(ctx, wait); err != nil {
+			return Completion{}, err
+		}
+		backoff = min(backoff*2, f.policy.max)
+	}
+	return Completion{}, err
+}
+
+// retryDelay decides whether err is worth retrying on the same model and
+// how long to wait first. A Retry-After from the server wins over backoff.
+func (f *fallbackProvider) retryDelay(err error, backoff time.Duration) (time.Duration, bool) {
+	var httpErr *HTTPError
+	if errors.As(err, &httpErr) {
+		if !httpErr.Temporary() {
+			return 0, false
+		}
+		if httpErr.RetryAfter > 0 {
+			if httpErr.RetryAfter > f.policy.maxRetryAfter {
+				return 0, false
+			}
+			return httpErr.RetryAfter, true
+		}
+		return backoff, true
+	}
+	if errors.Is(err, ErrTruncated) {
+		return 0, false
+	}
+	// Network errors, timeouts and malformed or empty replies
+	return backoff, true
+}
+
+// checkSummary rejects replies that are not a summary document, the reply
+// every prompt of this package asks for.
+func checkSummary(completion Completion) error {
+	_, err := parseSections(completion.Text)
+	return err
+}
+
+// canFallBack reports whether another model might succeed where err failed.
+// Credential and billing problems affect every model alike.
+func canFallBack(err error) bool {
+	var keyErr *missingKeyError
+	if errors.As(err, &keyErr) {
+		return false
+	}
+	var httpErr *HTTPError
+	if errors.As(err, &httpErr) {
+		return httpErr.StatusCode != http.StatusUnauthorized && httpErr.StatusCode != http.StatusForbidden && httpErr.StatusCode != http.StatusPaymentRequired
+	}
+	return true
+}
+
+// isTruncated recognizes the OpenAI ("length") and Anthropic ("max_tokens")
+// finish reasons for hitting the output limit.
+func isTruncated(finishReason string) bool {
+	return finishReason == "length" || finishReason == "max_tokens"
+}
+
+func attemptsError(provider string, models int, attempts []Attempt) error {
+	lines := make([]string, len(attempts))
+	for i, a := range attempts {
+		lines[i] = fmt.Sprintf("  %s attempt %d (%s): %v", a.Model, a.Number, a.Duration.Round(time.Millisecond), a.Err)
+	}
+	if models == 1 {
+		return fmt.Errorf("%s: giving up after %d attempts:\n%s", provider, len(attempts), strings.Join(lines, "\n"))
+	}
+	return fmt.Errorf("%s: all %d models failed:\n%s", provider, models, strings.Join(lines, "\n"))
+}
+
+// Restarter is implemented by stream writers that can take back what a
+// failed attempt wrote, so the retry's answer replaces it rather than
+// following it.
+type Restarter interface {
+	Restart()
+}
+
+// lineWriter separates the attempts streamed to w: a failed attempt is taken
+// back when w is a Restarter, and otherwise ended with a newline, so whatever
+// is written next starts on a line of its own.
+type lineWriter struct {
+	w       io.Writer
+	midLine bool
+}
+
+func (l *lineWriter) Write(p []byte) (int, error) {
+	if len(p) > 0 {
+		l.midLine = p[len(p)-1] != '\n'
+	}
+	return l.w.Write(p)
+}
+
+func (l *lineWriter) endAttempt(failed bool) {
+	if r, ok := l.w.(Restarter); ok && failed {
+		r.Restart()
+		l.midLine = false
+		return
+	}
+	if l.midLine {
+		_, _ = io.WriteString(l.w, "\n")
+		l.midLine = false
+	}
+}
+
+func sleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
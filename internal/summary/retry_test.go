@@ -0,0 +1,155 @@
+package summary
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+// scriptedProvider answers with its replies in order, repeating the last,
+// and keeps the prompts it was sent.
+type scriptedProvider struct {
+	model   string
+	replies []scriptedReply
+	calls   int
+	prompts []string
+}
+
+type scriptedReply struct {
+	completion Completion
+	err        error
+}
+
+func (p *scriptedProvider) Name() string  { return "fake" }
+func (p *scriptedProvider) Model() string { return p.model }
+func (p *scriptedProvider) Complete(ctx context.Context, prompt string) (Completion, error) {
+	reply := p.replies[min(p.calls, len(p.replies)-1)]
+	p.calls++
+	p.prompts = append(p.prompts, prompt)
+	return reply.completion, reply.err
+}
+
+func answer(text string) scriptedReply {
+	return scriptedReply{completion: Completion{Text: text, FinishReason: "stop"}}
+}
+
+func failure(err error) scriptedReply {
+	return scriptedReply{err: err}
+}
+
+// testFallback retries without sleeping.
+func testFallback(providers ...Provider) *fallbackProvider {
+	f := newFallbackProvider(providers, nil)
+	f.policy = retryPolicy{attempts: 3, initial: time.Nanosecond, max: time.Nanosecond, maxRetryAfter: time.Second}
+	return f
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2026, 10, 17, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		value string
+		want  time.Duration
+	}{
+		{"", 0},
+		{"30", 30 * time.Second},
+		{" 5 ", 5 * time.Second},
+		{"0", 0},
+		{"-3", 0},
+		{"1.5", 0},
+		{now.Add(90 * time.Second).Format(http.TimeFormat), 90 * time.Second},
+		{now.Add(-time.Minute).Format(http.TimeFormat), 0},
+		{"next tuesday", 0},
+	}
+	for _, tt := range tests {
+		if got := parseRetryAfter(tt.value, now); got != tt.want {
+			t.Errorf("parseRetryAfter(%q) = %s, want %s", tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestFallbackOrder(t *testing.T) {
+	overloaded := &HTTPError{StatusCode: 529}
+	tests := []struct {
+		name    string
+		primary []scriptedReply
+		backup  []scriptedReply
+		// calls made to each model
+		primaryCalls, backupCalls int
+		text                      string
+		wantErr                   string
+	}{
+		{"first model answers", []scriptedReply{answer("a")}, []scriptedReply{answer("b")}, 1, 0, "a", ""},
+		{"retried on the same model", []scriptedReply{failure(overloaded), answer("a")}, []scriptedReply{answer("b")}, 2, 0, "a", ""},
+		{"falls back after the retries", []scriptedReply{failure(overloaded)}, []scriptedReply{answer("b")}, 3, 1, "b", ""},
+		{"malformed replies are retried", []scriptedReply{failure(errors.New("unexpected EOF")), answer("a")}, nil, 2, 0, "a", ""},
+		{"bad request falls back at once", []scriptedReply{failure(&HTTPError{StatusCode: 400})}, []scriptedReply{answer("b")}, 1, 1, "b", ""},
+		{"truncation falls back at once", []scriptedReply{{completion: Completion{Text: "{", FinishReason: "length"}}}, []scriptedReply{answer("b")}, 1, 1, "b", ""},
+		{"long Retry-After falls back", []scriptedReply{failure(&HTTPError{StatusCode: 429, RetryAfter: time.Hour})}, []scriptedReply{answer("b")}, 1, 1, "b", ""},
+		{"bad key stops", []scriptedReply{failure(&HTTPError{StatusCode: 401})}, []scriptedReply{answer("b")}, 1, 0, "", "HTTP 401"},
+		{"missing key stops", []scriptedReply{failure(&missingKeyError{env: "FAKE_API_KEY"})}, []scriptedReply{answer("b")}, 1, 0, "", "FAKE_API_KEY"},
+		{"every model fails", []scriptedReply{failure(overloaded)}, []scriptedReply{failure(overloaded)}, 3, 3, "", "all 2 models failed"},
+	}
+	for _, tt := range tests {
+		primary := &scriptedProvider{model: "primary", replies: tt.primary}
+		backup := &scriptedProvider{model: "backup", replies: tt.backup}
+		if backup.replies == nil {
+			backup.replies = []scriptedReply{answer("b")}
+		}
+		completion, err := testFallback(primary, backup).Complete(context.Background(), "prompt")
+		if primary.calls != tt.primaryCalls || backup.calls != tt.backupCalls {
+			t.Errorf("%s: calls = %d, %d; want %d, %d", tt.name, primary.calls, backup.calls, tt.primaryCalls, tt.backupCalls)
+		}
+		if tt.wantErr != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("%s: err = %v, want %q", tt.name, err, tt.wantErr)
+			}
+			continue
+		}
+		if err != nil || completion.Text != tt.text {
+			t.Errorf("%s: got %q, %v; want %q", tt.name, completion.Text, err, tt.text)
+		}
+	}
+}
+
+func TestFallbackRetriesInvalidReplies(t *testing.T) {
+	tests := []struct {
+		name                      string
+		primary                   []scriptedReply
+		primaryCalls, backupCalls int
+	}{
+		{"invalid JSON, then valid", []scriptedReply{answer(`{"sections":[{"name":"Dev",}]}`), answer(validReply)}, 2, 0},
+		{"empty, then valid", []scriptedReply{answer(""), answer(validReply)}, 2, 0},
+		{"no sections, then valid", []scriptedReply{answer(`{"sections":[]}`), answer(validReply)}, 2, 0},
+		{"never valid falls back", []scriptedReply{answer("Sorry, I cannot help with that.")}, 3, 1},
+	}
+	for _, tt := range tests {
+		primary := &scriptedProvider{model: "primary", replies: tt.primary}
+		backup := &scriptedProvider{model: "backup", replies: []scriptedReply{answer(validReply)}}
+		fallback := testFallback(primary, backup)
+		fallback.validate = checkSummary
+		completion, err := fallback.Complete(context.Background(), "prompt")
+		if primary.calls != tt.primaryCalls || backup.calls != tt.backupCalls {
+			t.Errorf("%s: calls = %d, %d; want %d, %d", tt.name, primary.calls, backup.calls, tt.primaryCalls, tt.backupCalls)
+		}
+		if err != nil || completion.Text != validReply {
+			t.Errorf("%s: got %q, %v; want the valid reply", tt.name, completion.Text, err)
+		}
+	}
+}
+
+func TestTagsSummaryRetriesInvalidReply(t *testing.T) {
+	provider := &scriptedProvider{model: "m", replies: []scriptedReply{answer("not JSON at all"), answer(validReply)}}
+	fallback := testFallback(provider)
+	fallback.validate = checkSummary
+	items := fakeItems(1, 2)
+	s, err := TagsSummary(context.Background(), fallback, itemEntries(items), "2026-10-05", "2026-10-05", 1, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if provider.calls != 2 || len(s.Sections) != 1 {
+		t.Errorf("calls = %d, sections = %+v; want the second reply used", provider.calls, s.Sections)
+	}
+}
@@ -1,8 +1,10 @@
 package summary
 
 import (
+	"context"
 	"fmt"
 	"html"
+	"io"
 	"sort"
 	"strings"
 	"time"
@@ -10,32 +12,143 @@ import (
 	"web-log/internal/history"
 )
 
-func TagsSummary(entries []history.Entry, startDate, endDate string, days int) (string, error) {
-	// Filter out noise (domain-level only)
-	filtered := make([]history.Entry, 0, len(entries))
-	for _, entry := range entries {
-		domain := normalizeDomain(entry.URL)
-		// Skip by domain
-		if domain == "mail.google.com" ||
-			domain == "accounts.google.com" ||
-			strings.HasPrefix(domain, "auth.") ||
-			strings.HasPrefix(domain, "login.") ||
-			strings.HasPrefix(domain, "sso.") {
-			continue
+// Options tunes how TagsSummary talks to the provider.
+type Options struct {
+	// MaxPromptTokens is the estimated prompt size above which the history
+	// is summarized in chunks and merged. 0 means DefaultMaxPromptTokens.
+	MaxPromptTokens int
+	// Progress, when set, receives one-line status messages.
+	Progress func(string)
+	// Stream, when set, receives the model's final answer as it is
+	// generated, if the provider supports streaming. Partial summaries of a
+	// chunked run are not streamed. A Stream that is a Restarter is
+	// restarted when an attempt fails and the answer is asked for again.
+	Stream io.Writer
+	// PromptTemplate, when set, replaces DefaultPromptGuidance in every
+	// prompt. {start}, {end} and {days} are filled in with the period.
+	PromptTemplate string
+}
+
+func (o Options) maxPromptTokens() int {
+	if o.MaxPromptTokens > 0 {
+		return o.MaxPromptTokens
+	}
+	return DefaultMaxPromptTokens
+}
+
+// promptRules is the output format followed by the grouping guidance.
+func (o Options) promptRules() string {
+	guidance := DefaultPromptGuidance
+	if strings.TrimSpace(o.PromptTemplate) != "" {
+		guidance = strings.TrimSpace(o.PromptTemplate) + "\n"
+	}
+	return promptFormat + "\n" + guidance
+}
+
+func (o Options) progress(format string, args ...any) {
+	if o.Progress != nil {
+		o.Progress(fmt.Sprintf(format, args...))
+	}
+}
+
+// TagsSummary asks the provider for a tag summary of entries and returns it
+// validated, with counts recomputed from the entries each tag references.
+// Entries should already be filtered by the exclusion rules. When it fails,
+// it still returns a Summary without sections whose Calls are the calls
+// made before the failure, so their usage can be accounted for.
+func TagsSummary(ctx context.Context, provider Provider, entries []history.Entry, startDate, endDate string, days int, opts Options) (*Summary, error) {
+	items := numberEntries(entries)
+	summary := &Summary{
+		StartDate: startDate,
+		EndDate:   endDate,
+		Days:      days,
+		Entries:   itemEntries(items),
+	}
+
+	calls := &recorder{provider: provider}
+	provider = calls
+
+	var sections []Section
+	prompt := buildPrompt(items, startDate, endDate, days, opts.promptRules())
+	if tokens := EstimateTokens(prompt); tokens > opts.maxPromptTokens() {
+		chunks := splitChunks(items, opts.maxPromptTokens(), opts.promptRules())
+		opts.progress("prompt is ~%d tokens, over the %d budget; summarizing in %d parts", tokens, opts.maxPromptTokens(), len(chunks))
+		merged, err := mapReduce(ctx, provider, chunks, startDate, endDate, days, len(items), opts)
+		if err != nil {
+			summary.Calls = calls.calls
+			return summary, err
+		}
+		sections = merged
+	} else {
+		completion, err := complete(ctx, provider, prompt, opts.Stream)
+		if err == nil {
+			sections, err = parseSections(completion.Text)
+		}
+		if err != nil {
+			summary.Calls = calls.calls
+			return summary, err
 		}
-		filtered = append(filtered, entry)
 	}
 
-	prompt := buildPrompt(filtered, startDate, endDate, days)
-	return callOpenRouter(prompt)
+	summary.Sections = normalizeSections(sections, len(items))
+	summary.Calls = calls.calls
+	return summary, nil
+}
+
+// Prompts returns the prompts TagsSummary starts with: the whole period in
+// one, or one per chunk when that is over the token budget. Merge prompts
+// depend on the model's answers and are not included.
+func Prompts(entries []history.Entry, startDate, endDate string, days int, opts Options) []string {
+	items := numberEntries(entries)
+	prompt := buildPrompt(items, startDate, endDate, days, opts.promptRules())
+	if EstimateTokens(prompt) <= opts.maxPromptTokens() {
+		return []string{prompt}
+	}
+	chunks := splitChunks(items, opts.maxPromptTokens(), opts.promptRules())
+	prompts := make([]string, len(chunks))
+	for i, c := range chunks {
+		prompts[i] = c.prompt(i+1, len(chunks), startDate, endDate, opts.promptRules())
+	}
+	return prompts
+}
+
+// item is a history entry with the id the model uses to reference it.
+type item struct {
+	id    int
+	entry history.Entry
+}
+
+// numberEntries orders entries chronologically and numbers them from 1, so
+// ids stay stable across chunks of the same run.
+func numberEntries(entries []history.Entry) []item {
+	sorted := append([]history.Entry{}, entries...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].VisitTime.Before(sorted[j].VisitTime)
+	})
+	items := make([]item, len(sorted))
+	for i, entry := range sorted {
+		items[i] = item{id: i + 1, entry: entry}
+	}
+	return items
+}
+
+func itemEntries(items []item) []history.Entry {
+	entries := make([]history.Entry, len(items))
+	for i, it := range items {
+		entries[i] = it.entry
+	}
+	return entries
 }
 
-func buildPrompt(entries []history.Entry, startDate, endDate string, days int) string {
+// buildActivity renders the history as per-day tables plus the searches.
+func buildActivity(items []item, startDate, endDate string, days int) string {
+	entries := itemEntries(items)
+
 	// Group entries by date
-	byDate := map[string][]history.Entry{}
-	for _, entry := range entries {
-		date := entry.VisitTime.Format("2006-01-02")
-		byDate[date] = append(byDate[date], entry)
+	byDate := map[string][]item{}
+	for _, it := range items {
+		date := it.entry.VisitTime.Format("2006-01-02")
+		byDate[date] = append(byDate[date], it)
 	}
 
 	// Sort dates
@@ -45,22 +158,53 @@ func buildPrompt(entries []history.Entry, startDate, endDate string, days int) s
 	}
 	sort.Strings(dates)
 
+	// Only show the profile column when there is something to tell apart
+	profiles := map[string]bool{}
+	for _, entry := range entries {
+		if entry.Profile != "" {
+			profiles[entry.Profile] = true
+		}
+	}
+	showProfile := len(profiles) > 1
+
+	// Time on page is only recorded by some browsers
+	showDwell := false
+	for _, entry := range entries {
+		if entry.Duration > 0 {
+			showDwell = true
+			break
+		}
+	}
+
 	var lines []string
 	lines = append(lines, fmt.Sprintf("Browsing history from %s to %s (%d days):", startDate, endDate, days))
 	lines = append(lines, "")
 
 	for _, date := range dates {
 		lines = append(lines, "## "+date)
-		lines = append(lines, "time | url | title")
-		lines = append(lines, "--- | --- | ---")
+		columns := []string{"id", "time"}
+		if showProfile {
+			columns = append(columns, "profile")
+		}
+		columns = append(columns, "url", "title")
+		if showDwell {
+			columns = append(columns, "dwell")
+		}
+		separators := make([]string, len(columns))
+		for i := range separators {
+			separators[i] = "---"
+		}
+		lines = append(lines, strings.Join(columns, " | "))
+		lines = append(lines, strings.Join(separators, " | "))
 
 		// Sort entries by time within the day
-		dayEntries := byDate[date]
-		sort.Slice(dayEntries, func(i, j int) bool {
-			return dayEntries[i].VisitTime.Before(dayEntries[j].VisitTime)
+		dayItems := byDate[date]
+		sort.Slice(dayItems, func(i, j int) bool {
+			return dayItems[i].entry.VisitTime.Before(dayItems[j].entry.VisitTime)
 		})
 
-		for _, entry := range dayEntries {
+		for _, it := range dayItems {
+			entry := it.entry
 			timeStr := entry.VisitTime.Format("15:04")
 			url := entry.URL
 			if len(url) > 100 {
@@ -74,57 +218,101 @@ func buildPrompt(entries []history.Entry, startDate, endDate string, days int) s
 			// Escape pipe characters in URL and title
 			url = strings.ReplaceAll(url, "|", "%7C")
 			title = strings.ReplaceAll(title, "|", "-")
-			lines = append(lines, fmt.Sprintf("%s | %s | %s", timeStr, url, title))
+			row := []string{fmt.Sprintf("%d", it.id), timeStr}
+			if showProfile {
+				profile := strings.ReplaceAll(entry.Profile, "|", "-")
+				if profile == "" {
+					profile = "-"
+				}
+				row = append(row, profile)
+			}
+			row = append(row, url, title)
+			if showDwell {
+				row = append(row, formatDwell(entry.Duration))
+			}
+			lines = append(lines, strings.Join(row, " | "))
 		}
 		lines = append(lines, "")
 	}
 
-	activityText := strings.Join(lines, "\n")
+	lines = append(lines, searchLines(history.ExtractSearches(entries))...)
+
+	return strings.Join(lines, "\n")
+}
+
+// buildPrompt asks for the summary of a whole period in one call.
+func buildPrompt(items []item, startDate, endDate string, days int, rules string) string {
+	prompt := promptIntro + "\n\n" + rules + `
+Now produce the summary based on the browsing history below.
+
+` + buildActivity(items, startDate, endDate, days)
+	return fillPeriod(prompt, startDate, endDate, days)
+}
+
+func fillPeriod(prompt string, startDate, endDate string, days int) string {
+	prompt = strings.ReplaceAll(prompt, "{start}", startDate)
+	prompt = strings.ReplaceAll(prompt, "{end}", endDate)
+	prompt = strings.ReplaceAll(prompt, "{days}", fmt.Sprintf("%d", days))
+	return prompt
+}
+
+const promptIntro = `You are summarizing browsing history from {start} to {end} ({days} days) into a structured tag summary for personal journaling.`
 
-	prompt := `You are summarizing browsing history into a structured tag summary for personal journaling.
+// promptFormat is shared by the single-call, chunk and merge prompts so all
+// of them produce the same format. Unlike the guidance it cannot be
+// replaced, since the reply is parsed against it.
+const promptFormat = `Output format:
+- Reply with a single JSON object and nothing else: no Markdown, no code fences, no commentary.
+- Shape:
+  {
+    "sections": [
+      {
+        "name": "Development",
+        "tags": [
+          {
+            "tag": "ai-agents",
+            "description": "explored AI agents, compared cost of running them remotely",
+            "sites": ["x.com/clawdbot", "github.com/michaelshimeles/ralphy"],
+            "entries": [3, 4, 9, 12],
+            "subtags": [
+              {"tag": "clawdbot", "description": "personal AI assistant setup", "sites": ["clawd.bot"], "entries": [3, 9]}
+            ]
+          }
+        ]
+      }
+    ]
+  }
+- "entries" lists the id column values of every history row the tag covers. Every tag MUST reference its rows; counts are computed from them, so do not add a count field.
+- A parent tag's entries include the entries of its subtags.
+- Tag names are lowercase words joined by dashes, without the leading #.
+- "description" is the action text: what was done, with specific details. No HTML entities (no &nbsp;, &amp;, etc).
+`
 
-Rules:
-- Output plain Markdown only. No HTML entities (no &nbsp;, &amp;, etc). Use spaces for indentation.
-- Start with: "# Browsing Summary - {start} to {end} ({days} days)".
-- Dynamically create sections based on topic categories found (e.g., **Shopping**, **Development**, **Research**, **Finance**).
-- STRICT: Only create a section if it has 5+ items total. Merge smaller groups into the most relevant larger section.
+// DefaultPromptGuidance tells the model how to group and describe the
+// history; Options.PromptTemplate replaces it.
+const DefaultPromptGuidance = `Grouping rules:
+- Dynamically create sections based on topic categories found (e.g., Shopping, Development, Research, Finance).
+- Prefer few, larger sections: a section needs 5+ entries, smaller ones are folded together afterwards.
 - Group by topic/tag, NOT by site. Site is secondary info.
-- Each line format: "#tag (COUNT) action text [site1.com, site2.com]".
-- ABSOLUTE RULE - SUB-TAGS (STRICTLY ENFORCED):
-  COUNT THE NUMBER. If the tag count is less than 10, it MUST be a single line with NO indented sub-bullets beneath it.
-
-  ✓ CORRECT:
-  #ai-agents (18) explored AI agents
-    - #clawdbot (8) personal AI assistant [clawd.bot]
-    - #ralph (5) autonomous coding loop [github.com/repo]
-  #vscode (3) downloaded VS Code [code.visualstudio.com]
-  #stocks (5) checked NVDA, TSLA charts [finance.yahoo.com]
-  #books (4) read 'Atomic Habits', 'Deep Work' [ridibooks.com]
-
-  ✗ WRONG - these have sub-bullets but count is under 10:
-  #stocks (5) stock charts
-    - #nvda (2) NVIDIA
-  #books (4) browsed books
-    - #fiction (2) fiction
-
-- Sub-tags must sum to LESS than parent total. Skip minor items (1-2 counts).
-- If sub-tags share the same site as parent, put site URL in parent only. Example:
-  #youtube (29) watched various videos [m.youtube.com]
-    - #korean-vlogs (10) watched vlogs about daily life
-    - #running (6) watched videos about Garmin watches for runners
-- Combine very small unrelated tags (1-2 counts each) into one summary line at end of section. Example:
-  #misc-dev (4) downloaded VS Code, checked Remotion, explored Raycast [code.visualstudio.com, remotion.dev, raycast.com]
+- Use subtags only for large tags (10+ entries) with distinct sub-topics of 3+ entries each; smaller subtags are dropped afterwards.
+- If subtags share the same site as parent, list the site in the parent only.
+- Combine very small unrelated tags (1-2 entries each) into one tag at the end of the section, e.g. "misc-dev": "downloaded VS Code, checked Remotion, explored Raycast".
 - Do NOT list individual webpage titles. Always group into meaningful tags.
-- Tags must be in descending COUNT within each section.
+- If the history has a dwell column (time spent on the page), weight tags and details toward pages with long dwell times; pages seen for a few seconds matter less.
+- If the history has a profile column, keep browsing from different profiles (e.g. Work vs Personal) in separate tags and name the profile in the description, e.g. "[Work] triaged sprint tickets".
 
 Site references (IMPORTANT):
 - For github.com: ALWAYS include repo path like github.com/steipete/bird, github.com/michaelshimeles/ralphy. NEVER just "github.com"
 - For x.com: ALWAYS include username like x.com/steipete, x.com/clawdbot. NEVER just "x.com"
 - For google maps: mention searched locations/keywords
-- For local URLs: just use [localhost], no IP addresses
-- NEVER repeat the same site/path in brackets.
+- For local URLs: just use "localhost", no IP addresses
+- NEVER repeat the same site/path in a sites list.
 
-Ignore completely (not useful for journaling):
+Searches:
+- Search queries are listed separately under "Searches", grouped by day. They show what was being looked for; use them to make tag descriptions specific (e.g. "searched 'garmin venu x1 battery life'").
+- Count a search toward the tag of its topic, not a separate google or search tag.
+
+Ignore completely (not useful for journaling; leave their entries out of every tag):
 - Authentication, account management, login pages (accounts.google.com, myaccount.google.com, sso.*, login.*, etc.)
 - Redirect, consent, cookie pages
 - Gmail and mail.google.com
@@ -133,9 +321,9 @@ Ignore completely (not useful for journaling):
 
 Categorization:
 - Product research (comparing watches, reading reviews) belongs in Shopping, not Research.
-- Use specific meaningful tags: #grocery for food items (not #products), #books for reading, etc.
-- Avoid generic tags like #products, #misc, #general.
-- IMPORTANT: Group by TOPIC, not by site. If browsing Garmin watches on ricardo.ch, include ricardo.ch in #garmin sites list - do NOT create separate #ricardo tag. The tag should reflect WHAT was browsed, not WHERE.
+- Use specific meaningful tags: grocery for food items (not products), books for reading, etc.
+- Avoid generic tags like products, misc, general.
+- IMPORTANT: Group by TOPIC, not by site. If browsing Garmin watches on ricardo.ch, include ricardo.ch in the garmin tag's sites - do NOT create a separate ricardo tag. The tag should reflect WHAT was browsed, not WHERE.
 
 Content detail (CRITICAL - this is for personal journaling to remember what was done):
 - Do NOT give generic descriptions. Provide SPECIFIC details that help recall what was actually consumed.
@@ -145,19 +333,48 @@ Content detail (CRITICAL - this is for personal journaling to remember what was
 - For articles: mention specific subjects covered
 - For products: list specific models compared
 - The goal is to easily recall what was actually viewed/done - generic summaries are useless.
-  Good: "#crypto (10) Saylor acquired 22,305 BTC at $95k, debate on BTC as risk-on vs risk-off asset, silver decade-long base breakout [x.com/saylor, x.com/JoeConsorti]"
-  Bad: "#crypto (10) followed Bitcoin price discussions and metrics [x.com]"
-  Good: "#books (7) read 'Atomic Habits', browsed 'Deep Work' [ridibooks.com]"
-  Bad: "#ridibooks (7) ebook subscription service and books [ridibooks.com]"
-
-Now produce the summary based on the browsing history below.
+  Good crypto description: "Saylor acquired 22,305 BTC at $95k, debate on BTC as risk-on vs risk-off asset, silver decade-long base breakout"
+  Bad crypto description: "followed Bitcoin price discussions and metrics"
+  Good books description: "read 'Atomic Habits', browsed 'Deep Work'"
+  Bad: a "ridibooks" tag described as "ebook subscription service and books"
+`
 
-` + activityText
+// searchLines renders searches as their own per-day section of the prompt.
+func searchLines(searches []history.Search) []string {
+	if len(searches) == 0 {
+		return nil
+	}
+	lines := []string{"# Searches", ""}
+	date := ""
+	for _, search := range searches {
+		if day := search.VisitTime.Format("2006-01-02"); day != date {
+			if date != "" {
+				lines = append(lines, "")
+			}
+			date = day
+			lines = append(lines, "## "+day)
+			lines = append(lines, "time | engine | query")
+			lines = append(lines, "--- | --- | ---")
+		}
+		query := strings.ReplaceAll(search.Query, "|", "-")
+		lines = append(lines, fmt.Sprintf("%s | %s | %s", search.VisitTime.Format("15:04"), search.Engine, query))
+	}
+	return append(lines, "")
+}
 
-	prompt = strings.ReplaceAll(prompt, "{start}", startDate)
-	prompt = strings.ReplaceAll(prompt, "{end}", endDate)
-	prompt = strings.ReplaceAll(prompt, "{days}", fmt.Sprintf("%d", days))
-	return prompt
+// formatDwell renders time on page compactly ("45s", "12m"), or "-" when the
+// browser did not record it.
+func formatDwell(d time.Duration) string {
+	switch {
+	case d <= 0:
+		return "-"
+	case d < time.Minute:
+		return fmt.Sprintf("%ds", int(d.Seconds()))
+	case d < time.Hour:
+		return fmt.Sprintf("%dm", int(d.Minutes()))
+	default:
+		return fmt.Sprintf("%dh%02dm", int(d.Hours()), int(d.Minutes())%60)
+	}
 }
 
 func normalizeDomain(url string) string {
@@ -214,8 +431,10 @@ func contains(list []string, value string) bool {
 	return false
 }
 
-func DateRange(days int, from string, to string) (time.Time, time.Time, string, string, int, error) {
-	now := time.Now().UTC()
+// DateRange turns --days or --from/--to into the period to read: since and
+// until bound the visits, and the dates are calendar days in location.
+func DateRange(days int, from string, to string, location *time.Location) (time.Time, time.Time, string, string, int, error) {
+	now := time.Now().In(location)
 	if days > 0 && (from != "" || to != "") {
 		return time.Time{}, time.Time{}, "", "", 0, fmt.Errorf("--days cannot be used with --from/--to")
 	}
@@ -233,21 +452,21 @@ func DateRange(days int, from string, to string) (time.Time, time.Time, string,
 		return startDate, endDate.AddDate(0, 0, 1), startDate.Format("2006-01-02"), endDate.Format("2006-01-02"), days, nil
 	}
 
-	start, err := time.ParseInLocation("2006-01-02", from, time.UTC)
+	start, err := time.ParseInLocation("2006-01-02", from, location)
 	if err != nil {
 		return time.Time{}, time.Time{}, "", "", 0, fmt.Errorf("invalid --from date")
 	}
-	endDate := now
+	endDate := startOfDay(now)
 	if to != "" {
-		endDate, err = time.ParseInLocation("2006-01-02", to, time.UTC)
+		endDate, err = time.ParseInLocation("2006-01-02", to, location)
 		if err != nil {
 			return time.Time{}, time.Time{}, "", "", 0, fmt.Errorf("invalid --to date")
 		}
 	}
-	actualDays := int(endDate.Sub(start).Hours()/24) + 1
+	actualDays := int(endDate.Sub(start).Hours()/24+0.5) + 1
 	return start, endDate.AddDate(0, 0, 1), start.Format("2006-01-02"), endDate.Format("2006-01-02"), actualDays, nil
 }
 
 func startOfDay(t time.Time) time.Time {
-	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
 }
@@ -0,0 +1,53 @@
+package summary
+
+import (
+	"context"
+	"io"
+)
+
+// Call is one completion made while producing a summary.
+type Call struct {
+	Provider string
+	// Model is the model that answered.
+	Model string
+	Usage Usage
+}
+
+// TotalUsage sums the usage of every call, cached ones included at zero.
+func (s *Summary) TotalUsage() Usage {
+	total := Usage{}
+	for _, call := range s.Calls {
+		total = total.Add(call.Usage)
+	}
+	return total
+}
+
+// recorder notes the usage of every completion passing through it,
+// including failed attempts the API reported usage for.
+type recorder struct {
+	provider Provider
+	calls    []Call
+}
+
+func (r *recorder) Name() string  { return r.provider.Name() }
+func (r *recorder) Model() string { return r.provider.Model() }
+
+func (r *recorder) Complete(ctx context.Context, prompt string) (Completion, error) {
+	return r.record(complete(ctx, r.provider, prompt, nil))
+}
+
+func (r *recorder) CompleteStream(ctx context.Context, prompt string, w io.Writer) (Completion, error) {
+	return r.record(complete(ctx, r.provider, prompt, w))
+}
+
+func (r *recorder) record(completion Completion, err error) (Completion, error) {
+	r.calls = append(r.calls, completion.Failed...)
+	if err == nil || completion.Usage.reported() {
+		r.calls = append(r.calls, Call{
+			Provider: r.provider.Name(),
+			Model:    firstNonEmpty(completion.Model, r.provider.Model()),
+			Usage:    completion.Usage,
+		})
+	}
+	return completion, err
+}
@@ -0,0 +1,64 @@
+package summary
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func billed(prompt, completion int) Usage {
+	return Usage{PromptTokens: prompt, CompletionTokens: completion}
+}
+
+func TestRecorderCountsFailedAttempts(t *testing.T) {
+	truncated := scriptedReply{completion: Completion{Text: "{", FinishReason: "length", Usage: billed(100, 50)}}
+	overloaded := scriptedReply{completion: Completion{Usage: billed(100, 0)}, err: &HTTPError{StatusCode: 529}}
+	tests := []struct {
+		name    string
+		primary []scriptedReply
+		backup  []scriptedReply
+		calls   int
+		total   Usage
+		wantErr bool
+	}{
+		{"answered", []scriptedReply{{completion: Completion{Text: validReply, Usage: billed(100, 20)}}}, nil, 1, billed(100, 20), false},
+		{"truncated, then the next model", []scriptedReply{truncated}, []scriptedReply{{completion: Completion{Text: validReply, Usage: billed(100, 20)}}}, 2, billed(200, 70), false},
+		{"error after usage was reported", []scriptedReply{overloaded, {completion: Completion{Text: validReply, Usage: billed(100, 20)}}}, nil, 2, billed(200, 20), false},
+		{"errors without usage are not calls", []scriptedReply{failure(errors.New("connection reset")), {completion: Completion{Text: validReply, Usage: billed(100, 20)}}}, nil, 1, billed(100, 20), false},
+		{"every model truncates", []scriptedReply{truncated}, []scriptedReply{truncated}, 2, billed(200, 100), true},
+	}
+	for _, tt := range tests {
+		primary := &scriptedProvider{model: "primary", replies: tt.primary}
+		backup := &scriptedProvider{model: "backup", replies: tt.backup}
+		if backup.replies == nil {
+			backup.replies = []scriptedReply{answer(validReply)}
+		}
+		r := &recorder{provider: testFallback(primary, backup)}
+		_, err := r.Complete(context.Background(), "prompt")
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%s: err = %v", tt.name, err)
+		}
+		s := &Summary{Calls: r.calls}
+		if total := s.TotalUsage(); len(r.calls) != tt.calls || total.PromptTokens != tt.total.PromptTokens || total.CompletionTokens != tt.total.CompletionTokens {
+			t.Errorf("%s: %d calls using %+v, want %d using %+v", tt.name, len(r.calls), total, tt.calls, tt.total)
+		}
+	}
+}
+
+func TestTagsSummaryKeepsUsageOnFailure(t *testing.T) {
+	items := fakeItems(10, 4)
+	part := scriptedReply{completion: Completion{Text: validReply, Usage: billed(1000, 100)}}
+	provider := &scriptedProvider{model: "m", replies: []scriptedReply{part, part, failure(&HTTPError{StatusCode: 401})}}
+	opts := Options{MaxPromptTokens: EstimateTokens(buildPrompt(items[:8], "2026-10-05", "2026-10-06", 2, Options{}.promptRules()))}
+
+	s, err := TagsSummary(context.Background(), provider, itemEntries(items), "2026-10-05", "2026-10-14", 10, opts)
+	if err == nil {
+		t.Fatal("want the third part's error")
+	}
+	if s == nil || len(s.Calls) != 2 || s.TotalUsage().PromptTokens != 2000 {
+		t.Fatalf("summary = %+v, want the two parts that were answered", s)
+	}
+	if len(s.Sections) != 0 {
+		t.Errorf("failed summary has sections: %+v", s.Sections)
+	}
+}
@@ -0,0 +1,159 @@
+// Package usage keeps a ledger of the tokens and money spent on model calls.
+package usage
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"web-log/internal/appdir"
+)
+
+// Record is what one run spent on one model.
+type Record struct {
+	Time             time.Time `json:"time"`
+	Provider         string    `json:"provider"`
+	Model            string    `json:"model"`
+	Calls            int       `json:"calls"`
+	PromptTokens     int       `json:"prompt_tokens"`
+	CompletionTokens int       `json:"completion_tokens"`
+	// Cost is in USD, omitted when the provider does not report it.
+	Cost *float64 `json:"cost,omitempty"`
+	// Period is the summarized date range, e.g. "2026-10-01 to 2026-10-07".
+	Period string `json:"period,omitempty"`
+}
+
+// Total is the spend on one model over a set of records.
+type Total struct {
+	Provider         string
+	Model            string
+	Runs             int
+	Calls            int
+	PromptTokens     int
+	CompletionTokens int
+	Cost             float64
+	// UnpricedRuns counts runs whose provider reported no cost; Cost leaves
+	// them out.
+	UnpricedRuns int
+}
+
+// DefaultPath returns the ledger location in the per-user data directory
+// (see appdir.Data).
+func DefaultPath() (string, error) {
+	dir, err := appdir.Data()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "usage.jsonl"), nil
+}
+
+// Append adds records to the ledger at path, one JSON object per line.
+func Append(path string, records []Record) error {
+	if len(records) == 0 {
+		return nil
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_RDWR, 0o600)
+	if err != nil {
+		return err
+	}
+	// A run killed mid-write leaves a last line without its newline; start
+	// a fresh line so the new records are not glued onto it.
+	if info, err := f.Stat(); err == nil && info.Size() > 0 {
+		last := make([]byte, 1)
+		if _, err := f.ReadAt(last, info.Size()-1); err == nil && last[0] != '\n' {
+			if _, err := f.Write([]byte("\n")); err != nil {
+				f.Close()
+				return err
+			}
+		}
+	}
+	enc := json.NewEncoder(f)
+	for _, record := range records {
+		if err := enc.Encode(record); err != nil {
+			f.Close()
+			return err
+		}
+	}
+	return f.Close()
+}
+
+// Read returns every record in the ledger at path; a missing ledger is empty.
+// Lines that do not parse are skipped.
+func Read(path string) ([]Record, error) {
+	f, err := os.Open(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	records := []Record{}
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64<<10), 1<<20)
+	for scanner.Scan() {
+		var record Record
+		if json.Unmarshal(scanner.Bytes(), &record) == nil {
+			records = append(records, record)
+		}
+	}
+	return records, scanner.Err()
+}
+
+// Between returns the records made in [since, until).
+func Between(records []Record, since, until time.Time) []Record {
+	result := []Record{}
+	for _, record := range records {
+		if !record.Time.Before(since) && record.Time.Before(until) {
+			result = append(result, record)
+		}
+	}
+	return result
+}
+
+// Totals sums records per provider and model, most expensive first.
+func Totals(records []Record) []Total {
+	type key struct{ provider, model string }
+	byModel := map[key]*Total{}
+	for _, record := range records {
+		k := key{record.Provider, record.Model}
+		total, ok := byModel[k]
+		if !ok {
+			total = &Total{Provider: record.Provider, Model: record.Model}
+			byModel[k] = total
+		}
+		total.Runs++
+		total.Calls += record.Calls
+		total.PromptTokens += record.PromptTokens
+		total.CompletionTokens += record.CompletionTokens
+		if record.Cost != nil {
+			total.Cost += *record.Cost
+		} else {
+			total.UnpricedRuns++
+		}
+	}
+
+	totals := make([]Total, 0, len(byModel))
+	for _, total := range byModel {
+		totals = append(totals, *total)
+	}
+	sort.Slice(totals, func(i, j int) bool {
+		if totals[i].Cost != totals[j].Cost {
+			return totals[i].Cost > totals[j].Cost
+		}
+		if totals[i].PromptTokens+totals[i].CompletionTokens != totals[j].PromptTokens+totals[j].CompletionTokens {
+			return totals[i].PromptTokens+totals[i].CompletionTokens > totals[j].PromptTokens+totals[j].CompletionTokens
+		}
+		return totals[i].Model < totals[j].Model
+	})
+	return totals
+}
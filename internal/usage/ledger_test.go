@@ -0,0 +1,128 @@
+package usage
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func cost(usd float64) *float64 {
+	return &usd
+}
+
+func TestAppendRead(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "data", "usage.jsonl")
+	if records, err := Read(path); err != nil || len(records) != 0 {
+		t.Fatalf("missing ledger: got %v, %v, want no records", records, err)
+	}
+
+	day := time.Date(2026, 10, 1, 9, 0, 0, 0, time.UTC)
+	first := []Record{
+		{Time: day, Provider: "openai", Model: "gpt-4o-mini", Calls: 2, PromptTokens: 1000, CompletionTokens: 200, Cost: cost(0.01), Period: "2026-09-24 to 2026-09-30"},
+	}
+	second := []Record{
+		{Time: day.AddDate(0, 0, 1), Provider: "ollama", Model: "llama3", Calls: 1, PromptTokens: 500, CompletionTokens: 50},
+		{Time: day.AddDate(0, 0, 1), Provider: "openai", Model: "gpt-4o", Calls: 1, PromptTokens: 800, CompletionTokens: 100, Cost: cost(0.02)},
+	}
+	for _, records := range [][]Record{first, second, nil} {
+		if err := Append(path, records); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	got, err := Read(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := append(append([]Record{}, first...), second...); !reflect.DeepEqual(got, want) {
+		t.Errorf("got %+v\nwant %+v", got, want)
+	}
+}
+
+// TestReadTruncatedLine reads a ledger whose last line was cut off mid-write
+// and one with a line that is not JSON.
+func TestReadTruncatedLine(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "usage.jsonl")
+	day := time.Date(2026, 10, 1, 9, 0, 0, 0, time.UTC)
+	if err := Append(path, []Record{{Time: day, Provider: "openai", Model: "gpt-4o", Calls: 1}}); err != nil {
+		t.Fatal(err)
+	}
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0o600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := f.WriteString("not json\n{\"time\":\"2026-10-02T09:00:00Z\",\"provider\":\"openai\",\"mod"); err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+
+	records, err := Read(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 1 || records[0].Model != "gpt-4o" {
+		t.Errorf("got %+v, want only the complete record", records)
+	}
+
+	// The next run starts a new line rather than finishing the broken one.
+	if err := Append(path, []Record{{Time: day.AddDate(0, 0, 2), Provider: "openai", Model: "gpt-4o-mini", Calls: 1}}); err != nil {
+		t.Fatal(err)
+	}
+	records, err = Read(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 2 || records[1].Model != "gpt-4o-mini" {
+		t.Errorf("after appending: got %+v, want the new record read back", records)
+	}
+}
+
+func TestBetween(t *testing.T) {
+	start := time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC)
+	records := []Record{
+		{Time: start.Add(-time.Nanosecond), Model: "before"},
+		{Time: start, Model: "first"},
+		{Time: start.Add(36 * time.Hour), Model: "middle"},
+		{Time: start.AddDate(0, 0, 7).Add(-time.Nanosecond), Model: "last"},
+		{Time: start.AddDate(0, 0, 7), Model: "after"},
+	}
+	got := []string{}
+	for _, record := range Between(records, start, start.AddDate(0, 0, 7)) {
+		got = append(got, record.Model)
+	}
+	if want := []string{"first", "middle", "last"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+func TestTotals(t *testing.T) {
+	records := []Record{
+		{Provider: "openai", Model: "gpt-4o-mini", Calls: 2, PromptTokens: 1000, CompletionTokens: 200, Cost: cost(0.01)},
+		{Provider: "ollama", Model: "llama3", Calls: 1, PromptTokens: 9000, CompletionTokens: 900},
+		{Provider: "openai", Model: "gpt-4o", Calls: 1, PromptTokens: 800, CompletionTokens: 100, Cost: cost(0.25)},
+		{Provider: "openai", Model: "gpt-4o-mini", Calls: 3, PromptTokens: 1500, CompletionTokens: 300, Cost: cost(0.02)},
+		{Provider: "openrouter", Model: "gpt-4o-mini", Calls: 1, PromptTokens: 100, CompletionTokens: 10},
+		{Provider: "openai", Model: "gpt-4o-mini", Calls: 1, PromptTokens: 100, CompletionTokens: 10},
+	}
+	want := []Total{
+		{Provider: "openai", Model: "gpt-4o", Runs: 1, Calls: 1, PromptTokens: 800, CompletionTokens: 100, Cost: 0.25},
+		{Provider: "openai", Model: "gpt-4o-mini", Runs: 3, Calls: 6, PromptTokens: 2600, CompletionTokens: 510, Cost: 0.03, UnpricedRuns: 1},
+		{Provider: "ollama", Model: "llama3", Runs: 1, Calls: 1, PromptTokens: 9000, CompletionTokens: 900, UnpricedRuns: 1},
+		{Provider: "openrouter", Model: "gpt-4o-mini", Runs: 1, Calls: 1, PromptTokens: 100, CompletionTokens: 10, UnpricedRuns: 1},
+	}
+	got := Totals(records)
+	if len(got) != len(want) {
+		t.Fatalf("got %+v\nwant %+v", got, want)
+	}
+	for i := range want {
+		if diff := got[i].Cost - want[i].Cost; diff > 1e-9 || diff < -1e-9 {
+			t.Errorf("total %d: cost %v, want %v", i, got[i].Cost, want[i].Cost)
+		}
+		got[i].Cost = want[i].Cost
+		if got[i] != want[i] {
+			t.Errorf("total %d: got %+v, want %+v", i, got[i], want[i])
+		}
+	}
+}